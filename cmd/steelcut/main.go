@@ -2,20 +2,33 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/factsmanager"
 	"github.com/steelcutops/steelcut/steelcut/filemanager"
 	"github.com/steelcutops/steelcut/steelcut/host"
 	"github.com/steelcutops/steelcut/steelcut/hostgroup"
+	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+	"github.com/steelcutops/steelcut/steelcut/logmanager"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
 
 	"golang.org/x/term"
 	"gopkg.in/ini.v1"
@@ -24,32 +37,85 @@ import (
 var programLevel = new(slog.LevelVar)
 
 type HostInfo struct {
-	CPUUsage         float64                   `json:"cpuUsage"`
+	CPUUsage         float64                   `json:"cpuUsage"` // percent
+	Cores            []hostmanager.CPUStat     `json:"cores"`
 	DiskUsageDetails filemanager.DiskUsageInfo `json:"diskUsageDetails"`
-	MemoryUsage      int64                     `json:"memoryUsage"`
-	RunningProcesses []string                  `json:"runningProcesses"`
+	Load             hostmanager.LoadAverage   `json:"load"`
+	Memory           hostmanager.MemoryStats   `json:"memory"`
+	MemoryUsage      float64                   `json:"memoryUsage"` // percent
+	Mounts           []filemanager.Mount       `json:"mounts"`
+	Uptime           string                    `json:"uptime"`
+	RunningProcesses []hostmanager.Process     `json:"runningProcesses"`
+	TopCPU           []hostmanager.Process     `json:"topCpu"`
+	TopMemory        []hostmanager.Process     `json:"topMemory"`
+}
+
+// unmonitoredFSTypes are filesystems that are always full or never fill up,
+// so alerting on them is noise.
+var unmonitoredFSTypes = map[string]bool{
+	"squashfs": true,
+	"iso9660":  true,
+	"devtmpfs": true,
+	"overlay":  true,
 }
 
 type flags struct {
+	CheckDeps          string
 	CheckHealth        bool
+	ClockSkew          bool
+	CompareAlgorithm   string
+	CompareDiff        bool
+	CompareFile        string
 	CPUThreshold       float64
 	Concurrency        int
 	Debug              bool
 	DiskThreshold      float64
 	ExecCommand        string
+	FactsDump          bool
+	HealthProbes       string
 	Hostnames          hostnamesValue
 	InfoDump           bool
 	IniFilePath        string
+	InodeThreshold     float64
+	Inventory          bool
+	InventoryFile      string
+	InventoryFormat    string
 	KeyPassPrompt      bool
 	ListPackages       bool
 	ListUpgradable     bool
+	Listening          bool
 	LogFileName        string
-	MemoryThreshold    int64
+	Logs               bool
+	LogsGrep           string
+	LogsLines          int
+	LogsPriority       string
+	LogsSince          string
+	LogsUnit           string
+	LogsUntil          string
+	MemoryThreshold    float64
 	Monitor            bool
 	MonitorInterval    time.Duration
+	NetworkReport      bool
 	PasswordPrompt     bool
+	PingCount          int
+	PingTimeout        time.Duration
+	PortAllowlist      string
+	PortTimeout        time.Duration
+	Reachability       bool
+	RebootBatch        int
+	RebootRequired     bool
+	RebootCheck        string
+	RebootTimeout      time.Duration
+	ResolveName        string
+	RollingReboot      bool
 	ScriptPath         string
+	SkewThreshold      time.Duration
 	SudoPasswordPrompt bool
+	TailFilter         string
+	TailFollow         bool
+	TailLines          int
+	TailPath           string
+	TopProcesses       int
 	UpgradePackages    bool
 	Username           string
 }
@@ -83,38 +149,203 @@ func readHostsFromFile(filePath string) (map[string][]string, error) {
 	return hosts, nil
 }
 
-func checkHostHealth(host *host.Host) error {
-	// Ping the host
-	result, err := host.NetworkManager.Ping(host.Hostname)
-	if err != nil || !result.Success {
-		return fmt.Errorf("host %s is not reachable: %v", host.Hostname, err)
+// checkHealth checks that every host can be reached over SSH, answers ping
+// and serves the endpoints given in the -health-probes file, printing one row
+// per check. It returns an error if any host is unhealthy.
+func checkHealth(hg *hostgroup.HostGroup, f *flags) error {
+	var checks map[string][]hostgroup.EndpointCheck
+	if f.HealthProbes != "" {
+		var err error
+		checks, err = readHealthProbes(f.HealthProbes, f.IniFilePath, hg.Hostnames())
+		if err != nil {
+			return err
+		}
 	}
 
-	slog.Info("Host %s is healthy with RTT: %f ms", host.Hostname, result.RTT)
+	// Ping from the controller, which is what can tell a host is down.
+	controller := &networkmanager.UnixNetworkManager{CommandManager: &commandmanager.UnixCommandManager{Hostname: "localhost"}}
+	results := hg.CheckHealth(controller, checks, f.PingTimeout, f.Concurrency)
+
+	unhealthy := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tCHECK\tSTATUS\tLATENCY\tDETAIL")
+	for _, h := range results {
+		if !h.Healthy() {
+			unhealthy++
+		}
+		if h.SSHErr != nil {
+			fmt.Fprintf(w, "%s\tssh\tFAIL\t\t%v\n", h.Hostname, h.SSHErr)
+			continue
+		}
+		fmt.Fprintf(w, "%s\tssh\tOK\t\t\n", h.Hostname)
+
+		switch {
+		case h.PingErr != nil:
+			fmt.Fprintf(w, "%s\tping\tFAIL\t\t%v\n", h.Hostname, h.PingErr)
+		case !h.Ping.Success:
+			fmt.Fprintf(w, "%s\tping\tFAIL\t\tno reply\n", h.Hostname)
+		default:
+			fmt.Fprintf(w, "%s\tping\tOK\t%s\t\n", h.Hostname, h.Ping.Avg)
+		}
+
+		for _, endpoint := range h.Endpoints {
+			status, detail := "OK", fmt.Sprintf("%d", endpoint.Result.StatusCode)
+			if !endpoint.Result.TLSExpiry.IsZero() {
+				detail += ", certificate expires " + endpoint.Result.TLSExpiry.Format("2006-01-02")
+			}
+			if endpoint.Err != nil {
+				status, detail = "FAIL", endpoint.Err.Error()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", h.Hostname, endpoint.Check.Name, status, endpoint.Result.Latency.Round(time.Millisecond), detail)
+		}
+	}
+	w.Flush()
+
+	if unhealthy > 0 {
+		return fmt.Errorf("%d of %d hosts are unhealthy", unhealthy, len(results))
+	}
 	return nil
 }
 
+// readHealthProbes reads the HTTP endpoint checks of each host from an INI
+// file with a section per check:
+//
+//	[api]
+//	url = https://localhost/healthz
+//	group = web
+//	expect_status = 200, 204
+//	body_match = "status":\s*"ok"
+//	timeout = 5s
+//	min_tls_validity = 336h
+//	insecure = false
+//
+// A check applies to the hosts of its -ini group, or to every host when it
+// names no group. Only url is required.
+func readHealthProbes(path, hostsFile string, hostnames []string) (map[string][]hostgroup.EndpointCheck, error) {
+	cfg, err := ini.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups map[string][]string
+	if hostsFile != "" {
+		if groups, err = readHostsFromFile(hostsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	checks := make(map[string][]hostgroup.EndpointCheck)
+	for _, section := range cfg.Sections() {
+		if section.Name() == ini.DefaultSection {
+			continue
+		}
+		check := hostgroup.EndpointCheck{
+			Name: section.Name(),
+			Probe: networkmanager.HTTPProbe{
+				URL:       section.Key("url").String(),
+				BodyMatch: section.Key("body_match").String(),
+			},
+		}
+		if check.Probe.URL == "" {
+			return nil, fmt.Errorf("%s [%s]: no url given", path, check.Name)
+		}
+		if _, err := regexp.Compile(check.Probe.BodyMatch); err != nil {
+			return nil, fmt.Errorf("%s [%s]: invalid body_match: %w", path, check.Name, err)
+		}
+		if section.HasKey("expect_status") {
+			if check.ExpectStatus, err = section.Key("expect_status").StrictInts(","); err != nil {
+				return nil, fmt.Errorf("%s [%s]: invalid expect_status: %w", path, check.Name, err)
+			}
+		}
+		if section.HasKey("timeout") {
+			if check.Probe.Timeout, err = section.Key("timeout").Duration(); err != nil {
+				return nil, fmt.Errorf("%s [%s]: invalid timeout: %w", path, check.Name, err)
+			}
+		}
+		if section.HasKey("min_tls_validity") {
+			if check.MinTLSValidity, err = section.Key("min_tls_validity").Duration(); err != nil {
+				return nil, fmt.Errorf("%s [%s]: invalid min_tls_validity: %w", path, check.Name, err)
+			}
+		}
+		if section.HasKey("insecure") {
+			if check.Probe.Insecure, err = section.Key("insecure").Bool(); err != nil {
+				return nil, fmt.Errorf("%s [%s]: invalid insecure: %w", path, check.Name, err)
+			}
+		}
+
+		targets := hostnames
+		if group := section.Key("group").String(); group != "" {
+			members, found := groups[group]
+			if !found {
+				return nil, fmt.Errorf("%s [%s]: unknown group %q", path, check.Name, group)
+			}
+			targets = members
+		}
+		for _, hostname := range targets {
+			checks[hostname] = append(checks[hostname], check)
+		}
+	}
+	return checks, nil
+}
+
 func parseFlags() *flags {
 	f := &flags{}
-	flag.BoolVar(&f.CheckHealth, "check-health", false, "Perform a basic health check on the host")
+	flag.BoolVar(&f.CheckHealth, "check-health", false, "Check that hosts can be reached over SSH, answer ping and serve the endpoints in -health-probes")
+	flag.BoolVar(&f.ClockSkew, "clock-skew", false, "Report how far each host's clock is from this machine's")
+	flag.BoolVar(&f.CompareDiff, "compare-diff", false, "Show a diff of outlier hosts against the majority version with -compare-file")
 	flag.BoolVar(&f.Debug, "debug", false, "Enable debug log level")
+	flag.BoolVar(&f.FactsDump, "facts", false, "Gather facts about the hosts and print them as JSON")
 	flag.BoolVar(&f.InfoDump, "info", false, "Dump information about the hosts")
+	flag.BoolVar(&f.Inventory, "inventory", false, "Collect a hardware inventory of the hosts")
 	flag.BoolVar(&f.KeyPassPrompt, "keypass", false, "Passphrase for decrypting SSH keys")
 	flag.BoolVar(&f.ListPackages, "list", false, "List all packages")
+	flag.BoolVar(&f.Listening, "listening", false, "List the listening TCP and UDP sockets of all hosts")
+	flag.BoolVar(&f.Logs, "logs", false, "Print matching log entries from all hosts as one time-ordered stream")
 	flag.BoolVar(&f.ListUpgradable, "upgradable", false, "List all upgradable packages")
 	flag.DurationVar(&f.MonitorInterval, "monitor-interval", 5*time.Second, "Interval between monitoring checks")
 	flag.BoolVar(&f.Monitor, "monitor", false, "Enable host monitoring")
+	flag.BoolVar(&f.NetworkReport, "network-report", false, "List interfaces of all hosts and flag missing default routes and MTUs that differ across the fleet")
 	flag.BoolVar(&f.PasswordPrompt, "password", false, "Use a password for SSH connection")
+	flag.BoolVar(&f.Reachability, "reachability", false, "Ping every host from every other host and report asymmetric connectivity")
+	flag.BoolVar(&f.RebootRequired, "reboot-required", false, "Report which hosts need a reboot and why")
+	flag.BoolVar(&f.RollingReboot, "rolling-reboot", false, "Reboot the hosts in batches, waiting for each batch to come back before the next")
 	flag.BoolVar(&f.SudoPasswordPrompt, "sudo-password", false, "Prompt for sudo password")
 	flag.BoolVar(&f.UpgradePackages, "upgrade", false, "Upgrade all packages")
 	flag.Float64Var(&f.CPUThreshold, "cpu-threshold", 80.0, "Threshold for CPU usage in percent")
 	flag.Float64Var(&f.DiskThreshold, "disk-threshold", 80.0, "Threshold for disk usage in percent")
-	flag.Int64Var(&f.MemoryThreshold, "memory-threshold", 80, "Threshold for memory usage in percent")
+	flag.Float64Var(&f.InodeThreshold, "inode-threshold", 80.0, "Threshold for inode usage in percent")
+	flag.Float64Var(&f.MemoryThreshold, "memory-threshold", 80.0, "Threshold for memory usage in percent")
+	flag.BoolVar(&f.TailFollow, "tail-follow", true, "Keep following the file given to -tail, like tail -F")
 	flag.IntVar(&f.Concurrency, "concurrency", 10, "Maximum number of concurrent host connections")
+	flag.DurationVar(&f.SkewThreshold, "skew-threshold", time.Second, "Clock offset above which -clock-skew flags a host")
+	flag.DurationVar(&f.RebootTimeout, "reboot-timeout", 10*time.Minute, "How long a host may take to come back from -rolling-reboot")
+	flag.IntVar(&f.PingCount, "ping-count", 3, "Number of pings sent per pair with -reachability")
+	flag.DurationVar(&f.PingTimeout, "ping-timeout", 5*time.Second, "How long each ping may take with -reachability and -check-health")
+	flag.DurationVar(&f.PortTimeout, "port-timeout", 5*time.Second, "How long each connection may take with -check-deps")
+	flag.IntVar(&f.RebootBatch, "reboot-batch", 1, "Number of hosts rebooted at once with -rolling-reboot")
+	flag.IntVar(&f.TopProcesses, "top", 5, "Number of top CPU and memory consumers to report with -info and -monitor")
+	flag.IntVar(&f.LogsLines, "logs-lines", logmanager.DefaultLines, "Maximum number of entries read per host with -logs")
+	flag.IntVar(&f.TailLines, "tail-lines", 10, "Number of existing lines to show per host with -tail")
+	flag.StringVar(&f.CheckDeps, "check-deps", "", "Check the connections listed in a file of \"source target:port[/proto]\" lines")
+	flag.StringVar(&f.CompareAlgorithm, "compare-algo", "sha256", "Checksum algorithm for -compare-file (sha256, sha1 or md5)")
+	flag.StringVar(&f.CompareFile, "compare-file", "", "Compare a file across all hosts by checksum")
 	flag.StringVar(&f.ExecCommand, "exec", "", "Execute command on the host")
+	flag.StringVar(&f.InventoryFile, "inventory-file", "", "File to write the -inventory report to instead of stdout")
+	flag.StringVar(&f.InventoryFormat, "inventory-format", "csv", "Format of the -inventory report (csv or json)")
+	flag.StringVar(&f.HealthProbes, "health-probes", "", "INI file of HTTP endpoints to probe from the hosts with -check-health")
 	flag.StringVar(&f.IniFilePath, "ini", "", "Path to INI file with host configurations")
 	flag.StringVar(&f.LogFileName, "log", "slog.txt", "Log file name")
+	flag.StringVar(&f.LogsGrep, "logs-grep", "", "Only show -logs entries whose message matches this POSIX extended regular expression (as for grep -E)")
+	flag.StringVar(&f.LogsPriority, "logs-priority", "", "Only show -logs entries of this journald priority or more severe, e.g. err")
+	flag.StringVar(&f.LogsSince, "logs-since", "", "Only show -logs entries since a time (RFC 3339 or \"2006-01-02 15:04:05\") or a duration ago such as 1h")
+	flag.StringVar(&f.LogsUnit, "logs-unit", "", "Only show -logs entries of this systemd unit")
+	flag.StringVar(&f.LogsUntil, "logs-until", "", "Only show -logs entries until a time or a duration ago, like -logs-since")
+	flag.StringVar(&f.PortAllowlist, "port-allowlist", "", "INI file of allowed listening ports per host group, flagging other ports with -listening")
+	flag.StringVar(&f.RebootCheck, "reboot-check", "", "Command that must succeed on each host after -rolling-reboot before moving on")
+	flag.StringVar(&f.ResolveName, "resolve", "", "Resolve a name on every host and report hosts that get a different answer")
 	flag.StringVar(&f.ScriptPath, "script", "", "Path to script file to be executed on the host")
+	flag.StringVar(&f.TailFilter, "tail-filter", "", "Only show lines from -tail matching this regular expression")
+	flag.StringVar(&f.TailPath, "tail", "", "Tail a file on all hosts, prefixing each line with the hostname")
 	flag.StringVar(&f.Username, "username", "", "Username to use for SSH connection")
 	flag.Var(&f.Hostnames, "hostname", "Hostname to connect to")
 
@@ -129,28 +360,41 @@ func monitorHosts(hg *hostgroup.HostGroup, f *flags) {
 		for _, host := range hg.Hosts {
 			hostLogger := slog.With("host", host.Hostname) // Setting up contextual logger
 			hostLogger.Debug("Monitoring host")
-			hostInfo, err := getHostInfo(host)
+			hostInfo, err := getHostInfo(host, f.TopProcesses)
 			if err != nil {
 				hostLogger.Error("Failed to get host info", "error", err)
 				continue
 			}
 
 			if hostInfo.CPUUsage > f.CPUThreshold {
-				hostLogger.Info("CPU usage exceeded threshold", "Usage", hostInfo.CPUUsage, "Threshold", f.CPUThreshold)
+				hostLogger.Info("CPU usage exceeded threshold", "Usage", hostInfo.CPUUsage, "Threshold", f.CPUThreshold, "Top", summarizeProcesses(hostInfo.TopCPU))
 			} else {
 				hostLogger.Debug("CPU usage is within threshold", "Usage", hostInfo.CPUUsage, "Threshold", f.CPUThreshold)
 			}
 
 			if hostInfo.MemoryUsage > f.MemoryThreshold {
-				hostLogger.Warn("Memory usage exceeded threshold", "Usage", hostInfo.MemoryUsage, "Threshold", f.MemoryThreshold)
+				hostLogger.Warn("Memory usage exceeded threshold", "Usage", hostInfo.MemoryUsage, "Threshold", f.MemoryThreshold, "Top", summarizeProcesses(hostInfo.TopMemory))
 			} else {
 				hostLogger.Debug("Memory usage is within threshold", "Usage", hostInfo.MemoryUsage, "Threshold", f.MemoryThreshold)
 			}
 
-			if float64(hostInfo.DiskUsageDetails.Available) > f.DiskThreshold {
-				hostLogger.Warn("Disk usage exceeded threshold", "Usage", hostInfo.DiskUsageDetails.Available, "Threshold", f.DiskThreshold)
-			} else {
-				hostLogger.Debug("Disk usage is within threshold", "Usage", hostInfo.DiskUsageDetails.Available, "Threshold", f.DiskThreshold)
+			for _, mount := range hostInfo.Mounts {
+				if unmonitoredFSTypes[mount.FSType] {
+					continue
+				}
+				mountLogger := hostLogger.With("mount", mount.MountPoint)
+
+				if mount.Usage.UsePercent > f.DiskThreshold {
+					mountLogger.Warn("Disk usage exceeded threshold", "Usage", mount.Usage.UsePercent, "Threshold", f.DiskThreshold)
+				} else {
+					mountLogger.Debug("Disk usage is within threshold", "Usage", mount.Usage.UsePercent, "Threshold", f.DiskThreshold)
+				}
+
+				if mount.Inodes.UsePercent > f.InodeThreshold {
+					mountLogger.Warn("Inode usage exceeded threshold", "Usage", mount.Inodes.UsePercent, "Threshold", f.InodeThreshold)
+				} else {
+					mountLogger.Debug("Inode usage is within threshold", "Usage", mount.Inodes.UsePercent, "Threshold", f.InodeThreshold)
+				}
 			}
 		}
 		hg.RUnlock()
@@ -159,6 +403,387 @@ func monitorHosts(hg *hostgroup.HostGroup, f *flags) {
 	}
 }
 
+func tailHosts(hg *hostgroup.HostGroup, f *flags) error {
+	var filter *regexp.Regexp
+	if f.TailFilter != "" {
+		var err error
+		filter, err = regexp.Compile(f.TailFilter)
+		if err != nil {
+			return fmt.Errorf("invalid tail filter: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for hostLine := range hg.Tail(ctx, f.TailPath, f.TailLines, f.TailFollow) {
+		if filter != nil && !filter.MatchString(hostLine.Line) {
+			continue
+		}
+		fmt.Printf("%s: %s\n", hostLine.Hostname, hostLine.Line)
+	}
+
+	return nil
+}
+
+func compareFile(hg *hostgroup.HostGroup, f *flags) {
+	comparison := hg.CompareFile(f.CompareFile, filemanager.ChecksumAlgorithm(f.CompareAlgorithm), f.CompareDiff)
+
+	fmt.Printf("Comparing %s (%s):\n", comparison.Path, comparison.Algorithm)
+	for i, group := range comparison.Groups {
+		label := "outlier"
+		if i == 0 {
+			label = "majority"
+		}
+		fmt.Printf("%s %s (%d hosts): %s\n", group.Digest, label, len(group.Hostnames), strings.Join(group.Hostnames, ", "))
+	}
+
+	for hostname, err := range comparison.Errors {
+		slog.Error("Failed to compare file", "host", hostname, "path", comparison.Path, "error", err)
+	}
+
+	// Hosts sharing a digest share a diff, so print it once per group.
+	for _, group := range comparison.Groups {
+		if d, ok := comparison.Diffs[group.Hostnames[0]]; ok {
+			fmt.Print(d)
+		}
+	}
+
+	if comparison.Consistent() {
+		fmt.Println("All hosts are consistent")
+	}
+}
+
+// resolveName resolves a name on every host and prints the hosts grouped by
+// the addresses they got, the majority answer first.
+func resolveName(hg *hostgroup.HostGroup, f *flags) {
+	resolution := hg.ResolveName(f.ResolveName)
+
+	fmt.Printf("Resolving %s:\n", resolution.Name)
+	for i, group := range resolution.Groups {
+		label := "outlier"
+		if i == 0 {
+			label = "majority"
+		}
+		answer := strings.Join(group.Addresses, " ")
+		if answer == "" {
+			answer = "(does not resolve)"
+		}
+		fmt.Printf("%s %s (%d hosts): %s\n", answer, label, len(group.Hostnames), strings.Join(group.Hostnames, ", "))
+	}
+
+	for hostname, err := range resolution.Errors {
+		slog.Error("Failed to resolve name", "host", hostname, "name", resolution.Name, "error", err)
+	}
+
+	if resolution.Consistent() {
+		fmt.Println("All hosts agree")
+	}
+}
+
+func rollingReboot(hg *hostgroup.HostGroup, f *flags) error {
+	var checks []hostgroup.HostCheck
+	if f.RebootCheck != "" {
+		checks = append(checks, rebootCheck(f.RebootCheck))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return hg.RollingReboot(ctx, f.RebootBatch, f.RebootTimeout, checks...)
+}
+
+// rebootCheck returns a check that runs command with sh on the host. A
+// non-zero exit fails the check and stops the rollout.
+func rebootCheck(command string) hostgroup.HostCheck {
+	return func(ctx context.Context, h *host.Host) error {
+		_, err := commandmanager.RunChecked(ctx, h.CommandManager, commandmanager.CommandConfig{
+			Command: "sh",
+			Args:    []string{"-c", command},
+		}, nil)
+		return err
+	}
+}
+
+func reportClockSkew(hg *hostgroup.HostGroup, f *flags) {
+	for _, skew := range hg.ClockSkew() {
+		if skew.Err != nil {
+			slog.Error("Failed to read clock", "host", skew.Hostname, "error", skew.Err)
+			continue
+		}
+
+		status := "ok"
+		if skew.Offset.Abs() > f.SkewThreshold {
+			status = "SKEWED"
+		}
+		fmt.Printf("%s: %s offset %v (±%v)\n", skew.Hostname, status, skew.Offset.Round(time.Millisecond), (skew.RoundTrip / 2).Round(time.Millisecond))
+	}
+}
+
+func printLogs(hg *hostgroup.HostGroup, f *flags) error {
+	now := time.Now()
+	since, err := parseTimeFlag(f.LogsSince, now)
+	if err != nil {
+		return fmt.Errorf("invalid -logs-since: %w", err)
+	}
+	until, err := parseTimeFlag(f.LogsUntil, now)
+	if err != nil {
+		return fmt.Errorf("invalid -logs-until: %w", err)
+	}
+
+	entries, err := hg.QueryLogs(logmanager.Query{
+		Unit:     f.LogsUnit,
+		Priority: f.LogsPriority,
+		Since:    since,
+		Until:    until,
+		Grep:     f.LogsGrep,
+		Lines:    f.LogsLines,
+	})
+
+	for _, entry := range entries {
+		source := entry.Identifier
+		if source == "" {
+			source = entry.Unit
+		}
+		if entry.PID != 0 {
+			source += fmt.Sprintf("[%d]", entry.PID)
+		}
+		fmt.Printf("%s %s %s: %s\n", entry.Time.Local().Format(time.RFC3339), entry.Hostname, source, entry.Message)
+	}
+
+	return err
+}
+
+// parseTimeFlag parses an absolute time, or a duration which is taken as that
+// long before now. An empty value is the zero time.
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d.Abs()), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+}
+
+// checkDependencies checks the connections declared in the -check-deps file
+// and prints a pass/fail table. It returns an error if any of them failed.
+func checkDependencies(hg *hostgroup.HostGroup, f *flags) error {
+	file, err := os.Open(f.CheckDeps)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	deps, err := hostgroup.ParseDependencies(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.CheckDeps, err)
+	}
+
+	results := hg.CheckDependencies(deps, f.PortTimeout)
+	failed := 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FROM\tTARGET\tRESULT\tLATENCY\tDETAIL")
+	for _, result := range results {
+		status, latency, detail := "PASS", "", result.Method
+		switch {
+		case result.Err != nil:
+			status, detail = "ERROR", result.Err.Error()
+		case !result.Open:
+			status, detail = "FAIL", result.Error
+		}
+		if status != "PASS" {
+			failed++
+		}
+		if result.Latency > 0 {
+			latency = result.Latency.Round(10 * time.Microsecond).String()
+		}
+		target := net.JoinHostPort(result.Dependency.Host, strconv.Itoa(result.Dependency.Port)) + "/" + result.Dependency.Protocol
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.From, target, status, latency, detail)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d dependency checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// reportListening prints the listening sockets of every host. With an
+// allowlist, sockets on ports it does not allow are marked and counted, and
+// an error is returned if there are any.
+func reportListening(hg *hostgroup.HostGroup, f *flags) error {
+	hosts := hg.ListeningSockets()
+
+	var allowlists map[string]hostgroup.PortAllowlist
+	unexpected := make(map[string]bool)
+	if f.PortAllowlist != "" {
+		hostnames := make([]string, len(hosts))
+		for i, h := range hosts {
+			hostnames[i] = h.Hostname
+		}
+		var err error
+		allowlists, err = readPortAllowlists(f.PortAllowlist, f.IniFilePath, hostnames)
+		if err != nil {
+			return err
+		}
+		for _, socket := range hostgroup.UnexpectedSockets(hosts, allowlists) {
+			unexpected[fmt.Sprintf("%s %s %s %d", socket.Hostname, socket.Protocol, socket.LocalAddress, socket.LocalPort)] = true
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tPROTO\tADDRESS\tPORT\tPID\tPROCESS\tSTATUS")
+	for _, h := range hosts {
+		if h.Err != nil {
+			slog.Error("Failed to list listening sockets", "host", h.Hostname, "error", h.Err)
+			continue
+		}
+		for _, socket := range h.Sockets {
+			pid, status := "", ""
+			if socket.PID > 0 {
+				pid = strconv.Itoa(socket.PID)
+			}
+			if unexpected[fmt.Sprintf("%s %s %s %d", h.Hostname, socket.Protocol, socket.LocalAddress, socket.LocalPort)] {
+				status = "UNEXPECTED"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", h.Hostname, socket.Protocol, socket.LocalAddress, socket.LocalPort, pid, socket.Process, status)
+		}
+	}
+	w.Flush()
+
+	if len(unexpected) > 0 {
+		return fmt.Errorf("%d sockets listen on ports not in %s", len(unexpected), f.PortAllowlist)
+	}
+	return nil
+}
+
+// readPortAllowlists reads the allowed ports of each host from an INI file
+// with a "ports" key per host group, named as in the -ini file. Ports listed
+// before the first section are allowed on every host.
+func readPortAllowlists(path, hostsFile string, hostnames []string) (map[string]hostgroup.PortAllowlist, error) {
+	cfg, err := ini.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := hostgroup.ParsePortAllowlist(cfg.Section(ini.DefaultSection).Key("ports").String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	allowlists := make(map[string]hostgroup.PortAllowlist)
+	allowlist := func(hostname string) hostgroup.PortAllowlist {
+		if allowlists[hostname] == nil {
+			allowlists[hostname] = make(hostgroup.PortAllowlist)
+			for port := range defaults {
+				allowlists[hostname][port] = true
+			}
+		}
+		return allowlists[hostname]
+	}
+	for _, hostname := range hostnames {
+		allowlist(hostname)
+	}
+
+	if hostsFile == "" {
+		return allowlists, nil
+	}
+	groups, err := readHostsFromFile(hostsFile)
+	if err != nil {
+		return nil, err
+	}
+	for group, members := range groups {
+		if group == ini.DefaultSection || !cfg.HasSection(group) {
+			continue
+		}
+		ports, err := hostgroup.ParsePortAllowlist(cfg.Section(group).Key("ports").String())
+		if err != nil {
+			return nil, fmt.Errorf("%s [%s]: %w", path, group, err)
+		}
+		for _, hostname := range members {
+			for port := range ports {
+				allowlist(hostname)[port] = true
+			}
+		}
+	}
+	return allowlists, nil
+}
+
+// reportNetworks prints the addresses and default gateway of every host,
+// followed by missing default routes and MTUs that differ from the rest of
+// the fleet. It returns an error if any issues were found.
+func reportNetworks(hg *hostgroup.HostGroup) error {
+	networks := hg.Networks()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tINTERFACE\tSTATE\tMTU\tMAC\tADDRESSES")
+	for _, network := range networks {
+		if network.Err != nil {
+			slog.Error("Failed to read network configuration", "host", network.Hostname, "error", network.Err)
+			continue
+		}
+		for _, iface := range network.Interfaces {
+			addresses := make([]string, len(iface.Addresses))
+			for i, addr := range iface.Addresses {
+				addresses[i] = addr.CIDR()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", network.Hostname, iface.Name, iface.State, iface.MTU, iface.MAC, strings.Join(addresses, " "))
+		}
+	}
+	w.Flush()
+
+	issues := hostgroup.CheckNetworks(networks)
+	for _, issue := range issues {
+		if issue.Interface != "" {
+			fmt.Printf("%s %s: %s\n", issue.Hostname, issue.Interface, issue.Problem)
+		} else {
+			fmt.Printf("%s: %s\n", issue.Hostname, issue.Problem)
+		}
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%d network issues found", len(issues))
+	}
+	return nil
+}
+
+// reportReachability prints a matrix with a row per source host and a column
+// per target, followed by the pairs that can only connect one way.
+func reportReachability(hg *hostgroup.HostGroup, f *flags) {
+	reachability := hg.Reachability(f.PingCount, f.PingTimeout)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "FROM \\ TO\t%s\n", strings.Join(reachability.Hosts, "\t"))
+	for _, from := range reachability.Hosts {
+		cells := []string{from}
+		for _, to := range reachability.Hosts {
+			outcome, pinged := reachability.Results[from][to]
+			switch {
+			case !pinged:
+				cells = append(cells, "-")
+			case outcome.Err != nil:
+				cells = append(cells, "error")
+				slog.Error("Failed to ping", "from", from, "to", to, "error", outcome.Err)
+			case !outcome.Success:
+				cells = append(cells, "unreachable")
+			case outcome.PacketLoss > 0:
+				cells = append(cells, fmt.Sprintf("%v (%.0f%% loss)", outcome.Avg.Round(10*time.Microsecond), outcome.PacketLoss))
+			default:
+				cells = append(cells, outcome.Avg.Round(10*time.Microsecond).String())
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+
+	for _, pair := range reachability.Asymmetric() {
+		fmt.Printf("Asymmetric: %s reaches %s, but not the other way round\n", pair.From, pair.To)
+	}
+}
+
 func readScriptFile(path string) (string, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
@@ -235,8 +860,8 @@ func processHosts(hg *hostgroup.HostGroup, action func(h *host.Host) error, maxC
 	return nil
 }
 
-func dumpHostInfo(host *host.Host) error {
-	hostInfo, err := getHostInfo(host)
+func dumpHostInfo(host *host.Host, top int) error {
+	hostInfo, err := getHostInfo(host, top)
 	if err != nil {
 		return err
 	}
@@ -253,6 +878,128 @@ func dumpHostInfo(host *host.Host) error {
 	return err
 }
 
+func dumpFacts(hg *hostgroup.HostGroup, f *flags) error {
+	var mu sync.Mutex
+	facts := make(map[string]factsmanager.Facts)
+
+	err := processHosts(hg, func(h *host.Host) error {
+		hostFacts, err := h.FactsManager.Facts()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		facts[h.Hostname] = hostFacts
+		return nil
+	}, f.Concurrency)
+
+	b, marshalErr := json.MarshalIndent(facts, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	fmt.Println(string(b))
+
+	return err
+}
+
+// HostInventory is one host's row in the -inventory report.
+type HostInventory struct {
+	Hostname string `json:"hostname"`
+	hostmanager.HardwareInventory
+	Error string `json:"error,omitempty"`
+}
+
+var inventoryColumns = []string{
+	"hostname", "vendor", "product", "serial", "bios_version",
+	"cpu_model", "sockets", "cores", "threads",
+	"memory_bytes", "dimms", "disks", "nics", "warnings", "error",
+}
+
+// writeInventory collects the hardware inventory of every host into one
+// report, sorted by hostname. Hosts that could not be inventoried keep their
+// row with the error filled in, so the report always covers the whole fleet.
+func writeInventory(hg *hostgroup.HostGroup, f *flags) error {
+	if f.InventoryFormat != "csv" && f.InventoryFormat != "json" {
+		return fmt.Errorf("unknown inventory format: %s", f.InventoryFormat)
+	}
+
+	var mu sync.Mutex
+	var inventories []HostInventory
+
+	err := processHosts(hg, func(h *host.Host) error {
+		inventory, err := h.HostManager.HardwareInventory()
+		row := HostInventory{Hostname: h.Hostname, HardwareInventory: inventory}
+		if err != nil {
+			row.Error = err.Error()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		inventories = append(inventories, row)
+		return err
+	}, f.Concurrency)
+
+	sort.Slice(inventories, func(i, j int) bool { return inventories[i].Hostname < inventories[j].Hostname })
+
+	out := os.Stdout
+	if f.InventoryFile != "" {
+		file, createErr := os.Create(f.InventoryFile)
+		if createErr != nil {
+			return createErr
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var writeErr error
+	if f.InventoryFormat == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		writeErr = encoder.Encode(inventories)
+	} else {
+		writeErr = writeInventoryCSV(out, inventories)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+
+	return err
+}
+
+// writeInventoryCSV writes one row per host. Disks, NICs and DIMMs are
+// flattened into semicolon separated lists.
+func writeInventoryCSV(out io.Writer, inventories []HostInventory) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(inventoryColumns); err != nil {
+		return err
+	}
+
+	for _, inv := range inventories {
+		var dimms, disks, nics []string
+		for _, dimm := range inv.Memory.DIMMs {
+			dimms = append(dimms, fmt.Sprintf("%s=%d", dimm.Locator, dimm.Size))
+		}
+		for _, disk := range inv.Disks {
+			disks = append(disks, fmt.Sprintf("%s=%d", disk.Name, disk.Size))
+		}
+		for _, nic := range inv.NICs {
+			nics = append(nics, fmt.Sprintf("%s=%s@%d", nic.Name, nic.MAC, nic.Speed))
+		}
+
+		err := w.Write([]string{
+			inv.Hostname, inv.System.Vendor, inv.System.Product, inv.System.Serial, inv.System.BIOSVersion,
+			inv.CPU.Model, strconv.Itoa(inv.CPU.Sockets), strconv.Itoa(inv.CPU.Cores), strconv.Itoa(inv.CPU.Threads),
+			strconv.FormatInt(inv.Memory.Total, 10), strings.Join(dimms, ";"), strings.Join(disks, ";"), strings.Join(nics, ";"),
+			strings.Join(inv.Warnings, ";"), inv.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
 func listAllPackages(host *host.Host) error {
 	packages, err := host.PackageManager.ListPackages()
 	if err != nil {
@@ -274,6 +1021,28 @@ func listUpgradablePackages(host *host.Host) error {
 	for _, pkg := range upgradable {
 		fmt.Println(pkg)
 	}
+
+	required, reasons, err := host.HostManager.RebootRequired()
+	if err != nil {
+		return fmt.Errorf("failed to check whether a reboot is required: %v", err)
+	}
+	if required {
+		fmt.Printf("Reboot required: %s\n", strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+func reportRebootRequired(host *host.Host) error {
+	required, reasons, err := host.HostManager.RebootRequired()
+	if err != nil {
+		return fmt.Errorf("failed to check whether %s needs a reboot: %v", host.Hostname, err)
+	}
+
+	if required {
+		fmt.Printf("%s: reboot required (%s)\n", host.Hostname, strings.Join(reasons, "; "))
+	} else {
+		fmt.Printf("%s: no reboot required\n", host.Hostname)
+	}
 	return nil
 }
 
@@ -334,35 +1103,45 @@ func executeCommandOnHost(host *host.Host, command string) error {
 	return nil
 }
 
-func getHostInfo(host *host.Host) (HostInfo, error) {
-	cpuUsage, err := host.HostManager.CPUUsage()
-	if err != nil {
-		return HostInfo{}, err
-	}
-
-	memoryUsage, err := host.HostManager.FreeMemory()
+func getHostInfo(host *host.Host, top int) (HostInfo, error) {
+	// Stats gathers everything in one round trip instead of a connection per probe
+	stats, err := host.HostManager.Stats()
 	if err != nil {
 		return HostInfo{}, err
 	}
 
-	diskUsageDetails, err := host.FileManager.DiskUsage("/")
-	if err != nil {
-		return HostInfo{}, err
-	}
-
-	runningProcesses, err := host.HostManager.Processes()
-	if err != nil {
-		return HostInfo{}, err
+	var diskUsageDetails filemanager.DiskUsageInfo
+	for _, mount := range stats.Mounts {
+		if mount.MountPoint == "/" {
+			diskUsageDetails = mount.Usage
+		}
 	}
 
 	return HostInfo{
-		CPUUsage:         cpuUsage,
+		CPUUsage:         stats.CPU.Usage,
+		Cores:            stats.Cores,
 		DiskUsageDetails: diskUsageDetails,
-		MemoryUsage:      memoryUsage,
-		RunningProcesses: runningProcesses,
+		Load:             stats.Load,
+		Memory:           stats.Memory,
+		MemoryUsage:      stats.Memory.UsedPercent,
+		Mounts:           stats.Mounts,
+		Uptime:           stats.Uptime.String(),
+		RunningProcesses: stats.Processes,
+		TopCPU:           hostmanager.TopByCPU(stats.Processes, top),
+		TopMemory:        hostmanager.TopByMemory(stats.Processes, top),
 	}, nil
 }
 
+// summarizeProcesses formats processes as "pid:command" pairs for log lines.
+func summarizeProcesses(processes []hostmanager.Process) string {
+	summary := make([]string, len(processes))
+	for i, p := range processes {
+		command, _, _ := strings.Cut(p.Command, " ")
+		summary[i] = fmt.Sprintf("%d:%s (%.1f%%, %d bytes)", p.PID, command, p.CPUPercent, p.RSS)
+	}
+	return strings.Join(summary, ", ")
+}
+
 func main() {
 	f := parseFlags()
 	configureLogger(f)
@@ -372,13 +1151,28 @@ func main() {
 
 	hostGroup := initializeHosts(f, options)
 
+	if f.CheckDeps != "" {
+		err := checkDependencies(hostGroup, f)
+		if err != nil {
+			slog.Error("Error during CheckDeps", "error", err)
+		}
+	}
+
 	if f.CheckHealth {
-		err := processHosts(hostGroup, checkHostHealth, f.Concurrency)
+		err := checkHealth(hostGroup, f)
 		if err != nil {
 			slog.Error("Error during Health Check", "error", err)
 		}
 	}
 
+	if f.ClockSkew {
+		reportClockSkew(hostGroup, f)
+	}
+
+	if f.CompareFile != "" {
+		compareFile(hostGroup, f)
+	}
+
 	if f.ExecCommand != "" {
 		err := processHosts(hostGroup, func(host *host.Host) error {
 			return executeCommandOnHost(host, f.ExecCommand)
@@ -388,13 +1182,29 @@ func main() {
 		}
 	}
 
+	if f.FactsDump {
+		err := dumpFacts(hostGroup, f)
+		if err != nil {
+			slog.Error("Error during FactsDump", "error", err)
+		}
+	}
+
 	if f.InfoDump {
-		err := processHosts(hostGroup, dumpHostInfo, f.Concurrency)
+		err := processHosts(hostGroup, func(host *host.Host) error {
+			return dumpHostInfo(host, f.TopProcesses)
+		}, f.Concurrency)
 		if err != nil {
 			slog.Error("Error during InfoDump", "error", err)
 		}
 	}
 
+	if f.Inventory {
+		err := writeInventory(hostGroup, f)
+		if err != nil {
+			slog.Error("Error during Inventory", "error", err)
+		}
+	}
+
 	if f.ListPackages {
 		err := processHosts(hostGroup, listAllPackages, f.Concurrency)
 		if err != nil {
@@ -416,6 +1226,49 @@ func main() {
 		}
 	}
 
+	if f.Listening {
+		err := reportListening(hostGroup, f)
+		if err != nil {
+			slog.Error("Error during Listening", "error", err)
+		}
+	}
+
+	if f.Logs {
+		err := printLogs(hostGroup, f)
+		if err != nil {
+			slog.Error("Error during Logs", "error", err)
+		}
+	}
+
+	if f.NetworkReport {
+		err := reportNetworks(hostGroup)
+		if err != nil {
+			slog.Error("Error during NetworkReport", "error", err)
+		}
+	}
+
+	if f.Reachability {
+		reportReachability(hostGroup, f)
+	}
+
+	if f.RebootRequired {
+		err := processHosts(hostGroup, reportRebootRequired, f.Concurrency)
+		if err != nil {
+			slog.Error("Error during RebootRequired", "error", err)
+		}
+	}
+
+	if f.ResolveName != "" {
+		resolveName(hostGroup, f)
+	}
+
+	if f.RollingReboot {
+		err := rollingReboot(hostGroup, f)
+		if err != nil {
+			slog.Error("Error during RollingReboot", "error", err)
+		}
+	}
+
 	if f.ScriptPath != "" {
 		script, err := readScriptFile(f.ScriptPath)
 		if err != nil {
@@ -429,6 +1282,13 @@ func main() {
 		}
 	}
 
+	if f.TailPath != "" {
+		err := tailHosts(hostGroup, f)
+		if err != nil {
+			slog.Error("Error during Tail", "error", err)
+		}
+	}
+
 	if f.Monitor {
 		monitorHosts(hostGroup, f)
 	}
@@ -482,7 +1342,7 @@ func buildHostOptions(f *flags, password, keyPass string) []host.HostOption {
 		fmt.Print("Enter the sudo password: ")
 		sudoPasswordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
 		if err != nil {
-			slog.Error("Failed to read sudo password: %v", err)
+			slog.Error("Failed to read sudo password", "error", err)
 		}
 		sudoPassword := string(sudoPasswordBytes)
 		fmt.Println()
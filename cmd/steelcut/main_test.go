@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/hostgroup"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
 )
 
 func TestReadHostsFromFile(t *testing.T) {
@@ -42,3 +49,127 @@ host3=127.0.0.3`
 		t.Errorf("Expected %v, got %v", expected, hosts)
 	}
 }
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"", time.Time{}},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"2024-04-30T08:00:00Z", time.Date(2024, time.April, 30, 8, 0, 0, 0, time.UTC)},
+		{"2024-04-30 08:00:00", time.Date(2024, time.April, 30, 8, 0, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		got, err := parseTimeFlag(test.value, now)
+		if err != nil {
+			t.Errorf("parseTimeFlag(%q) returned error: %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.expected) {
+			t.Errorf("parseTimeFlag(%q) = %v, expected %v", test.value, got, test.expected)
+		}
+	}
+
+	if _, err := parseTimeFlag("yesterday", now); err == nil {
+		t.Error("Expected an error for an unparseable time")
+	}
+}
+
+func TestReadPortAllowlists(t *testing.T) {
+	dir := t.TempDir()
+	hostsFile := dir + "/hosts.ini"
+	allowlistFile := dir + "/ports.ini"
+	if err := os.WriteFile(hostsFile, []byte("[web]\nhost1=web1\n\n[db]\nhost2=db1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(allowlistFile, []byte("ports = 22/tcp\n\n[web]\nports = 80/tcp, 443/tcp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	allowlists, err := readPortAllowlists(allowlistFile, hostsFile, []string{"web1", "db1", "adhoc"})
+	if err != nil {
+		t.Fatalf("Error reading port allowlists: %v", err)
+	}
+
+	expected := map[string]hostgroup.PortAllowlist{
+		"web1":  {"22/tcp": true, "80/tcp": true, "443/tcp": true},
+		"db1":   {"22/tcp": true},
+		"adhoc": {"22/tcp": true},
+	}
+	if !reflect.DeepEqual(allowlists, expected) {
+		t.Errorf("Expected %v, got %v", expected, allowlists)
+	}
+}
+
+func TestReadHealthProbes(t *testing.T) {
+	dir := t.TempDir()
+	hostsFile := dir + "/hosts.ini"
+	probesFile := dir + "/probes.ini"
+	if err := os.WriteFile(hostsFile, []byte("[web]\nhost1=web1\n\n[db]\nhost2=db1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	probes := "[node]\nurl = http://localhost:9100/metrics\n\n" +
+		"[api]\nurl = https://localhost/healthz\ngroup = web\nexpect_status = 200, 204\nbody_match = ok\ntimeout = 3s\nmin_tls_validity = 336h\n"
+	if err := os.WriteFile(probesFile, []byte(probes), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checks, err := readHealthProbes(probesFile, hostsFile, []string{"db1", "web1"})
+	if err != nil {
+		t.Fatalf("Error reading health probes: %v", err)
+	}
+
+	node := hostgroup.EndpointCheck{Name: "node", Probe: networkmanager.HTTPProbe{URL: "http://localhost:9100/metrics"}}
+	api := hostgroup.EndpointCheck{
+		Name:           "api",
+		Probe:          networkmanager.HTTPProbe{URL: "https://localhost/healthz", BodyMatch: "ok", Timeout: 3 * time.Second},
+		ExpectStatus:   []int{200, 204},
+		MinTLSValidity: 336 * time.Hour,
+	}
+	expected := map[string][]hostgroup.EndpointCheck{
+		"db1":  {node},
+		"web1": {node, api},
+	}
+	if !reflect.DeepEqual(checks, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, checks)
+	}
+
+	if err := os.WriteFile(probesFile, []byte("[api]\nurl = http://localhost/\ngroup = cache\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readHealthProbes(probesFile, hostsFile, nil); err == nil {
+		t.Error("Expected an error for an unknown group")
+	}
+}
+
+// exitCommandManager reports every command as exiting with ExitCode, without
+// an error, as some command managers do.
+type exitCommandManager struct {
+	ExitCode int
+}
+
+func (m exitCommandManager) RunLocal(ctx context.Context, config commandmanager.CommandConfig) (commandmanager.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m exitCommandManager) RunRemote(ctx context.Context, config commandmanager.CommandConfig) (commandmanager.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m exitCommandManager) Run(ctx context.Context, config commandmanager.CommandConfig) (commandmanager.CommandResult, error) {
+	return commandmanager.CommandResult{ExitCode: m.ExitCode, STDERR: "not ready"}, nil
+}
+
+func TestRebootCheck(t *testing.T) {
+	check := rebootCheck("systemctl is-system-running")
+
+	if err := check(context.Background(), &host.Host{CommandManager: exitCommandManager{}}); err != nil {
+		t.Errorf("Expected the check to pass, got: %v", err)
+	}
+	if err := check(context.Background(), &host.Host{CommandManager: exitCommandManager{ExitCode: 1}}); err == nil {
+		t.Error("Expected a non-zero exit to fail the check")
+	}
+}
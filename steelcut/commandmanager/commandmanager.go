@@ -2,6 +2,8 @@ package commandmanager
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -34,3 +36,88 @@ type CommandManager interface {
 	// Run executes a command on the local system if the host is localhost, otherwise it executes the command on the remote system.
 	Run(ctx context.Context, config CommandConfig) (CommandResult, error)
 }
+
+// StreamingCommandManager is implemented by command managers that can hand
+// back output while a long running command, such as tail -F, is still going.
+type StreamingCommandManager interface {
+	CommandManager
+
+	// Stream executes a command and calls onLine for every line written to
+	// stdout. It returns when the command exits or the context is cancelled.
+	Stream(ctx context.Context, config CommandConfig, onLine func(line string)) error
+}
+
+// BatchCommandManager is implemented by command managers that can run several
+// commands in a single round trip to the host.
+type BatchCommandManager interface {
+	CommandManager
+
+	// RunBatch executes the commands in order and returns one result per
+	// command. A failing command does not stop the ones after it; an error is
+	// only returned when the batch as a whole could not be run.
+	RunBatch(ctx context.Context, configs []CommandConfig) ([]CommandResult, error)
+}
+
+// RunBatch runs configs through manager in a single round trip when it
+// supports batching, and one at a time otherwise. Either way a failing command
+// is reported through its result's ExitCode and STDERR rather than an error.
+func RunBatch(ctx context.Context, manager CommandManager, configs []CommandConfig) ([]CommandResult, error) {
+	if batcher, ok := manager.(BatchCommandManager); ok {
+		return batcher.RunBatch(ctx, configs)
+	}
+
+	results := make([]CommandResult, len(configs))
+	for i, config := range configs {
+		result, err := manager.Run(ctx, config)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if result.ExitCode == 0 {
+				result.ExitCode = -1
+			}
+			if result.STDERR == "" {
+				result.STDERR = err.Error()
+			}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// RunChecked runs config through manager and turns a non-zero exit status into
+// an error carrying the command's stderr, whether the command ran locally or
+// over SSH. Statuses for which allowed returns true count as success; allowed
+// may be nil.
+func RunChecked(ctx context.Context, manager CommandManager, config CommandConfig, allowed func(status int) bool) (CommandResult, error) {
+	result, err := manager.Run(ctx, config)
+	stderr := strings.TrimSpace(result.STDERR)
+	switch {
+	case result.ExitCode > 0 && allowed != nil && allowed(result.ExitCode):
+		return result, nil
+	case result.ExitCode != 0:
+		return result, fmt.Errorf("%s exited with status %d: %s", config.Command, result.ExitCode, stderr)
+	case err != nil && stderr != "":
+		return result, fmt.Errorf("%w: %s", err, stderr)
+	}
+	return result, err
+}
+
+// CutFields splits the first n whitespace separated fields off a line of
+// command output and returns them along with the rest of the line, whose
+// spacing is preserved. It suits columns such as the command of ps or cron,
+// which may itself contain spaces.
+func CutFields(line string, n int) ([]string, string) {
+	var fields []string
+	rest := strings.TrimSpace(line)
+	for len(fields) < n && rest != "" {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			fields = append(fields, rest)
+			return fields, ""
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	return fields, rest
+}
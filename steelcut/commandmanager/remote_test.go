@@ -0,0 +1,333 @@
+package commandmanager_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/steelcutops/steelcut/common"
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/environmentmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/hostgroup"
+	"github.com/steelcutops/steelcut/steelcut/packagemanager"
+	"github.com/steelcutops/steelcut/steelcut/servicemanager"
+)
+
+// testSSHDialer ignores the address it is given and connects to a local test
+// server instead.
+type testSSHDialer struct {
+	addr string
+}
+
+func (d testSSHDialer) Dial(network, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	config.Timeout = timeout
+	return ssh.Dial(network, d.addr, config)
+}
+
+// startSSHServer runs an SSH server that executes every exec request with
+// sh. A sudo shim that simply runs its command is put first on the PATH,
+// along with a shell script for each of shims, keyed by command name.
+func startSSHServer(t *testing.T, shims map[string]string) string {
+	t.Helper()
+
+	binDir := t.TempDir()
+	scripts := map[string]string{"sudo": "shift 2\nexec \"$@\"\n"}
+	for name, script := range shims {
+		scripts[name] = script
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := "PATH=" + binDir + string(os.PathListSeparator) + os.Getenv("PATH")
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config, path)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig, path string) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				var payload struct{ Command string }
+				ssh.Unmarshal(req.Payload, &payload)
+				req.Reply(true, nil)
+
+				cmd := exec.Command("sh", "-c", payload.Command)
+				cmd.Env = append(os.Environ(), path)
+				cmd.Stdin = channel
+				cmd.Stdout = channel
+				cmd.Stderr = channel.Stderr()
+				status := struct{ Status uint32 }{}
+				if err := cmd.Run(); err != nil {
+					status.Status = 255
+					if exitErr, ok := err.(*exec.ExitError); ok {
+						status.Status = uint32(exitErr.ExitCode())
+					}
+				}
+				channel.SendRequest("exit-status", false, ssh.Marshal(&status))
+				return
+			}
+		}()
+	}
+}
+
+func newRemoteCommandManager(t *testing.T, shims map[string]string) *cm.UnixCommandManager {
+	return &cm.UnixCommandManager{
+		Hostname:    "remote.example.com",
+		SSHClient:   testSSHDialer{addr: startSSHServer(t, shims)},
+		Credentials: common.Credentials{User: "test", Password: "test"},
+	}
+}
+
+func TestRunRemoteExitCode(t *testing.T) {
+	manager := newRemoteCommandManager(t, nil)
+
+	result, err := manager.Run(context.Background(), cm.CommandConfig{Command: "sh", Args: []string{"-c", "echo out; exit 3"}})
+	if err == nil {
+		t.Errorf("Expected an error for a non-zero exit")
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", result.ExitCode)
+	}
+	if result.STDOUT != "out\n" {
+		t.Errorf("Expected stdout to be kept, got %q", result.STDOUT)
+	}
+
+	result, err = manager.Run(context.Background(), cm.CommandConfig{Command: "no-such-command-steelcut"})
+	if err == nil || result.ExitCode != 127 {
+		t.Errorf("Expected exit code 127 and an error for a missing command, got %d and %v", result.ExitCode, err)
+	}
+
+	result, err = manager.Run(context.Background(), cm.CommandConfig{Command: "true"})
+	if err != nil || result.ExitCode != 0 {
+		t.Errorf("Expected success, got exit code %d and %v", result.ExitCode, err)
+	}
+}
+
+func TestRemoteWriteFileAtomicValidationFailure(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "config")
+	if err := os.WriteFile(target, []byte("original\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager := filemanager.UnixFileManager{
+		CommandManager: newRemoteCommandManager(t, nil),
+		BackupDir:      filepath.Join(dir, "backups"),
+	}
+
+	err := manager.WriteFileAtomic(target, []byte("replacement\n"), filemanager.AtomicWriteOptions{Validate: "grep -q valid %s"})
+	if err == nil {
+		t.Fatalf("Expected failed validation to return an error")
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "original\n" {
+		t.Errorf("Expected target to be untouched, got %q", content)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".steelcut.") {
+			t.Errorf("Expected temp file to be removed, found %s", entry.Name())
+		}
+	}
+	if _, err := os.Stat(manager.BackupDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup to be taken for a failed write")
+	}
+}
+
+func TestRemoteWriteFileAtomicLargeContent(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "large")
+	manager := filemanager.UnixFileManager{
+		CommandManager: newRemoteCommandManager(t, nil),
+		BackupDir:      filepath.Join(dir, "backups"),
+	}
+
+	// Well over the 128KiB a single argument may take, even before encoding.
+	content := []byte(strings.Repeat("0123456789abcdef\n", 20000))
+	if err := manager.WriteFileAtomic(target, content, filemanager.AtomicWriteOptions{Mode: 0644}); err != nil {
+		t.Fatalf("Expected the write to succeed, got: %v", err)
+	}
+
+	written, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != string(content) {
+		t.Errorf("Expected %d bytes to be written, got %d", len(content), len(written))
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".steelcut.") {
+			t.Errorf("Expected temp files to be removed, found %s", entry.Name())
+		}
+	}
+}
+
+func TestRunCheckedLocalAndRemote(t *testing.T) {
+	managers := map[string]cm.CommandManager{
+		"local":  &cm.UnixCommandManager{Hostname: "localhost"},
+		"remote": newRemoteCommandManager(t, nil),
+	}
+	config := cm.CommandConfig{Command: "sh", Args: []string{"-c", "echo broken >&2; exit 2"}}
+
+	for name, manager := range managers {
+		result, err := cm.RunChecked(context.Background(), manager, config, nil)
+		if err == nil || err.Error() != "sh exited with status 2: broken" {
+			t.Errorf("%s: expected exit status error, got %v", name, err)
+		}
+		if result.ExitCode != 2 {
+			t.Errorf("%s: expected exit code 2, got %d", name, result.ExitCode)
+		}
+
+		_, err = cm.RunChecked(context.Background(), manager, config, func(status int) bool { return status == 2 })
+		if err != nil {
+			t.Errorf("%s: expected allowed status to succeed, got %v", name, err)
+		}
+	}
+}
+
+// The remote tests below cover callers that read an answer from a command's
+// exit status, which over SSH comes back as an error just as it does locally.
+
+func TestRemoteServiceStatus(t *testing.T) {
+	systemctl := `case "$1 $2" in
+"is-active web") echo active ;;
+"is-active db") echo inactive; exit 3 ;;
+"is-active cache") echo failed; exit 3 ;;
+"is-enabled web") echo enabled ;;
+"is-enabled db") echo disabled; exit 1 ;;
+*) echo "unexpected $*" >&2; exit 4 ;;
+esac
+`
+	manager := &servicemanager.LinuxServiceManager{CommandManager: newRemoteCommandManager(t, map[string]string{"systemctl": systemctl})}
+
+	expected := map[string]servicemanager.ServiceStatus{"web": servicemanager.Active, "db": servicemanager.Inactive, "cache": servicemanager.Failed}
+	for service, status := range expected {
+		got, err := manager.CheckServiceStatus(service)
+		if err != nil || got != status {
+			t.Errorf("Expected %s to be %s, got %s and %v", service, status, got, err)
+		}
+	}
+	if _, err := manager.CheckServiceStatus("other"); err == nil {
+		t.Errorf("Expected an error when systemctl fails")
+	}
+
+	if enabled, err := manager.IsServiceEnabled("web"); err != nil || !enabled {
+		t.Errorf("Expected web to be enabled, got %v and %v", enabled, err)
+	}
+	if enabled, err := manager.IsServiceEnabled("db"); err != nil || enabled {
+		t.Errorf("Expected db to be disabled, got %v and %v", enabled, err)
+	}
+}
+
+func TestRemoteDarwinServiceNotLoaded(t *testing.T) {
+	launchctl := "echo \"Could not find service \\\"$2\\\" in domain for system\" >&2\nexit 113\n"
+	manager := &servicemanager.DarwinServiceManager{CommandManager: newRemoteCommandManager(t, map[string]string{"launchctl": launchctl})}
+
+	if status, err := manager.CheckServiceStatus("com.example.web"); err != nil || status != servicemanager.Inactive {
+		t.Errorf("Expected a service that is not loaded to be inactive, got %s and %v", status, err)
+	}
+	if enabled, err := manager.IsServiceEnabled("com.example.web"); err != nil || enabled {
+		t.Errorf("Expected a service that is not loaded to be disabled, got %v and %v", enabled, err)
+	}
+}
+
+func TestRemoteNoOSUpdates(t *testing.T) {
+	none := "echo 'Error: No matching Packages to list' >&2\nexit 1\n"
+	managers := map[string]packagemanager.PackageManager{
+		"dnf": &packagemanager.DnfPackageManager{CommandManager: newRemoteCommandManager(t, map[string]string{"dnf": none})},
+		"yum": &packagemanager.YumPackageManager{CommandManager: newRemoteCommandManager(t, map[string]string{"yum": none})},
+	}
+
+	for name, manager := range managers {
+		updates, err := manager.CheckOSUpdates()
+		if err != nil || len(updates) != 0 {
+			t.Errorf("%s: expected no updates, got %v and %v", name, updates, err)
+		}
+	}
+}
+
+func TestRemoteUnsetEnvironmentVariable(t *testing.T) {
+	manager := &environmentmanager.UnixEnvironmentManager{CommandManager: newRemoteCommandManager(t, nil)}
+
+	value, err := manager.Get("STEELCUT_UNSET_VARIABLE")
+	if err != nil || value != "" {
+		t.Errorf("Expected an unset variable to be empty, got %q and %v", value, err)
+	}
+}
+
+func TestRemoteHostGroupRunKeepsStderr(t *testing.T) {
+	hg := hostgroup.NewHostGroup()
+	hg.AddHost(&host.Host{Hostname: "remote.example.com", CommandManager: newRemoteCommandManager(t, nil)})
+
+	results := hg.Run(context.Background(), "sh", "-c", "echo broken >&2; exit 2")
+	if len(results) != 1 || results[0].ExitCode != 2 || results[0].STDERR != "broken\n" {
+		t.Errorf("Expected exit code 2 with the command's stderr, got %+v", results)
+	}
+}
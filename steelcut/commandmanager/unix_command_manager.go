@@ -1,13 +1,18 @@
 package commandmanager
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/steelcutops/steelcut/common"
@@ -142,22 +147,7 @@ func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig
 		"sudo", config.Sudo,
 	)
 
-	if u.SSHClient == nil {
-		return CommandResult{}, errors.New("SSHClient is not initialized")
-	}
-
-	sshConfig, err := u.getSSHConfig()
-	if err != nil {
-		return CommandResult{}, err
-	}
-	var dialTimeout time.Duration
-	if deadline, ok := ctx.Deadline(); ok {
-		dialTimeout = time.Until(deadline)
-	} else {
-		dialTimeout = 15 * time.Minute
-	}
-
-	client, err := u.SSHClient.Dial("tcp", u.Hostname+":22", sshConfig, dialTimeout)
+	client, err := u.dial(ctx)
 	if err != nil || client == nil {
 		return CommandResult{}, err
 	}
@@ -169,22 +159,19 @@ func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig
 	}
 	defer session.Close()
 
-	cmdStr := config.Command + " " + strings.Join(config.Args, " ")
-
+	cmdStr := u.remoteCommand(config)
 	if config.Sudo {
-		cmdStr = "sudo -S -- " + cmdStr
 		session.Stdin = strings.NewReader(u.SudoPassword + "\n")
 	}
 
-	// Prepend environment variables
-	if len(config.Env) > 0 {
-		envStr := strings.Join(config.Env, " ") + " "
-		cmdStr = envStr + cmdStr
-	}
-
 	start := time.Now()
 
-	outputCh := make(chan CommandResult)
+	// Buffered so the goroutine can finish even when ctx expires first.
+	type outcome struct {
+		result CommandResult
+		err    error
+	}
+	outputCh := make(chan outcome, 1)
 	go func() {
 		var result CommandResult
 
@@ -196,7 +183,7 @@ func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig
 		// Execute command
 		err := session.Run(cmdStr)
 		if err != nil {
-			slog.Error("Failed to execute command over SSH", "command", cmdStr, "error", err, "stdout", stdout.String(), "stderr", stderr.String())
+			slog.Debug("Remote command failed", "command", cmdStr, "error", err, "stdout", stdout.String(), "stderr", stderr.String())
 			result.ExitCode = getExitCode(err)
 		}
 
@@ -204,11 +191,12 @@ func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig
 		result.STDERR = stderr.String()
 
 		// Send the result to the channel
-		outputCh <- result
+		outputCh <- outcome{result, err}
 	}()
 
 	select {
-	case result := <-outputCh:
+	case outcome := <-outputCh:
+		result := outcome.result
 		result.Duration = time.Since(start)
 		result.Timestamp = start
 		result.Command = cmdStr
@@ -219,7 +207,8 @@ func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig
 			return result, sudoErr
 		}
 
-		return result, nil
+		// A non-zero exit is an error, as it is for RunLocal.
+		return result, outcome.err
 
 	case <-ctx.Done():
 		slog.Error("Command over SSH timed out.", "command_string", cmdStr)
@@ -227,6 +216,154 @@ func (u *UnixCommandManager) RunRemote(ctx context.Context, config CommandConfig
 	}
 }
 
+// Stream runs the command and calls onLine for each line it writes to stdout,
+// returning once the command exits or ctx is cancelled.
+func (u *UnixCommandManager) Stream(ctx context.Context, config CommandConfig, onLine func(line string)) error {
+	if u.isLocal() {
+		return u.streamLocal(ctx, config, onLine)
+	}
+	return u.streamRemote(ctx, config, onLine)
+}
+
+func (u *UnixCommandManager) streamLocal(ctx context.Context, config CommandConfig, onLine func(line string)) error {
+	cmd := exec.CommandContext(ctx, config.Command, config.Args...)
+	if config.Sudo {
+		cmdArgs := append([]string{"sudo", "-S", "--", config.Command}, config.Args...)
+		cmd = exec.CommandContext(ctx, cmdArgs[0], cmdArgs[1:]...)
+		cmd.Stdin = strings.NewReader(u.SudoPassword + "\n")
+	}
+	if len(config.Env) > 0 {
+		cmd.Env = append(os.Environ(), config.Env...)
+	}
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanLines(stdout, onLine)
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if sudoErr := u.checkSudoErrors(CommandResult{STDERR: stderr.String()}); sudoErr != nil {
+		return sudoErr
+	}
+	return err
+}
+
+func (u *UnixCommandManager) streamRemote(ctx context.Context, config CommandConfig, onLine func(line string)) error {
+	client, err := u.dial(ctx)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return errors.New("SSH dial returned no client")
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	cmdStr := u.remoteCommand(config)
+	if config.Sudo {
+		session.Stdin = strings.NewReader(u.SudoPassword + "\n")
+	}
+
+	var stderr strings.Builder
+	session.Stderr = &stderr
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := session.Start(cmdStr); err != nil {
+		return err
+	}
+
+	// Closing the connection is the only reliable way to stop a remote command,
+	// as most SSH servers ignore signal requests.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	scanLines(stdout, onLine)
+
+	err = session.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if sudoErr := u.checkSudoErrors(CommandResult{STDERR: stderr.String()}); sudoErr != nil {
+		return sudoErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func scanLines(r io.Reader, onLine func(line string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		onLine(scanner.Text())
+	}
+}
+
+// dial opens an SSH connection to the host, bounded by the context deadline if there is one.
+func (u *UnixCommandManager) dial(ctx context.Context) (*ssh.Client, error) {
+	if u.SSHClient == nil {
+		return nil, errors.New("SSHClient is not initialized")
+	}
+
+	sshConfig, err := u.getSSHConfig()
+	if err != nil {
+		return nil, err
+	}
+	var dialTimeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		dialTimeout = time.Until(deadline)
+	} else {
+		dialTimeout = 15 * time.Minute
+	}
+
+	return u.SSHClient.Dial("tcp", u.Hostname+":22", sshConfig, dialTimeout)
+}
+
+// remoteCommand builds the shell command line sent over SSH for config.
+func (u *UnixCommandManager) remoteCommand(config CommandConfig) string {
+	cmdStr := config.Command
+	for _, arg := range config.Args {
+		cmdStr += " " + ShellQuote(arg)
+	}
+
+	if config.Sudo {
+		cmdStr = "sudo -S -- " + cmdStr
+	}
+
+	// Prepend environment variables
+	if len(config.Env) > 0 {
+		envStr := strings.Join(config.Env, " ") + " "
+		cmdStr = envStr + cmdStr
+	}
+
+	return cmdStr
+}
+
 func (u *UnixCommandManager) Run(ctx context.Context, config CommandConfig) (CommandResult, error) {
 	if u.isLocal() {
 		slog.Debug("Detected local so running local command", "hostname", u.Hostname, "command", config.Command, "sshclient", u.SSHClient)
@@ -237,16 +374,159 @@ func (u *UnixCommandManager) Run(ctx context.Context, config CommandConfig) (Com
 	return u.RunRemote(ctx, config)
 }
 
+// RunBatch runs all commands in one shell invocation, so a remote host is only
+// dialled once. Each command's output is framed by marker lines carrying a
+// random nonce, which are used to split the output back into separate results.
+func (u *UnixCommandManager) RunBatch(ctx context.Context, configs []CommandConfig) ([]CommandResult, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	sudo := configs[0].Sudo
+	for _, config := range configs[1:] {
+		if config.Sudo != sudo {
+			return nil, errors.New("batched commands must either all use sudo or none")
+		}
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	marker := "@@steelcut-batch:" + hex.EncodeToString(nonce) + ":"
+
+	result, err := u.Run(ctx, CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", batchScript(marker, configs)},
+		Sudo:    sudo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := splitBatchOutput(marker, result.STDOUT, configs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(result.STDERR))
+	}
+	for i := range results {
+		results[i].Duration = result.Duration
+		results[i].Timestamp = result.Timestamp
+	}
+	return results, nil
+}
+
+// batchScript builds a script printing, for every command, a marker, its
+// stdout, a marker with its exit code and its stderr.
+func batchScript(marker string, configs []CommandConfig) string {
+	var script strings.Builder
+	script.WriteString("steelcut_err=$(mktemp) || exit 1\n")
+
+	for i, config := range configs {
+		cmdStr := config.Command
+		for _, arg := range config.Args {
+			cmdStr += " " + ShellQuote(arg)
+		}
+		if len(config.Env) > 0 {
+			env := make([]string, len(config.Env))
+			for j, e := range config.Env {
+				env[j] = ShellQuote(e)
+			}
+			cmdStr = "env " + strings.Join(env, " ") + " " + cmdStr
+		}
+
+		fmt.Fprintf(&script, "printf '\\n%sout:%d\\n'\n", marker, i)
+		fmt.Fprintf(&script, "(%s) </dev/null 2>\"$steelcut_err\"\n", cmdStr)
+		fmt.Fprintf(&script, "printf '\\n%serr:%d:%%d\\n' $?\n", marker, i)
+		script.WriteString("cat \"$steelcut_err\"\n")
+	}
+
+	fmt.Fprintf(&script, "printf '\\n%send\\n'\n", marker)
+	script.WriteString("rm -f \"$steelcut_err\"\n")
+	return script.String()
+}
+
+// splitBatchOutput splits the output of a batch script back into one result per command.
+func splitBatchOutput(marker, output string, configs []CommandConfig) ([]CommandResult, error) {
+	results := make([]CommandResult, len(configs))
+	for i, config := range configs {
+		results[i].Command = config.Command + " " + strings.Join(config.Args, " ")
+	}
+
+	complete := false
+	for _, section := range strings.Split(output, "\n"+marker)[1:] {
+		header, body, _ := strings.Cut(section, "\n")
+		fields := strings.Split(header, ":")
+
+		if fields[0] == "end" {
+			complete = true
+			break
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed batch marker: %s", header)
+		}
+		index, err := strconv.Atoi(fields[1])
+		if err != nil || index < 0 || index >= len(results) {
+			return nil, fmt.Errorf("malformed batch marker: %s", header)
+		}
+
+		switch fields[0] {
+		case "out":
+			results[index].STDOUT = body
+		case "err":
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("malformed batch marker: %s", header)
+			}
+			results[index].ExitCode, _ = strconv.Atoi(fields[2])
+			results[index].STDERR = body
+		}
+	}
+
+	if !complete {
+		return nil, errors.New("batch output was truncated")
+	}
+	return results, nil
+}
+
 func (u *UnixCommandManager) isLocal() bool {
 	return u.Hostname == "localhost" || u.Hostname == "127.0.0.1"
 }
 
+// ShellQuote quotes s so that a POSIX shell passes it through as a single,
+// literal argument. Remote commands are run through the login shell, so
+// arguments must be quoted to match the semantics of a local exec.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, needsQuoting) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func needsQuoting(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	case strings.ContainsRune("-_./=:,+@%", r):
+		return false
+	}
+	return true
+}
+
+// getExitCode returns the exit status carried by the error of a local or SSH
+// command. A command that cannot be found counts as 127, as it does in a
+// shell.
 func getExitCode(err error) int {
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			status := exitError.Sys().(syscall.WaitStatus)
-			return status.ExitStatus()
-		}
+	var exitError *exec.ExitError
+	var sshExitError *ssh.ExitError
+	switch {
+	case errors.As(err, &exitError):
+		return exitError.ExitCode()
+	case errors.As(err, &sshExitError):
+		return sshExitError.ExitStatus()
+	case errors.Is(err, exec.ErrNotFound):
+		return 127
 	}
 	return 0
 }
@@ -3,6 +3,7 @@ package commandmanager
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -110,3 +111,131 @@ func TestRun(t *testing.T) {
 		t.Errorf("Expected Run with remote host to fail due to lack of mock, but it didn't")
 	}
 }
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":             "''",
+		"/etc/fstab":   "/etc/fstab",
+		"%F %Y %a":     "'%F %Y %a'",
+		"<":            "'<'",
+		"it's":         `'it'\''s'`,
+		"KEY=value,x+": "KEY=value,x+",
+	}
+
+	for input, expected := range tests {
+		if got := ShellQuote(input); got != expected {
+			t.Errorf("ShellQuote(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestCutFields(t *testing.T) {
+	tests := []struct {
+		line   string
+		n      int
+		fields []string
+		rest   string
+	}{
+		{"  1 root  /usr/bin/cmd  --flag x", 2, []string{"1", "root"}, "/usr/bin/cmd  --flag x"},
+		{"*/5\t*\t* * *\trun  job", 5, []string{"*/5", "*", "*", "*", "*"}, "run  job"},
+		{"a b", 3, []string{"a", "b"}, ""},
+		{"", 2, nil, ""},
+	}
+
+	for _, tt := range tests {
+		fields, rest := CutFields(tt.line, tt.n)
+		if strings.Join(fields, "|") != strings.Join(tt.fields, "|") || len(fields) != len(tt.fields) || rest != tt.rest {
+			t.Errorf("CutFields(%q, %d) = %q, %q, expected %q, %q", tt.line, tt.n, fields, rest, tt.fields, tt.rest)
+		}
+	}
+}
+
+func TestStreamLocal(t *testing.T) {
+	manager := UnixCommandManager{
+		Hostname: "localhost",
+	}
+
+	config := CommandConfig{
+		Command: "printf",
+		Args:    []string{"one\ntwo\nthree\n"},
+	}
+
+	var lines []string
+	err := manager.Stream(context.Background(), config, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(lines) != 3 || lines[2] != "three" {
+		t.Errorf("Expected 3 lines ending in 'three', got %v", lines)
+	}
+}
+
+func TestStreamLocalCancel(t *testing.T) {
+	manager := UnixCommandManager{
+		Hostname: "localhost",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := manager.Stream(ctx, CommandConfig{Command: "sleep", Args: []string{"10"}}, func(string) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Stream to stop with the context, got %v", err)
+	}
+}
+
+func TestRunBatchLocal(t *testing.T) {
+	manager := UnixCommandManager{
+		Hostname: "localhost",
+	}
+
+	results, err := manager.RunBatch(context.Background(), []CommandConfig{
+		{Command: "echo", Args: []string{"hello world"}},
+		{Command: "printf", Args: []string{"no newline"}},
+		{Command: "ls", Args: []string{"/does/not/exist"}},
+		{Command: "printenv", Args: []string{"STEELCUT_TEST"}, Env: []string{"STEELCUT_TEST=it's set"}},
+	})
+	if err != nil {
+		t.Fatalf("RunBatch failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	if results[0].STDOUT != "hello world\n" || results[0].ExitCode != 0 {
+		t.Errorf("Unexpected first result: %+v", results[0])
+	}
+	if results[1].STDOUT != "no newline" {
+		t.Errorf("Expected output without a trailing newline, got %q", results[1].STDOUT)
+	}
+	if results[2].ExitCode == 0 || results[2].STDERR == "" {
+		t.Errorf("Expected the failing command to report its exit code and stderr, got %+v", results[2])
+	}
+	if results[3].STDOUT != "it's set\n" {
+		t.Errorf("Expected the environment to be passed through, got %q", results[3].STDOUT)
+	}
+}
+
+func TestRunBatchMixedSudo(t *testing.T) {
+	manager := UnixCommandManager{
+		Hostname: "localhost",
+	}
+
+	_, err := manager.RunBatch(context.Background(), []CommandConfig{
+		{Command: "id"},
+		{Command: "id", Sudo: true},
+	})
+	if err == nil {
+		t.Errorf("Expected an error when mixing sudo and non-sudo commands")
+	}
+}
+
+func TestSplitBatchOutputTruncated(t *testing.T) {
+	marker := "@@steelcut-batch:abc:"
+	output := "\n" + marker + "out:0\npartial"
+
+	if _, err := splitBatchOutput(marker, output, []CommandConfig{{Command: "cat"}}); err == nil {
+		t.Errorf("Expected an error for truncated batch output")
+	}
+}
@@ -0,0 +1,37 @@
+package cronmanager
+
+// Job is a single cron entry.
+type Job struct {
+	// Name identifies jobs installed by steelcut. It is empty for entries
+	// that were not installed by steelcut.
+	Name     string `json:"name,omitempty"`
+	User     string `json:"user"`
+	Schedule string `json:"schedule"`
+	Command  string `json:"command"`
+	Source   string `json:"source"` // "crontab" or the path of the /etc/cron.d file
+}
+
+// CronManager manages cron jobs in user crontabs and in /etc/cron.d. Jobs are
+// identified by a marker comment on the line above them, so entries added
+// by hand are listed but never touched. The Ensure and Remove operations are
+// idempotent and report whether anything changed.
+type CronManager interface {
+	// ListJobs lists the jobs in user's crontab.
+	ListJobs(user string) ([]Job, error)
+
+	// EnsureJob installs or updates the named job in user's crontab.
+	EnsureJob(user, name, schedule, command string) (bool, error)
+
+	// RemoveJob removes the named job from user's crontab.
+	RemoveJob(user, name string) (bool, error)
+
+	// ListSystemJobs lists the jobs in every /etc/cron.d file.
+	ListSystemJobs() ([]Job, error)
+
+	// EnsureSystemJob installs or updates the named job in its own
+	// /etc/cron.d file, running command as user.
+	EnsureSystemJob(name, user, schedule, command string) (bool, error)
+
+	// RemoveSystemJob removes the /etc/cron.d file of the named job.
+	RemoveSystemJob(name string) (bool, error)
+}
@@ -0,0 +1,419 @@
+package cronmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+const (
+	// CronDir is where system jobs are installed, one file per job.
+	CronDir = "/etc/cron.d"
+
+	markerPrefix  = "# steelcut: "
+	crontabSource = "crontab"
+)
+
+var (
+	// jobName is restricted to what run-parts accepts in /etc/cron.d file names.
+	jobName  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	userName = regexp.MustCompile(`^[A-Za-z0-9._][A-Za-z0-9._-]*\$?$`)
+	envLine  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\s*=`)
+)
+
+var scheduleMacros = map[string]bool{
+	"@reboot": true, "@yearly": true, "@annually": true, "@monthly": true,
+	"@weekly": true, "@daily": true, "@midnight": true, "@hourly": true,
+}
+
+// scheduleFields are the bounds and names of the five cron time fields.
+var scheduleFields = []struct {
+	name     string
+	min, max int
+	names    []string // names[i] stands for min+i
+}{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// Both 0 and 7 are Sunday.
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+type UnixCronManager struct {
+	CommandManager cm.CommandManager
+	FileManager    filemanager.FileManager
+}
+
+func (ucm *UnixCronManager) ListJobs(user string) ([]Job, error) {
+	content, err := ucm.readCrontab(user)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCrontab(content, user, crontabSource, false), nil
+}
+
+// EnsureJob installs the job with crontab, so cron picks it up without a
+// restart. A % in command is a newline to cron and has to be escaped as \%;
+// a command with an unescaped % is rejected.
+func (ucm *UnixCronManager) EnsureJob(user, name, schedule, command string) (bool, error) {
+	if err := validateJob(user, name, schedule, command); err != nil {
+		return false, err
+	}
+
+	content, err := ucm.readCrontab(user)
+	if err != nil {
+		return false, err
+	}
+
+	updated, changed := updateCrontab(content, name, schedule+" "+command)
+	if !changed {
+		return false, nil
+	}
+	return true, ucm.installCrontab(user, updated)
+}
+
+func (ucm *UnixCronManager) RemoveJob(user, name string) (bool, error) {
+	if !userName.MatchString(user) {
+		return false, fmt.Errorf("invalid user: %q", user)
+	}
+	if !jobName.MatchString(name) {
+		return false, fmt.Errorf("invalid job name: %q", name)
+	}
+
+	content, err := ucm.readCrontab(user)
+	if err != nil {
+		return false, err
+	}
+
+	updated, changed := updateCrontab(content, name, "")
+	if !changed {
+		return false, nil
+	}
+	return true, ucm.installCrontab(user, updated)
+}
+
+func (ucm *UnixCronManager) ListSystemJobs() ([]Job, error) {
+	// grep -H prefixes every line with its file name, so all files are read
+	// in one command. It exits with 2 when the directory is empty or missing.
+	result, err := ucm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "grep -Hs '' " + CronDir + "/* || true"},
+		Sudo:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*strings.Builder)
+	var order []string
+	for _, line := range strings.Split(result.STDOUT, "\n") {
+		path, content, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		if _, ok := files[path]; !ok {
+			files[path] = &strings.Builder{}
+			order = append(order, path)
+		}
+		files[path].WriteString(content + "\n")
+	}
+
+	var jobs []Job
+	for _, path := range order {
+		jobs = append(jobs, ParseCrontab(files[path].String(), "", path, true)...)
+	}
+	return jobs, nil
+}
+
+func (ucm *UnixCronManager) EnsureSystemJob(name, user, schedule, command string) (bool, error) {
+	if err := validateJob(user, name, schedule, command); err != nil {
+		return false, err
+	}
+
+	path := systemJobPath(name)
+	desired := markerPrefix + name + "\n" + schedule + " " + user + " " + command + "\n"
+
+	current, err := filemanager.ReadFileIfExists(ucm.CommandManager, path, true)
+	if err != nil {
+		return false, err
+	}
+	if current == desired {
+		return false, nil
+	}
+
+	// cron ignores files in /etc/cron.d that are writable by group or others.
+	if err := ucm.FileManager.WriteFileAtomic(path, []byte(desired), filemanager.AtomicWriteOptions{Mode: 0644, NoBackup: true}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (ucm *UnixCronManager) RemoveSystemJob(name string) (bool, error) {
+	if !jobName.MatchString(name) {
+		return false, fmt.Errorf("invalid job name: %q", name)
+	}
+
+	path := cm.ShellQuote(systemJobPath(name))
+	result, err := ucm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "if [ -e " + path + " ]; then rm -f " + path + " && echo removed; fi"},
+		Sudo:    true,
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(result.STDOUT) == "removed", nil
+}
+
+func systemJobPath(name string) string {
+	return CronDir + "/steelcut-" + name
+}
+
+// readCrontab returns user's crontab, which is empty when the user has none.
+func (ucm *UnixCronManager) readCrontab(user string) (string, error) {
+	if !userName.MatchString(user) {
+		return "", fmt.Errorf("invalid user: %q", user)
+	}
+
+	result, err := ucm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "crontab",
+		Args:    []string{"-l", "-u", user},
+		Sudo:    true,
+	})
+	if strings.Contains(result.STDERR, "no crontab for") {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read crontab of %s: %w", user, err)
+	}
+	return result.STDOUT, nil
+}
+
+// installCrontab replaces user's crontab. The content is passed base64 encoded
+// so it survives the shell untouched.
+func (ucm *UnixCronManager) installCrontab(user, content string) error {
+	script := "printf '%s' " + base64.StdEncoding.EncodeToString([]byte(content)) + " | base64 -d | crontab -u " + cm.ShellQuote(user) + " -"
+	result, err := ucm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", script},
+		Sudo:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to install crontab of %s: %w: %s", user, err, strings.TrimSpace(result.STDERR))
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to install crontab of %s: %s", user, strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
+
+func validateJob(user, name, schedule, command string) error {
+	if !userName.MatchString(user) {
+		return fmt.Errorf("invalid user: %q", user)
+	}
+	if !jobName.MatchString(name) {
+		return fmt.Errorf("invalid job name: %q", name)
+	}
+	if strings.TrimSpace(command) == "" || strings.ContainsAny(command, "\r\n") {
+		return fmt.Errorf("invalid command for job %s: %q", name, command)
+	}
+	if hasUnescapedPercent(command) {
+		return fmt.Errorf("command for job %s has a %% that is not escaped as \\%%: %q", name, command)
+	}
+	return ValidateSchedule(schedule)
+}
+
+// hasUnescapedPercent reports whether command has a % that cron would turn
+// into a newline, that is one without a backslash in front of it.
+func hasUnescapedPercent(command string) bool {
+	for i := 0; i < len(command); i++ {
+		if command[i] == '%' && (i == 0 || command[i-1] != '\\') {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSchedule checks a cron schedule: either one of the @ macros or five
+// fields of numbers, ranges, lists and steps, with month and day names allowed.
+func ValidateSchedule(schedule string) error {
+	fields := strings.Fields(schedule)
+	if len(fields) == 1 && strings.HasPrefix(fields[0], "@") {
+		if !scheduleMacros[fields[0]] {
+			return fmt.Errorf("unknown schedule macro: %s", fields[0])
+		}
+		return nil
+	}
+	if len(fields) != len(scheduleFields) {
+		return fmt.Errorf("schedule %q must have %d fields, has %d", schedule, len(scheduleFields), len(fields))
+	}
+
+	for i, field := range fields {
+		spec := scheduleFields[i]
+		for _, item := range strings.Split(field, ",") {
+			if err := validateScheduleItem(item, spec.min, spec.max, spec.names); err != nil {
+				return fmt.Errorf("invalid %s in schedule %q: %w", spec.name, schedule, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateScheduleItem checks one list item: *, a value or a range, with an optional /step.
+func validateScheduleItem(item string, min, max int, names []string) error {
+	base, step, hasStep := strings.Cut(item, "/")
+	if hasStep {
+		n, err := strconv.Atoi(step)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad step %q", step)
+		}
+	}
+
+	if base == "*" {
+		return nil
+	}
+
+	low, high, isRange := strings.Cut(base, "-")
+	lowValue, err := scheduleValue(low, min, max, names)
+	if err != nil {
+		return err
+	}
+	if !isRange {
+		if hasStep {
+			// Vixie cron needs a range or * before a step.
+			return fmt.Errorf("step without a range in %q", item)
+		}
+		return nil
+	}
+
+	highValue, err := scheduleValue(high, min, max, names)
+	if err != nil {
+		return err
+	}
+	if highValue < lowValue {
+		return fmt.Errorf("backwards range %q", base)
+	}
+	return nil
+}
+
+func scheduleValue(value string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(value, name) {
+			return min + i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", value)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("%d is outside %d-%d", n, min, max)
+	}
+	return n, nil
+}
+
+// ParseCrontab parses the jobs in a crontab. System crontabs, such as the
+// files in /etc/cron.d, have a user column between the schedule and the
+// command; for user crontabs user is filled in instead. Comments, blank lines
+// and environment assignments are skipped.
+func ParseCrontab(content, user, source string, system bool) []Job {
+	var jobs []Job
+	var pendingName string
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if name, found := strings.CutPrefix(trimmed, markerPrefix); found {
+			pendingName = strings.TrimSpace(name)
+			continue
+		}
+		if !isJobLine(trimmed) {
+			pendingName = ""
+			continue
+		}
+
+		scheduleLen := len(scheduleFields)
+		if strings.HasPrefix(trimmed, "@") {
+			scheduleLen = 1
+		}
+		columns := scheduleLen + 1
+		if system {
+			columns++
+		}
+
+		fields, command := cm.CutFields(trimmed, columns-1)
+		if len(fields) < columns-1 || command == "" {
+			pendingName = ""
+			continue
+		}
+
+		job := Job{
+			Name:     pendingName,
+			User:     user,
+			Schedule: strings.Join(fields[:scheduleLen], " "),
+			Command:  command,
+			Source:   source,
+		}
+		if system {
+			job.User = fields[scheduleLen]
+		}
+		jobs = append(jobs, job)
+		pendingName = ""
+	}
+
+	return jobs
+}
+
+// isJobLine reports whether a trimmed crontab line is a job rather than a
+// blank line, a comment or an environment assignment.
+func isJobLine(trimmed string) bool {
+	return trimmed != "" && !strings.HasPrefix(trimmed, "#") && !envLine.MatchString(trimmed)
+}
+
+// updateCrontab replaces the entry below the named marker with entry, or
+// appends the marker and entry when there is none. An empty entry removes the
+// job. Every other line is kept as it is.
+func updateCrontab(content, name, entry string) (string, bool) {
+	marker := markerPrefix + name
+
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	var updated []string
+	found := false
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != marker {
+			updated = append(updated, lines[i])
+			continue
+		}
+
+		// Skip the marker and the job line below it. Anything else below the
+		// marker, such as a comment or a blank line, was not installed by
+		// steelcut and is kept.
+		if i+1 < len(lines) && isJobLine(strings.TrimSpace(lines[i+1])) {
+			i++
+		}
+		if entry != "" && !found {
+			updated = append(updated, marker, entry)
+		}
+		found = true
+	}
+	if !found && entry != "" {
+		updated = append(updated, marker, entry)
+	}
+
+	result := ""
+	if len(updated) > 0 {
+		result = strings.Join(updated, "\n") + "\n"
+	}
+	return result, result != content
+}
@@ -0,0 +1,290 @@
+package cronmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+// MockCommandManager answers by full command line and records what was run.
+// Commands in ExitCodes fail with that status, commands in Errors fail to run.
+type MockCommandManager struct {
+	Outputs   map[string]string
+	Stderr    map[string]string
+	ExitCodes map[string]int
+	Errors    map[string]error
+	Configs   []cm.CommandConfig
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Configs = append(m.Configs, config)
+	line := commandLine(config)
+	if err := m.Errors[line]; err != nil {
+		return cm.CommandResult{}, err
+	}
+	result := cm.CommandResult{STDOUT: m.Outputs[line], STDERR: m.Stderr[line], ExitCode: m.ExitCodes[line]}
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("Process exited with status %d", result.ExitCode)
+	}
+	return result, nil
+}
+
+// MockFileManager records atomic writes, embedding the interface so only
+// WriteFileAtomic needs implementing. Every write fails with Err when it is set.
+type MockFileManager struct {
+	filemanager.FileManager
+	Writes map[string]string
+	Err    error
+}
+
+func (m *MockFileManager) WriteFileAtomic(path string, content []byte, opts filemanager.AtomicWriteOptions) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.Writes == nil {
+		m.Writes = make(map[string]string)
+	}
+	m.Writes[path] = string(content)
+	return nil
+}
+
+// installed decodes the crontab passed to the last crontab install, if any.
+func (m *MockCommandManager) installed(t *testing.T) (string, bool) {
+	t.Helper()
+	for i := len(m.Configs) - 1; i >= 0; i-- {
+		script := strings.Join(m.Configs[i].Args, " ")
+		if !strings.Contains(script, "| crontab -u") {
+			continue
+		}
+		encoded := strings.Fields(strings.TrimPrefix(script, "-c printf '%s' "))[0]
+		content, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("bad install script %q: %v", script, err)
+		}
+		return string(content), true
+	}
+	return "", false
+}
+
+func commandLine(config cm.CommandConfig) string {
+	return strings.Join(append([]string{config.Command}, config.Args...), " ")
+}
+
+const sampleCrontab = `SHELL=/bin/bash
+MAILTO=ops@example.com
+# m h dom mon dow command
+*/5 * * * * /usr/local/bin/poll  --quiet
+# steelcut: backup
+30 2 * * 1-5 /usr/local/bin/backup > /var/log/backup.log 2>&1
+@reboot /usr/local/bin/warmup
+`
+
+func TestParseCrontab(t *testing.T) {
+	jobs := ParseCrontab(sampleCrontab, "alice", "crontab", false)
+
+	expected := []Job{
+		{User: "alice", Schedule: "*/5 * * * *", Command: "/usr/local/bin/poll  --quiet", Source: "crontab"},
+		{Name: "backup", User: "alice", Schedule: "30 2 * * 1-5", Command: "/usr/local/bin/backup > /var/log/backup.log 2>&1", Source: "crontab"},
+		{User: "alice", Schedule: "@reboot", Command: "/usr/local/bin/warmup", Source: "crontab"},
+	}
+	if !reflect.DeepEqual(jobs, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, jobs)
+	}
+}
+
+func TestParseSystemCrontab(t *testing.T) {
+	content := "PATH=/usr/bin:/bin\n# steelcut: rotate\n0 3 * * * root /usr/sbin/logrotate /etc/logrotate.conf\n@hourly www-data php /srv/cron.php\n"
+	jobs := ParseCrontab(content, "", "/etc/cron.d/steelcut-rotate", true)
+
+	expected := []Job{
+		{Name: "rotate", User: "root", Schedule: "0 3 * * *", Command: "/usr/sbin/logrotate /etc/logrotate.conf", Source: "/etc/cron.d/steelcut-rotate"},
+		{User: "www-data", Schedule: "@hourly", Command: "php /srv/cron.php", Source: "/etc/cron.d/steelcut-rotate"},
+	}
+	if !reflect.DeepEqual(jobs, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, jobs)
+	}
+}
+
+func TestUpdateCrontab(t *testing.T) {
+	updated, changed := updateCrontab(sampleCrontab, "backup", "0 4 * * * /usr/local/bin/backup")
+	if !changed {
+		t.Fatal("Expected the backup job to change")
+	}
+	expected := strings.Replace(sampleCrontab, "30 2 * * 1-5 /usr/local/bin/backup > /var/log/backup.log 2>&1", "0 4 * * * /usr/local/bin/backup", 1)
+	if updated != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, updated)
+	}
+
+	if _, changed := updateCrontab(updated, "backup", "0 4 * * * /usr/local/bin/backup"); changed {
+		t.Error("Expected no change when the job is already installed")
+	}
+
+	removed, changed := updateCrontab(updated, "backup", "")
+	if !changed || strings.Contains(removed, "backup") {
+		t.Errorf("Expected the backup job to be removed, got:\n%s", removed)
+	}
+	if !strings.Contains(removed, "/usr/local/bin/poll") || !strings.Contains(removed, "@reboot") {
+		t.Errorf("Expected other jobs to be kept, got:\n%s", removed)
+	}
+
+	// A marker left without its job keeps the lines below it.
+	orphaned := "# steelcut: report\n# keep this note\nMAILTO=ops\n"
+	readded, _ := updateCrontab(orphaned, "report", "@daily /usr/local/bin/report")
+	if readded != "# steelcut: report\n@daily /usr/local/bin/report\n# keep this note\nMAILTO=ops\n" {
+		t.Errorf("Expected the lines below an orphaned marker to be kept, got %q", readded)
+	}
+
+	added, _ := updateCrontab("", "report", "@daily /usr/local/bin/report")
+	if added != "# steelcut: report\n@daily /usr/local/bin/report\n" {
+		t.Errorf("Unexpected new crontab: %q", added)
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	valid := []string{"* * * * *", "*/15 0-6,22,23 1 jan-mar mon-fri", "0 0 * * 7", "5 4 * * SUN", "@daily", "0-30/10 * * * *"}
+	for _, schedule := range valid {
+		if err := ValidateSchedule(schedule); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", schedule, err)
+		}
+	}
+
+	invalid := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5/10 * * * *", "10-5 * * * *", "@fortnightly", "a * * * *"}
+	for _, schedule := range invalid {
+		if err := ValidateSchedule(schedule); err == nil {
+			t.Errorf("Expected %q to be invalid", schedule)
+		}
+	}
+}
+
+func TestEnsureJob(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Stderr:    map[string]string{"crontab -l -u alice": "no crontab for alice\n"},
+		ExitCodes: map[string]int{"crontab -l -u alice": 1},
+	}
+	manager := &UnixCronManager{CommandManager: mockCmd}
+
+	changed, err := manager.EnsureJob("alice", "report", "0 6 * * *", "/usr/local/bin/report")
+	if err != nil || !changed {
+		t.Fatalf("Expected the job to be installed, got changed=%v err=%v", changed, err)
+	}
+	content, ok := mockCmd.installed(t)
+	if !ok || content != "# steelcut: report\n0 6 * * * /usr/local/bin/report\n" {
+		t.Errorf("Unexpected crontab installed: %q", content)
+	}
+
+	if _, err := manager.EnsureJob("alice", "report", "0 25 * * *", "/usr/local/bin/report"); err == nil {
+		t.Error("Expected an invalid schedule to be rejected")
+	}
+	if _, err := manager.EnsureJob("alice", "report", "0 6 * * *", "/usr/local/bin/report --date $(date +%F)"); err == nil {
+		t.Error("Expected an unescaped % to be rejected")
+	}
+	if _, err := manager.EnsureJob("alice", "report", "0 6 * * *", "/usr/local/bin/report --date $(date +\\%F)"); err != nil {
+		t.Errorf("Expected an escaped %% to be accepted, got %v", err)
+	}
+}
+
+func TestEnsureJobUnchanged(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{"crontab -l -u alice": sampleCrontab},
+	}
+	manager := &UnixCronManager{CommandManager: mockCmd}
+
+	changed, err := manager.EnsureJob("alice", "backup", "30 2 * * 1-5", "/usr/local/bin/backup > /var/log/backup.log 2>&1")
+	if err != nil || changed {
+		t.Fatalf("Expected no change, got changed=%v err=%v", changed, err)
+	}
+	if _, ok := mockCmd.installed(t); ok {
+		t.Error("Expected the crontab not to be reinstalled")
+	}
+}
+
+func TestEnsureJobFailures(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Stderr:    map[string]string{"crontab -l -u alice": "crontab: your UID isn't in the passwd file\n"},
+		ExitCodes: map[string]int{"crontab -l -u alice": 1},
+	}
+	manager := &UnixCronManager{CommandManager: mockCmd}
+
+	if _, err := manager.EnsureJob("alice", "report", "0 6 * * *", "/usr/local/bin/report"); err == nil {
+		t.Error("Expected a failed crontab read to be an error")
+	}
+	if _, ok := mockCmd.installed(t); ok {
+		t.Error("Expected no crontab to be installed after a failed read")
+	}
+
+	mockCmd.Stderr["crontab -l -u alice"] = "no crontab for alice\n"
+	install := "sh -c printf '%s' " + base64.StdEncoding.EncodeToString([]byte("# steelcut: report\n0 6 * * * /usr/local/bin/report\n")) + " | base64 -d | crontab -u alice -"
+	mockCmd.ExitCodes[install] = 1
+	if _, err := manager.EnsureJob("alice", "report", "0 6 * * *", "/usr/local/bin/report"); err == nil {
+		t.Error("Expected a failed crontab install to be an error")
+	}
+}
+
+func TestEnsureSystemJob(t *testing.T) {
+	path := systemJobPath("backup")
+	read := "sh -c if test -e " + path + "; then cat " + path + "; fi"
+	mockCmd := &MockCommandManager{}
+	mockFile := &MockFileManager{}
+	manager := &UnixCronManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	changed, err := manager.EnsureSystemJob("backup", "root", "30 2 * * *", "/usr/local/bin/backup")
+	if err != nil || !changed {
+		t.Fatalf("Expected the job to be installed, got changed=%v err=%v", changed, err)
+	}
+	expected := "# steelcut: backup\n30 2 * * * root /usr/local/bin/backup\n"
+	if mockFile.Writes[path] != expected {
+		t.Errorf("Unexpected cron.d file: %q", mockFile.Writes[path])
+	}
+	if !mockCmd.Configs[0].Sudo {
+		t.Error("Expected the cron.d file to be read with sudo")
+	}
+
+	mockCmd.Outputs = map[string]string{read: expected}
+	mockFile.Writes = nil
+	if changed, err := manager.EnsureSystemJob("backup", "root", "30 2 * * *", "/usr/local/bin/backup"); err != nil || changed || mockFile.Writes != nil {
+		t.Errorf("Expected no change, got changed=%v err=%v", changed, err)
+	}
+}
+
+func TestEnsureSystemJobFailures(t *testing.T) {
+	path := systemJobPath("backup")
+	read := "sh -c if test -e " + path + "; then cat " + path + "; fi"
+	mockCmd := &MockCommandManager{ExitCodes: map[string]int{read: 1}}
+	mockFile := &MockFileManager{}
+	manager := &UnixCronManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	// An unreadable file must not be mistaken for a missing one and replaced.
+	if _, err := manager.EnsureSystemJob("backup", "root", "30 2 * * *", "/usr/local/bin/backup"); err == nil {
+		t.Error("Expected a failed read to be an error")
+	}
+	if mockFile.Writes != nil {
+		t.Errorf("Expected nothing to be written, got %v", mockFile.Writes)
+	}
+
+	mockCmd.ExitCodes = nil
+	mockCmd.Errors = map[string]error{read: errors.New("connection lost")}
+	if _, err := manager.EnsureSystemJob("backup", "root", "30 2 * * *", "/usr/local/bin/backup"); err == nil {
+		t.Error("Expected a read that could not run to be an error")
+	}
+
+	mockCmd.Errors = nil
+	mockFile.Err = errors.New("permission denied")
+	if _, err := manager.EnsureSystemJob("backup", "root", "30 2 * * *", "/usr/local/bin/backup"); err == nil {
+		t.Error("Expected a failed write to be an error")
+	}
+}
@@ -12,10 +12,11 @@ type UnixEnvironmentManager struct {
 }
 
 func (e *UnixEnvironmentManager) Get(key string) (string, error) {
-	output, err := e.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	// printenv exits with 1 when the variable is not set.
+	output, err := cm.RunChecked(context.TODO(), e.CommandManager, cm.CommandConfig{
 		Command: "printenv",
 		Args:    []string{key},
-	})
+	}, func(status int) bool { return status == 1 })
 	if err != nil {
 		return "", err
 	}
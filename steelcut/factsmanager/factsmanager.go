@@ -0,0 +1,40 @@
+package factsmanager
+
+import (
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// Facts is a snapshot of what is known about a host.
+type Facts struct {
+	Hostname       string                     `json:"hostname"`
+	FQDN           string                     `json:"fqdn"`
+	Kernel         string                     `json:"kernel"`
+	KernelVersion  string                     `json:"kernelVersion"`
+	Architecture   string                     `json:"architecture"`
+	DistroID       string                     `json:"distroId"`
+	DistroVersion  string                     `json:"distroVersion"`
+	DistroName     string                     `json:"distroName"`
+	Virtualization string                     `json:"virtualization"` // e.g. kvm, docker or none
+	Interfaces     []networkmanager.Interface `json:"interfaces"`
+	Mounts         []filemanager.Mount        `json:"mounts"`
+	MemoryTotal    int64                      `json:"memoryTotal"` // bytes
+	SwapTotal      int64                      `json:"swapTotal"`   // bytes
+	DefaultGateway string                     `json:"defaultGateway"`
+	DNSServers     []string                   `json:"dnsServers"`
+	SearchDomains  []string                   `json:"searchDomains"`
+	Timezone       string                     `json:"timezone"`
+	BootTime       time.Time                  `json:"bootTime"`
+	GatheredAt     time.Time                  `json:"gatheredAt"`
+}
+
+// FactsManager gathers facts about a host.
+type FactsManager interface {
+	// Facts returns the cached facts, gathering them again once they are older than the TTL.
+	Facts() (Facts, error)
+
+	// Refresh gathers the facts regardless of the cache.
+	Refresh() (Facts, error)
+}
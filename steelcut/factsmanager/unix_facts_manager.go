@@ -0,0 +1,229 @@
+package factsmanager
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// DefaultTTL is how long gathered facts are reused when UnixFactsManager.TTL is unset.
+const DefaultTTL = 5 * time.Minute
+
+// factsSections are gathered in a single batch. Each command is allowed to
+// fail, as not every source exists on every host.
+var factsSections = []struct {
+	name    string
+	command string
+}{
+	{"hostname", "hostname"},
+	{"fqdn", "hostname -f"},
+	{"uname", "uname -s; uname -r; uname -m"},
+	{"os-release", "cat /etc/os-release"},
+	{"sw_vers", "sw_vers -productName; sw_vers -productVersion"},
+	{"virtualization", `v=$(systemd-detect-virt 2>/dev/null); if [ -n "$v" ]; then echo "$v"; elif [ -f /.dockerenv ]; then echo docker; elif grep -q '^flags.* hypervisor' /proc/cpuinfo; then echo vm; else echo none; fi`},
+	{"addresses", networkmanager.InterfacesCommand},
+	{"df", "df -P -B1 -T"},
+	{"df-inodes", "df -P -i"},
+	{"meminfo", "cat /proc/meminfo"},
+	{"memsize", "sysctl -n hw.memsize"},
+	{"route", "ip route show default"},
+	{"resolv", "cat /etc/resolv.conf"},
+	{"timezone", "timedatectl show -p Timezone --value || cat /etc/timezone || readlink /etc/localtime"},
+	{"boottime", "grep '^btime' /proc/stat || sysctl -n kern.boottime"},
+}
+
+type UnixFactsManager struct {
+	CommandManager cm.CommandManager
+	TTL            time.Duration // how long facts are cached; defaults to DefaultTTL
+
+	mu    sync.Mutex
+	facts *Facts
+}
+
+// Facts returns the cached facts, gathering them first if the cache is empty or stale.
+func (ufm *UnixFactsManager) Facts() (Facts, error) {
+	ttl := ufm.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	ufm.mu.Lock()
+	cached := ufm.facts
+	ufm.mu.Unlock()
+
+	if cached != nil && time.Since(cached.GatheredAt) < ttl {
+		return *cached, nil
+	}
+	return ufm.Refresh()
+}
+
+// Refresh gathers all facts in a single round trip to the host and updates the cache.
+func (ufm *UnixFactsManager) Refresh() (Facts, error) {
+	configs := make([]cm.CommandConfig, len(factsSections))
+	for i, section := range factsSections {
+		configs[i] = cm.CommandConfig{
+			Command: "sh",
+			Args:    []string{"-c", section.command},
+		}
+	}
+
+	results, err := cm.RunBatch(context.TODO(), ufm.CommandManager, configs)
+	if err != nil {
+		return Facts{}, err
+	}
+
+	sections := make(map[string]string)
+	for i, section := range factsSections {
+		sections[section.name] = results[i].STDOUT
+	}
+
+	facts, err := parseFacts(sections)
+	if err != nil {
+		return Facts{}, err
+	}
+	facts.GatheredAt = time.Now()
+
+	ufm.mu.Lock()
+	ufm.facts = &facts
+	ufm.mu.Unlock()
+
+	return facts, nil
+}
+
+func parseFacts(sections map[string]string) (Facts, error) {
+	if strings.TrimSpace(sections["uname"]) == "" {
+		return Facts{}, errors.New("no facts were gathered")
+	}
+
+	facts := Facts{
+		Hostname:       strings.TrimSpace(sections["hostname"]),
+		FQDN:           strings.TrimSpace(sections["fqdn"]),
+		Virtualization: strings.TrimSpace(sections["virtualization"]),
+		DefaultGateway: parseDefaultGateway(sections["route"]),
+		Timezone:       parseTimezone(sections["timezone"]),
+		BootTime:       parseBootTime(sections["boottime"]),
+	}
+
+	uname := strings.Split(strings.TrimSpace(sections["uname"]), "\n")
+	if len(uname) == 3 {
+		facts.Kernel, facts.KernelVersion, facts.Architecture = uname[0], uname[1], uname[2]
+	}
+
+	if osRelease := parseOSRelease(sections["os-release"]); len(osRelease) > 0 {
+		facts.DistroID = osRelease["ID"]
+		facts.DistroVersion = osRelease["VERSION_ID"]
+		facts.DistroName = osRelease["PRETTY_NAME"]
+	} else if swVers := strings.Split(strings.TrimSpace(sections["sw_vers"]), "\n"); len(swVers) == 2 {
+		facts.DistroID = "macos"
+		facts.DistroName = swVers[0]
+		facts.DistroVersion = swVers[1]
+	}
+
+	facts.MemoryTotal = parseMeminfo(sections["meminfo"], "MemTotal")
+	facts.SwapTotal = parseMeminfo(sections["meminfo"], "SwapTotal")
+	if facts.MemoryTotal == 0 {
+		facts.MemoryTotal, _ = strconv.ParseInt(strings.TrimSpace(sections["memsize"]), 10, 64)
+	}
+
+	// Interfaces are best effort like the other sections.
+	facts.Interfaces, _ = networkmanager.ParseInterfaces(sections["addresses"])
+
+	dns := networkmanager.ParseResolvConf(sections["resolv"])
+	facts.DNSServers, facts.SearchDomains = dns.Servers, dns.Search
+
+	if strings.TrimSpace(sections["df"]) != "" {
+		mounts, err := filemanager.ParseMounts(sections["df"], sections["df-inodes"])
+		if err != nil {
+			return Facts{}, err
+		}
+		facts.Mounts = mounts
+	}
+
+	return facts, nil
+}
+
+// parseOSRelease parses the KEY=value pairs of /etc/os-release.
+func parseOSRelease(content string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// parseMeminfo returns a /proc/meminfo value in bytes, or zero if it is missing.
+func parseMeminfo(content, key string) int64 {
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, key+":") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			return 0
+		}
+		kbValue, _ := strconv.ParseInt(parts[1], 10, 64)
+		return kbValue * 1024
+	}
+	return 0
+}
+
+// parseDefaultGateway extracts the gateway from ip route show default.
+func parseDefaultGateway(output string) string {
+	fields := strings.Fields(output)
+	for i, field := range fields {
+		if field == "via" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+// parseTimezone takes the first answer from timedatectl, /etc/timezone or the
+// /etc/localtime symlink, which points into the zoneinfo database.
+func parseTimezone(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	if _, zone, found := strings.Cut(line, "zoneinfo/"); found {
+		return zone
+	}
+	return line
+}
+
+// parseBootTime handles both the Linux btime line and Darwin's kern.boottime.
+func parseBootTime(output string) time.Time {
+	output = strings.TrimSpace(output)
+
+	if strings.HasPrefix(output, "btime") {
+		fields := strings.Fields(output)
+		if len(fields) == 2 {
+			if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				return time.Unix(seconds, 0)
+			}
+		}
+	}
+
+	// { sec = 1700000000, usec = 0 } Tue Nov 14 22:13:20 2023
+	if _, rest, found := strings.Cut(output, "sec = "); found {
+		secondsStr, _, _ := strings.Cut(rest, ",")
+		if seconds, err := strconv.ParseInt(secondsStr, 10, 64); err == nil {
+			return time.Unix(seconds, 0)
+		}
+	}
+
+	return time.Time{}
+}
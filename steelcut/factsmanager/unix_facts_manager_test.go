@@ -0,0 +1,168 @@
+package factsmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// MockCommandManager answers each facts section from a fixture keyed by section name.
+type MockCommandManager struct {
+	Sections map[string]string
+	Err      error
+	Batches  int
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	for _, section := range factsSections {
+		if config.Args[1] == section.command {
+			return cm.CommandResult{STDOUT: m.Sections[section.name]}, m.Err
+		}
+	}
+	return cm.CommandResult{ExitCode: 127}, m.Err
+}
+
+func (m *MockCommandManager) RunBatch(ctx context.Context, configs []cm.CommandConfig) ([]cm.CommandResult, error) {
+	m.Batches++
+	results := make([]cm.CommandResult, len(configs))
+	for i, config := range configs {
+		results[i], _ = m.Run(ctx, config)
+	}
+	return results, m.Err
+}
+
+// fixture splits a facts listing into sections on its "@@name" lines.
+func fixture(listing string) map[string]string {
+	sections := make(map[string]string)
+	var name string
+	for _, line := range strings.SplitAfter(listing, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			name = strings.TrimSpace(strings.TrimPrefix(line, "@@"))
+			continue
+		}
+		sections[name] += line
+	}
+	return sections
+}
+
+const linuxFacts = `@@hostname
+web1
+@@fqdn
+web1.example.com
+@@uname
+Linux
+6.1.0-18-amd64
+x86_64
+@@os-release
+PRETTY_NAME="Debian GNU/Linux 12 (bookworm)"
+NAME="Debian GNU/Linux"
+VERSION_ID="12"
+ID=debian
+@@sw_vers
+@@virtualization
+kvm
+@@addresses
+[{"ifindex":1,"ifname":"lo","flags":["LOOPBACK","UP","LOWER_UP"],"mtu":65536,"operstate":"UNKNOWN","link_type":"loopback","address":"00:00:00:00:00:00","addr_info":[{"family":"inet","local":"127.0.0.1","prefixlen":8,"scope":"host"}]},{"ifindex":2,"ifname":"eth0","flags":["BROADCAST","MULTICAST","UP","LOWER_UP"],"mtu":1500,"operstate":"UP","link_type":"ether","address":"52:54:00:12:34:56","addr_info":[{"family":"inet","local":"10.0.0.5","prefixlen":24,"scope":"global"},{"family":"inet6","local":"fe80::1","prefixlen":64,"scope":"link"}]}]
+@@df
+Filesystem     Type     1-blocks        Used   Available Capacity Mounted on
+/dev/vda1      ext4     1000 250 750      25% /
+@@df-inodes
+Filesystem       Inodes  IUsed    IFree IUse% Mounted on
+/dev/vda1      100 10 90    10% /
+@@meminfo
+MemTotal:        4030328 kB
+MemFree:          302612 kB
+SwapTotal:       1048572 kB
+@@memsize
+@@route
+default via 10.0.0.1 dev eth0 proto dhcp metric 100
+@@resolv
+# Generated by NetworkManager
+search example.com corp.example.com
+nameserver 10.0.0.2
+nameserver 10.0.0.3
+@@timezone
+/usr/share/zoneinfo/Europe/Berlin
+@@boottime
+btime 1700000000
+`
+
+func TestRefresh(t *testing.T) {
+	mockCmd := &MockCommandManager{Sections: fixture(linuxFacts)}
+	manager := UnixFactsManager{CommandManager: mockCmd}
+
+	facts, err := manager.Refresh()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if facts.FQDN != "web1.example.com" || facts.Architecture != "x86_64" || facts.KernelVersion != "6.1.0-18-amd64" {
+		t.Errorf("Unexpected host facts: %+v", facts)
+	}
+	if facts.DistroID != "debian" || facts.DistroVersion != "12" || facts.DistroName != "Debian GNU/Linux 12 (bookworm)" {
+		t.Errorf("Unexpected distro facts: %+v", facts)
+	}
+	if facts.Virtualization != "kvm" {
+		t.Errorf("Expected kvm, got: %s", facts.Virtualization)
+	}
+	if len(facts.Interfaces) != 2 || len(facts.Interfaces[1].Addresses) != 2 || facts.Interfaces[1].Addresses[0].CIDR() != "10.0.0.5/24" {
+		t.Errorf("Unexpected interfaces: %+v", facts.Interfaces)
+	}
+	if len(facts.Mounts) != 1 || facts.Mounts[0].Inodes.UsePercent != 10 {
+		t.Errorf("Unexpected mounts: %+v", facts.Mounts)
+	}
+	if facts.MemoryTotal != 4030328*1024 || facts.SwapTotal != 1048572*1024 {
+		t.Errorf("Unexpected memory totals: %d %d", facts.MemoryTotal, facts.SwapTotal)
+	}
+	if facts.DefaultGateway != "10.0.0.1" {
+		t.Errorf("Expected gateway 10.0.0.1, got: %s", facts.DefaultGateway)
+	}
+	if len(facts.DNSServers) != 2 || len(facts.SearchDomains) != 2 {
+		t.Errorf("Unexpected resolver facts: %v %v", facts.DNSServers, facts.SearchDomains)
+	}
+	if facts.Timezone != "Europe/Berlin" {
+		t.Errorf("Expected Europe/Berlin, got: %s", facts.Timezone)
+	}
+	if !facts.BootTime.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected boot time: %v", facts.BootTime)
+	}
+}
+
+func TestFactsCache(t *testing.T) {
+	mockCmd := &MockCommandManager{Sections: fixture(linuxFacts)}
+	manager := UnixFactsManager{CommandManager: mockCmd, TTL: time.Hour}
+
+	for i := 0; i < 3; i++ {
+		if _, err := manager.Facts(); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if mockCmd.Batches != 1 {
+		t.Errorf("Expected facts to be gathered once, got %d round trips", mockCmd.Batches)
+	}
+
+	if _, err := manager.Refresh(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if mockCmd.Batches != 2 {
+		t.Errorf("Expected Refresh to bypass the cache")
+	}
+}
+
+func TestParseBootTimeDarwin(t *testing.T) {
+	bootTime := parseBootTime("{ sec = 1700000000, usec = 0 } Tue Nov 14 22:13:20 2023\n")
+	if !bootTime.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected boot time: %v", bootTime)
+	}
+}
@@ -1,7 +1,10 @@
 package filemanager
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -23,12 +26,51 @@ type FileOperations interface {
 	MoveFile(sourcePath, destPath string) error
 	CopyFile(sourcePath, destPath string) error
 	GetFileAttributes(path string) (File, error)
+	ReadFile(path string) ([]byte, error)
+	Checksum(path string, algo ChecksumAlgorithm) (string, error)
+
+	// WriteFileAtomic replaces the contents of path without ever leaving a
+	// partially written file behind, keeping a backup of the previous version.
+	WriteFileAtomic(path string, content []byte, opts AtomicWriteOptions) error
+	ListBackups(path string) ([]Backup, error)
+	Restore(path, backupID string) error
+
+	// Tail returns the last lines of a file on a channel. With follow set it
+	// keeps streaming new lines, across log rotation, until ctx is cancelled.
+	Tail(ctx context.Context, path string, lines int, follow bool) (<-chan string, error)
+}
+
+// SecurityOperations manages POSIX ACLs, extended attributes and SELinux labels.
+// Operations return an *UnsupportedError when the host lacks the tooling for them.
+type SecurityOperations interface {
+	GetACL(path string) ([]ACLEntry, error)
+	SetACL(path string, entries []ACLEntry) error
+	GetXAttrs(path string) (map[string]string, error)
+	SetXAttr(path, name, value string) error
+	RemoveXAttr(path, name string) error
+	GetSELinuxContext(path string) (SELinuxContext, error)
+	SetSELinuxContext(path string, label SELinuxContext) error
+	RestoreSELinuxContext(path string) error
+
+	// Idempotent security management
+	EnsureACL(path string, entries []ACLEntry) error
+	EnsureSELinuxContext(path string, label SELinuxContext) error
+}
+
+// MountOperations represents operations on mounted filesystems and /etc/fstab.
+type MountOperations interface {
+	ListMounts() ([]Mount, error)
+
+	// Idempotent mount management
+	EnsureMount(spec MountSpec) error
 }
 
 // FileManager encompasses operations on both files and directories.
 type FileManager interface {
 	FileOperations
 	DirOperations
+	SecurityOperations
+	MountOperations
 }
 
 // File describes basic file attributes.
@@ -39,6 +81,30 @@ type File struct {
 	Modified time.Time
 }
 
+// ChecksumAlgorithm names a digest algorithm supported by Checksum.
+type ChecksumAlgorithm string
+
+const (
+	SHA256 ChecksumAlgorithm = "sha256"
+	SHA1   ChecksumAlgorithm = "sha1"
+	MD5    ChecksumAlgorithm = "md5"
+)
+
+// AtomicWriteOptions controls how WriteFileAtomic replaces a file.
+type AtomicWriteOptions struct {
+	Mode     os.FileMode // mode of the new file; zero keeps the mode of the existing file
+	Validate string      // command run against the temp file before it is swapped in; %s is replaced by its path
+	NoBackup bool        // skip backing up the existing file
+	Retain   int         // number of backups to keep; zero means DefaultBackupRetain, negative keeps all
+}
+
+// Backup describes a saved copy of a file taken before it was overwritten.
+type Backup struct {
+	ID      string // timestamp based identifier, passed to Restore
+	Path    string // location of the backup on the host
+	Created time.Time
+}
+
 type DiskUsageInfo struct {
 	Total      int64   // total space in bytes
 	Used       int64   // used space in bytes
@@ -46,9 +112,128 @@ type DiskUsageInfo struct {
 	UsePercent float64 // usage percentage
 }
 
+// InodeUsageInfo describes inode usage of a filesystem.
+type InodeUsageInfo struct {
+	Total      int64
+	Used       int64
+	Free       int64
+	UsePercent float64 // zero for filesystems without a fixed inode table
+}
+
+// Mount describes a mounted filesystem along with its space and inode usage.
+type Mount struct {
+	Device     string
+	MountPoint string
+	FSType     string
+	Usage      DiskUsageInfo
+	Inodes     InodeUsageInfo
+}
+
+// MountState is the desired state of a filesystem passed to EnsureMount.
+type MountState string
+
+const (
+	MountMounted   MountState = "mounted"   // in /etc/fstab and mounted
+	MountUnmounted MountState = "unmounted" // in /etc/fstab but not mounted
+	MountPresent   MountState = "present"   // in /etc/fstab, mount state left alone
+	MountAbsent    MountState = "absent"    // neither in /etc/fstab nor mounted
+)
+
+// MountSpec describes an /etc/fstab entry and whether it should be mounted.
+type MountSpec struct {
+	Device     string // block device, UUID=..., LABEL=... or network share
+	MountPoint string
+	FSType     string
+	Options    []string // defaults to "defaults"
+	Dump       int
+	Pass       int
+	State      MountState // defaults to MountMounted
+}
+
 // Directory describes basic directory attributes.
 type Directory struct {
 	Path     string
 	Mode     os.FileMode
 	Modified time.Time
 }
+
+// ACLEntry is a single POSIX ACL entry such as user:alice:r-x or default:group::r-x.
+type ACLEntry struct {
+	Default   bool   // applies to new files created in a directory
+	Tag       string // user, group, mask or other
+	Qualifier string // user or group name; empty for the owning user and group
+	Perms     string // permissions in rwx form, e.g. "r-x"
+}
+
+// String formats the entry the way getfacl prints it and setfacl accepts it.
+func (e ACLEntry) String() string {
+	s := e.Tag + ":" + e.Qualifier + ":" + e.Perms
+	if e.Default {
+		s = "default:" + s
+	}
+	return s
+}
+
+// ParseACLEntry parses an entry as printed by getfacl, ignoring any trailing comment.
+func ParseACLEntry(s string) (ACLEntry, error) {
+	if i := strings.Index(s, "#"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+
+	var entry ACLEntry
+	if strings.HasPrefix(s, "default:") {
+		entry.Default = true
+		s = strings.TrimPrefix(s, "default:")
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return ACLEntry{}, fmt.Errorf("invalid ACL entry: %q", s)
+	}
+	entry.Tag, entry.Qualifier, entry.Perms = parts[0], parts[1], parts[2]
+	return entry, nil
+}
+
+// SELinuxContext is an SELinux security label.
+type SELinuxContext struct {
+	User  string
+	Role  string
+	Type  string
+	Level string // MLS/MCS level, e.g. s0; may be empty
+}
+
+// String formats the context as user:role:type:level.
+func (c SELinuxContext) String() string {
+	s := c.User + ":" + c.Role + ":" + c.Type
+	if c.Level != "" {
+		s += ":" + c.Level
+	}
+	return s
+}
+
+// ParseSELinuxContext parses a user:role:type[:level] label. The level may
+// itself contain colons, e.g. s0-s0:c0.c1023.
+func ParseSELinuxContext(s string) (SELinuxContext, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 4)
+	if len(parts) < 3 {
+		return SELinuxContext{}, fmt.Errorf("invalid SELinux context: %q", s)
+	}
+
+	label := SELinuxContext{User: parts[0], Role: parts[1], Type: parts[2]}
+	if len(parts) == 4 {
+		label.Level = parts[3]
+	}
+	return label, nil
+}
+
+// UnsupportedError is returned when an operation needs a tool or kernel
+// feature that the host does not have.
+type UnsupportedError struct {
+	Operation string
+	Tool      string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported on this host: %s is not available", e.Operation, e.Tool)
+}
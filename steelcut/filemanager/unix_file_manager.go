@@ -2,9 +2,14 @@ package filemanager
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +19,7 @@ import (
 
 type UnixFileManager struct {
 	CommandManager cm.CommandManager
+	BackupDir      string // where WriteFileAtomic keeps backups; defaults to DefaultBackupDir
 }
 
 func (ufm *UnixFileManager) CreateDirectory(path string) error {
@@ -214,7 +220,7 @@ func (ufm *UnixFileManager) GetFileAttributes(path string) (File, error) {
 func (ufm *UnixFileManager) DiskUsage(path string) (DiskUsageInfo, error) {
 	config := cm.CommandConfig{
 		Command: "df",
-		Args:    []string{"-B1", path}, // -B1 ensures output in bytes
+		Args:    []string{"-P", "-B1", path}, // -B1 ensures output in bytes, -P keeps it on one line
 	}
 	result, err := ufm.CommandManager.Run(context.TODO(), config)
 	if err != nil {
@@ -259,3 +265,773 @@ func (ufm *UnixFileManager) DiskUsage(path string) (DiskUsageInfo, error) {
 		UsePercent: usePercent,
 	}, nil
 }
+
+const (
+	// DefaultBackupDir is where backups are kept when UnixFileManager.BackupDir is unset.
+	// Each file gets its own directory mirroring its absolute path.
+	DefaultBackupDir = "/var/lib/steelcut/backups"
+
+	// DefaultBackupRetain is the number of backups kept per file when AtomicWriteOptions.Retain is zero.
+	DefaultBackupRetain = 5
+
+	backupIDLayout = "20060102T150405.000000000Z"
+
+	// maxInlineContent is the most base64 encoded content WriteFileAtomic puts
+	// in one command. Linux limits a single argument to 128KiB, and both sh -c
+	// and the login shell on the far side of SSH get the script as one.
+	maxInlineContent = 64 * 1024
+)
+
+// WriteFileAtomic writes content to a temp file in the same directory as path,
+// syncs it, optionally validates it and renames it over the original. The
+// previous version is backed up first so it can be brought back with Restore.
+// The write runs with sudo since both the targets and the backup directory are
+// usually owned by root.
+func (ufm *UnixFileManager) WriteFileAtomic(path string, content []byte, opts AtomicWriteOptions) error {
+	quotedPath := cm.ShellQuote(path)
+	encoded := base64.StdEncoding.EncodeToString(content)
+	cleanup := `rm -f "$tmp"`
+	fill := "printf '%s' " + encoded + ` | base64 -d > "$tmp"`
+	if len(encoded) > maxInlineContent {
+		// Larger content is staged next to path in chunks, one command each.
+		stage, err := ufm.stageContent(path, encoded)
+		if err != nil {
+			return fmt.Errorf("atomic write of %s failed: %w", path, err)
+		}
+		cleanup += " " + cm.ShellQuote(stage)
+		fill = "base64 -d < " + cm.ShellQuote(stage) + ` > "$tmp"`
+	}
+
+	script := []string{
+		"set -e",
+		"tmp=$(mktemp " + cm.ShellQuote(tempPattern(path)) + ")",
+		"trap " + cm.ShellQuote(cleanup) + " EXIT",
+		// Copying the original first carries its owner and mode over to the replacement.
+		"if [ -e " + quotedPath + " ]; then cp -p " + quotedPath + ` "$tmp"; else chmod 644 "$tmp"; fi`,
+		fill,
+	}
+
+	if opts.Mode != 0 {
+		script = append(script, fmt.Sprintf(`chmod %o "$tmp"`, opts.Mode.Perm()))
+	}
+
+	script = append(script, `sync "$tmp" 2>/dev/null || sync`)
+
+	if opts.Validate != "" {
+		script = append(script, strings.ReplaceAll(opts.Validate, "%s", `"$tmp"`))
+	}
+
+	if !opts.NoBackup {
+		backupDir := cm.ShellQuote(ufm.backupDir(path))
+		backupID := time.Now().UTC().Format(backupIDLayout)
+		script = append(script, "if [ -e "+quotedPath+" ]; then mkdir -p "+backupDir+" && cp -p "+quotedPath+" "+backupDir+"/"+backupID+" || exit 1; fi")
+	}
+
+	script = append(script, `mv -f "$tmp" `+quotedPath)
+
+	if _, err := ufm.runScript(strings.Join(script, "\n"), true); err != nil {
+		return fmt.Errorf("atomic write of %s failed: %w", path, err)
+	}
+
+	if opts.NoBackup {
+		return nil
+	}
+	return ufm.pruneBackups(path, opts.Retain)
+}
+
+// stageContent writes base64 encoded content to a new temp file next to path,
+// at most maxInlineContent per command, and returns the temp file's path. The
+// temp file is removed again if any chunk cannot be written.
+func (ufm *UnixFileManager) stageContent(path, encoded string) (string, error) {
+	result, err := ufm.runScript("mktemp "+cm.ShellQuote(tempPattern(path)), true)
+	if err != nil {
+		return "", err
+	}
+	stage := strings.TrimSpace(result.STDOUT)
+	quotedStage := cm.ShellQuote(stage)
+
+	for start := 0; start < len(encoded); start += maxInlineContent {
+		end := start + maxInlineContent
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		if _, err := ufm.runScript("printf '%s' "+encoded[start:end]+" >> "+quotedStage, true); err != nil {
+			ufm.runScript("rm -f "+quotedStage, true)
+			return "", err
+		}
+	}
+	return stage, nil
+}
+
+// ListBackups returns the backups kept for path, oldest first.
+func (ufm *UnixFileManager) ListBackups(path string) ([]Backup, error) {
+	dir := ufm.backupDir(path)
+	quotedDir := cm.ShellQuote(dir)
+	result, err := ufm.runScript("if [ -d "+quotedDir+" ]; then ls -1 "+quotedDir+"; fi", false)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, name := range strings.Split(strings.TrimSpace(result.STDOUT), "\n") {
+		created, err := time.Parse(backupIDLayout, name)
+		if err != nil {
+			// Not one of ours
+			continue
+		}
+		backups = append(backups, Backup{
+			ID:      name,
+			Path:    dir + "/" + name,
+			Created: created,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Created.Before(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// Restore atomically replaces path with the backup identified by backupID.
+func (ufm *UnixFileManager) Restore(path, backupID string) error {
+	if _, err := time.Parse(backupIDLayout, backupID); err != nil {
+		return fmt.Errorf("invalid backup ID %q", backupID)
+	}
+
+	backup := cm.ShellQuote(ufm.backupDir(path) + "/" + backupID)
+	script := []string{
+		"set -e",
+		"[ -f " + backup + " ] || { echo 'backup " + backupID + " not found' >&2; exit 1; }",
+		"tmp=$(mktemp " + cm.ShellQuote(tempPattern(path)) + ")",
+		`trap 'rm -f "$tmp"' EXIT`,
+		"cp -p " + backup + ` "$tmp"`,
+		`sync "$tmp" 2>/dev/null || sync`,
+		`mv -f "$tmp" ` + cm.ShellQuote(path),
+	}
+
+	if _, err := ufm.runScript(strings.Join(script, "\n"), true); err != nil {
+		return fmt.Errorf("restore of %s from backup %s failed: %w", path, backupID, err)
+	}
+	return nil
+}
+
+// pruneBackups removes all but the newest retain backups of path.
+func (ufm *UnixFileManager) pruneBackups(path string, retain int) error {
+	if retain < 0 {
+		return nil
+	}
+	if retain == 0 {
+		retain = DefaultBackupRetain
+	}
+
+	backups, err := ufm.ListBackups(path)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= retain {
+		return nil
+	}
+
+	args := []string{"-f"}
+	for _, backup := range backups[:len(backups)-retain] {
+		args = append(args, backup.Path)
+	}
+	return ufm.runPrivileged("rm", args...)
+}
+
+func (ufm *UnixFileManager) backupDir(path string) string {
+	dir := ufm.BackupDir
+	if dir == "" {
+		dir = DefaultBackupDir
+	}
+	return filepath.Join(dir, filepath.Clean("/"+path))
+}
+
+// runScript runs script with sh on the host, treating a non-zero exit as an error.
+func (ufm *UnixFileManager) runScript(script string, sudo bool) (cm.CommandResult, error) {
+	return cm.RunChecked(context.TODO(), ufm.CommandManager, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", script},
+		Sudo:    sudo,
+	}, nil)
+}
+
+// tempPattern returns a mktemp template in the same directory as path, so the
+// final rename never crosses a filesystem boundary.
+func tempPattern(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".steelcut.XXXXXX")
+}
+
+// Tail reads the last lines of path. When follow is set the file is followed by
+// name (tail -F), so the stream carries on after the log is rotated. The
+// returned channel is closed once tail exits or ctx is cancelled.
+func (ufm *UnixFileManager) Tail(ctx context.Context, path string, lines int, follow bool) (<-chan string, error) {
+	config := cm.CommandConfig{
+		Command: "tail",
+		Args:    []string{"-n", strconv.Itoa(lines)},
+	}
+
+	if !follow {
+		config.Args = append(config.Args, path)
+		result, err := ufm.CommandManager.Run(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		if result.ExitCode != 0 {
+			return nil, errors.New(result.STDERR)
+		}
+
+		output := strings.TrimSuffix(result.STDOUT, "\n")
+		out := make(chan string, strings.Count(output, "\n")+1)
+		if output != "" {
+			for _, line := range strings.Split(output, "\n") {
+				out <- line
+			}
+		}
+		close(out)
+		return out, nil
+	}
+
+	streamer, ok := ufm.CommandManager.(cm.StreamingCommandManager)
+	if !ok {
+		return nil, errors.New("following files requires a command manager that supports streaming")
+	}
+	config.Args = append(config.Args, "-F", path)
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		err := streamer.Stream(ctx, config, func(line string) {
+			select {
+			case out <- line:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Tail stopped unexpectedly", "path", path, "error", err)
+		}
+	}()
+
+	return out, nil
+}
+
+// ReadFile returns the contents of the file at path. It reads with sudo, as
+// Checksum does, so files only root can read are covered too.
+func (ufm *UnixFileManager) ReadFile(path string) ([]byte, error) {
+	result, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{path},
+		Sudo:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, errors.New(result.STDERR)
+	}
+	return []byte(result.STDOUT), nil
+}
+
+// ReadFileIfExists returns the content of path on the host behind manager, or
+// an empty string when there is no such file. Any other failure to read it is
+// an error, so a caller rewriting the file never mistakes an unreadable file
+// for an empty one.
+func ReadFileIfExists(manager cm.CommandManager, path string, sudo bool) (string, error) {
+	quoted := cm.ShellQuote(path)
+	result, err := cm.RunChecked(context.TODO(), manager, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "if test -e " + quoted + "; then cat " + quoted + "; fi"},
+		Sudo:    sudo,
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return result.STDOUT, nil
+}
+
+// Checksum returns the hex encoded digest of the file at path. The coreutils
+// tools are preferred, falling back to shasum and md5 as found on Darwin. Like
+// ReadFile it runs with sudo, so a digest can be taken of any file whose
+// content can be read.
+func (ufm *UnixFileManager) Checksum(path string, algo ChecksumAlgorithm) (string, error) {
+	var linuxTool, darwinTool string
+	switch algo {
+	case SHA256:
+		linuxTool, darwinTool = "sha256sum", "shasum -a 256"
+	case SHA1:
+		linuxTool, darwinTool = "sha1sum", "shasum -a 1"
+	case MD5:
+		linuxTool, darwinTool = "md5sum", "md5 -r"
+	default:
+		return "", fmt.Errorf("unsupported checksum algorithm: %s", algo)
+	}
+
+	quotedPath := cm.ShellQuote(path)
+	script := "if command -v " + linuxTool + " >/dev/null 2>&1; then " + linuxTool + " " + quotedPath + "; else " + darwinTool + " " + quotedPath + "; fi"
+	result, err := ufm.runScript(script, true)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(result.STDOUT)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected %s output: %s", algo, result.STDOUT)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// GetACL returns the POSIX ACL of path, including default entries on directories.
+func (ufm *UnixFileManager) GetACL(path string) ([]ACLEntry, error) {
+	result, err := ufm.runTool("reading ACLs", "getfacl", []string{"-c", "-p", path}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ACLEntry
+	for _, line := range strings.Split(result.STDOUT, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := ParseACLEntry(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// SetACL replaces the ACL of path with entries.
+func (ufm *UnixFileManager) SetACL(path string, entries []ACLEntry) error {
+	_, err := ufm.runTool("setting ACLs", "setfacl", []string{"--set", joinACL(entries), path}, true)
+	return err
+}
+
+// EnsureACL adds or updates the given entries, leaving any other entries in place.
+func (ufm *UnixFileManager) EnsureACL(path string, entries []ACLEntry) error {
+	current, err := ufm.GetACL(path)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]string)
+	for _, entry := range current {
+		existing[aclKey(entry)] = entry.Perms
+	}
+
+	var missing []ACLEntry
+	for _, entry := range entries {
+		if perms, ok := existing[aclKey(entry)]; !ok || perms != entry.Perms {
+			missing = append(missing, entry)
+		}
+	}
+	if len(missing) == 0 {
+		// ACL already in the desired state
+		return nil
+	}
+
+	_, err = ufm.runTool("setting ACLs", "setfacl", []string{"-m", joinACL(missing), path}, true)
+	return err
+}
+
+// GetXAttrs returns all extended attributes of path, including the security and trusted namespaces.
+func (ufm *UnixFileManager) GetXAttrs(path string) (map[string]string, error) {
+	result, err := ufm.runTool("reading extended attributes", "getfattr", []string{"--absolute-names", "-d", "-m", "-", "-e", "base64", path}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string]string)
+	for _, line := range strings.Split(result.STDOUT, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, encoded, found := strings.Cut(line, "=")
+		if !found {
+			attrs[name] = ""
+			continue
+		}
+		value, err := decodeXAttrValue(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding extended attribute %s: %v", name, err)
+		}
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+// SetXAttr sets the extended attribute name on path.
+func (ufm *UnixFileManager) SetXAttr(path, name, value string) error {
+	// Always pass the value base64 encoded so setfattr never reinterprets it.
+	encoded := "0s" + base64.StdEncoding.EncodeToString([]byte(value))
+	_, err := ufm.runTool("setting extended attributes", "setfattr", []string{"-n", name, "-v", encoded, path}, true)
+	return err
+}
+
+// RemoveXAttr removes the extended attribute name from path.
+func (ufm *UnixFileManager) RemoveXAttr(path, name string) error {
+	_, err := ufm.runTool("removing extended attributes", "setfattr", []string{"-x", name, path}, true)
+	return err
+}
+
+// GetSELinuxContext returns the SELinux label of path.
+func (ufm *UnixFileManager) GetSELinuxContext(path string) (SELinuxContext, error) {
+	if err := ufm.checkSELinux(); err != nil {
+		return SELinuxContext{}, err
+	}
+
+	result, err := ufm.runTool("reading SELinux contexts", "stat", []string{"-c", "%C", path}, false)
+	if err != nil {
+		return SELinuxContext{}, err
+	}
+	return ParseSELinuxContext(result.STDOUT)
+}
+
+// SetSELinuxContext labels path with the given context using chcon.
+func (ufm *UnixFileManager) SetSELinuxContext(path string, label SELinuxContext) error {
+	if err := ufm.checkSELinux(); err != nil {
+		return err
+	}
+
+	_, err := ufm.runTool("setting SELinux contexts", "chcon", []string{label.String(), path}, true)
+	return err
+}
+
+// RestoreSELinuxContext resets the label of path to the policy default using restorecon.
+func (ufm *UnixFileManager) RestoreSELinuxContext(path string) error {
+	if err := ufm.checkSELinux(); err != nil {
+		return err
+	}
+
+	_, err := ufm.runTool("restoring SELinux contexts", "restorecon", []string{path}, true)
+	return err
+}
+
+// EnsureSELinuxContext labels path with the given context unless it already has it.
+func (ufm *UnixFileManager) EnsureSELinuxContext(path string, label SELinuxContext) error {
+	current, err := ufm.GetSELinuxContext(path)
+	if err != nil {
+		return err
+	}
+
+	// An empty level in the desired context means the level does not matter.
+	if label.Level == "" {
+		current.Level = ""
+	}
+	if current == label {
+		// Context already in the desired state
+		return nil
+	}
+
+	return ufm.SetSELinuxContext(path, label)
+}
+
+// checkSELinux returns an *UnsupportedError unless SELinux is enabled on the host.
+func (ufm *UnixFileManager) checkSELinux() error {
+	result, err := ufm.runTool("SELinux labelling", "selinuxenabled", nil, false)
+	var unsupported *UnsupportedError
+	if errors.As(err, &unsupported) {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return &UnsupportedError{Operation: "SELinux labelling", Tool: "an enabled SELinux policy"}
+	}
+	// Anything else, such as a failed connection, says nothing about SELinux.
+	return err
+}
+
+// runTool runs tool with args, returning an *UnsupportedError when the tool is
+// not installed on the host.
+func (ufm *UnixFileManager) runTool(operation, tool string, args []string, sudo bool) (cm.CommandResult, error) {
+	script := "command -v " + tool + " >/dev/null 2>&1 || exit 127\nexec " + tool
+	for _, arg := range args {
+		script += " " + cm.ShellQuote(arg)
+	}
+
+	result, err := ufm.runScript(script, sudo)
+	if result.ExitCode == 127 {
+		return result, &UnsupportedError{Operation: operation, Tool: tool}
+	}
+	return result, err
+}
+
+func aclKey(entry ACLEntry) string {
+	return fmt.Sprintf("%t:%s:%s", entry.Default, entry.Tag, entry.Qualifier)
+}
+
+func joinACL(entries []ACLEntry) string {
+	specs := make([]string, len(entries))
+	for i, entry := range entries {
+		specs[i] = entry.String()
+	}
+	return strings.Join(specs, ",")
+}
+
+// decodeXAttrValue decodes a value in one of the encodings used by getfattr.
+func decodeXAttrValue(encoded string) (string, error) {
+	switch {
+	case strings.HasPrefix(encoded, "0s"):
+		decoded, err := base64.StdEncoding.DecodeString(encoded[2:])
+		return string(decoded), err
+	case strings.HasPrefix(encoded, "0x"):
+		decoded, err := hex.DecodeString(encoded[2:])
+		return string(decoded), err
+	default:
+		return strconv.Unquote(encoded)
+	}
+}
+
+const fstabPath = "/etc/fstab"
+
+// ListMounts returns every mounted filesystem with its space and inode usage.
+func (ufm *UnixFileManager) ListMounts() ([]Mount, error) {
+	space, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "df",
+		Args:    []string{"-P", "-B1", "-T"}, // -P keeps each filesystem on one line
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inodes, err := ufm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "df",
+		Args:    []string{"-P", "-i"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMounts(space.STDOUT, inodes.STDOUT)
+}
+
+// EnsureMount makes /etc/fstab and the mount table match spec. Changing the
+// options of a mounted filesystem remounts it; changing its device or type
+// unmounts it first.
+func (ufm *UnixFileManager) EnsureMount(spec MountSpec) error {
+	if spec.MountPoint == "" {
+		return errors.New("mount point is required")
+	}
+	if spec.State == "" {
+		spec.State = MountMounted
+	}
+	if spec.State != MountAbsent && (spec.Device == "" || spec.FSType == "") {
+		return fmt.Errorf("device and filesystem type are required for %s", spec.MountPoint)
+	}
+
+	fstab, err := ufm.ReadFile(fstabPath)
+	if err != nil {
+		return err
+	}
+
+	updated, previous, changed := updateFstab(string(fstab), spec)
+	if changed {
+		if err := ufm.WriteFileAtomic(fstabPath, []byte(updated), AtomicWriteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	mounted, err := ufm.isMounted(spec.MountPoint)
+	if err != nil {
+		return err
+	}
+
+	switch spec.State {
+	case MountMounted:
+		if !mounted {
+			if err := ufm.runPrivileged("mkdir", "-p", spec.MountPoint); err != nil {
+				return err
+			}
+			return ufm.runPrivileged("mount", spec.MountPoint)
+		}
+		if !changed {
+			// Mount already in the desired state
+			return nil
+		}
+		if len(previous) >= 3 && previous[0] == fstabEscape(spec.Device) && previous[2] == spec.FSType {
+			return ufm.runPrivileged("mount", "-o", "remount", spec.MountPoint)
+		}
+		if err := ufm.runPrivileged("umount", spec.MountPoint); err != nil {
+			return err
+		}
+		return ufm.runPrivileged("mount", spec.MountPoint)
+
+	case MountUnmounted, MountAbsent:
+		if mounted {
+			return ufm.runPrivileged("umount", spec.MountPoint)
+		}
+	}
+
+	return nil
+}
+
+// isMounted reports whether something is mounted on mountPoint according to /proc/mounts.
+func (ufm *UnixFileManager) isMounted(mountPoint string) (bool, error) {
+	mounts, err := ufm.ReadFile("/proc/mounts")
+	if err != nil {
+		return false, err
+	}
+
+	target := filepath.Clean(mountPoint)
+	for _, line := range strings.Split(string(mounts), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fstabUnescape(fields[1]) == target {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// runPrivileged runs a command with sudo, treating a non-zero exit as an error.
+func (ufm *UnixFileManager) runPrivileged(command string, args ...string) error {
+	_, err := cm.RunChecked(context.TODO(), ufm.CommandManager, cm.CommandConfig{
+		Command: command,
+		Args:    args,
+		Sudo:    true,
+	}, nil)
+	return err
+}
+
+// ParseMounts combines the output of df -P -B1 -T and df -P -i into a list of mounts.
+func ParseMounts(spaceOutput, inodeOutput string) ([]Mount, error) {
+	inodes := make(map[string]InodeUsageInfo)
+	for _, line := range dataLines(inodeOutput) {
+		columns := strings.Fields(line)
+		if len(columns) < 6 {
+			return nil, fmt.Errorf("unexpected df -i columns count: %s", line)
+		}
+
+		var info InodeUsageInfo
+		info.Total, _ = strconv.ParseInt(columns[1], 10, 64)
+		info.Used, _ = strconv.ParseInt(columns[2], 10, 64)
+		info.Free, _ = strconv.ParseInt(columns[3], 10, 64)
+		// Filesystems without a fixed inode table, like btrfs, report "-".
+		info.UsePercent, _ = strconv.ParseFloat(strings.TrimRight(columns[4], "%"), 64)
+		inodes[strings.Join(columns[5:], " ")] = info
+	}
+
+	var mounts []Mount
+	for _, line := range dataLines(spaceOutput) {
+		columns := strings.Fields(line)
+		if len(columns) < 7 {
+			return nil, fmt.Errorf("unexpected df columns count: %s", line)
+		}
+
+		total, err := strconv.ParseInt(columns[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing total space: %v", err)
+		}
+		used, err := strconv.ParseInt(columns[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing used space: %v", err)
+		}
+		available, err := strconv.ParseInt(columns[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing available space: %v", err)
+		}
+		usePercent, err := strconv.ParseFloat(strings.TrimRight(columns[5], "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing usage percentage: %v", err)
+		}
+
+		mountPoint := strings.Join(columns[6:], " ")
+		mounts = append(mounts, Mount{
+			Device:     columns[0],
+			FSType:     columns[1],
+			MountPoint: mountPoint,
+			Usage: DiskUsageInfo{
+				Total:      total,
+				Used:       used,
+				Available:  available,
+				UsePercent: usePercent,
+			},
+			Inodes: inodes[mountPoint],
+		})
+	}
+
+	return mounts, nil
+}
+
+// dataLines returns the non-empty lines of command output after the header.
+func dataLines(output string) []string {
+	var lines []string
+	for i, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if i == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// updateFstab replaces, adds or removes the entry for spec.MountPoint. It
+// returns the new content, the fields of the entry it replaced and whether
+// anything changed. Comments and other entries are left untouched.
+func updateFstab(fstab string, spec MountSpec) (string, []string, bool) {
+	var desired []string
+	if spec.State != MountAbsent {
+		options := strings.Join(spec.Options, ",")
+		if options == "" {
+			options = "defaults"
+		}
+		desired = []string{
+			fstabEscape(spec.Device),
+			fstabEscape(filepath.Clean(spec.MountPoint)),
+			spec.FSType,
+			options,
+			strconv.Itoa(spec.Dump),
+			strconv.Itoa(spec.Pass),
+		}
+	}
+
+	target := filepath.Clean(spec.MountPoint)
+	var lines []string
+	if strings.TrimSpace(fstab) != "" {
+		lines = strings.Split(strings.TrimSuffix(fstab, "\n"), "\n")
+	}
+	var previous []string
+	var out []string
+	found := false
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || fstabUnescape(fields[1]) != target {
+			out = append(out, line)
+			continue
+		}
+
+		previous = fields
+		if found || desired == nil {
+			// Drop duplicates as well as the entry itself when it should be absent
+			continue
+		}
+		found = true
+		if strings.Join(fields, " ") == strings.Join(desired, " ") {
+			// Keep the existing formatting of an entry that is already correct
+			out = append(out, line)
+		} else {
+			out = append(out, strings.Join(desired, "\t"))
+		}
+	}
+
+	if !found && desired != nil {
+		out = append(out, strings.Join(desired, "\t"))
+	}
+
+	updated := strings.Join(out, "\n") + "\n"
+	if updated == fstab || updated == fstab+"\n" {
+		return fstab, previous, false
+	}
+	return updated, previous, true
+}
+
+// fstabEscape encodes whitespace the way fstab and /proc/mounts expect it.
+func fstabEscape(s string) string {
+	return strings.NewReplacer(" ", `\040`, "\t", `\011`).Replace(s)
+}
+
+func fstabUnescape(s string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\134`, `\`).Replace(s)
+}
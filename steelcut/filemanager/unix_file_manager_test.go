@@ -3,14 +3,16 @@ package filemanager
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
 )
 
 type MockCommandManager struct {
-	Result cm.CommandResult
-	Err    error
+	Result  cm.CommandResult
+	Err     error
+	Configs []cm.CommandConfig
 }
 
 func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
@@ -22,6 +24,7 @@ func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandCon
 }
 
 func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Configs = append(m.Configs, config)
 	return m.Result, m.Err
 }
 
@@ -69,3 +72,347 @@ func TestListDirectoryError(t *testing.T) {
 		t.Errorf("Expected mock error, got: %v", err)
 	}
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	mockCmd := &MockCommandManager{}
+	manager := UnixFileManager{
+		CommandManager: mockCmd,
+	}
+
+	err := manager.WriteFileAtomic("/etc/sudoers", []byte("root ALL=(ALL) ALL\n"), AtomicWriteOptions{
+		Mode:     0440,
+		Validate: "visudo -cf %s",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	config := mockCmd.Configs[0]
+	if !config.Sudo {
+		t.Errorf("Expected atomic write to run with sudo")
+	}
+	script := config.Args[1]
+	for _, expected := range []string{
+		"mktemp /etc/.sudoers.steelcut.XXXXXX",
+		`chmod 440 "$tmp"`,
+		`visudo -cf "$tmp"`,
+		"mkdir -p /var/lib/steelcut/backups/etc/sudoers",
+		`mv -f "$tmp" /etc/sudoers`,
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("Expected script to contain %q, got:\n%s", expected, script)
+		}
+	}
+	if strings.Index(script, "visudo") > strings.Index(script, "mv -f") {
+		t.Errorf("Expected validation to happen before the rename")
+	}
+}
+
+func TestWriteFileAtomicValidationFailure(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{ExitCode: 1, STDERR: "parse error in /etc/.sudoers.steelcut.abc123\n"},
+	}
+	manager := UnixFileManager{
+		CommandManager: mockCmd,
+	}
+
+	err := manager.WriteFileAtomic("/etc/sudoers", []byte("garbage"), AtomicWriteOptions{Validate: "visudo -cf %s"})
+	if err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("Expected validation error, got: %v", err)
+	}
+	if len(mockCmd.Configs) != 1 {
+		t.Errorf("Expected no backup pruning after a failed write, got %d commands", len(mockCmd.Configs))
+	}
+}
+
+func TestListBackups(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: "20240102T030405.000000000Z\nnotes.txt\n20240101T030405.000000000Z\n"},
+	}
+	manager := UnixFileManager{
+		CommandManager: mockCmd,
+		BackupDir:      "/backups",
+	}
+
+	backups, err := manager.ListBackups("/etc/fstab")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got: %v", backups)
+	}
+	if backups[0].ID != "20240101T030405.000000000Z" {
+		t.Errorf("Expected oldest backup first, got: %v", backups[0].ID)
+	}
+	if backups[1].Path != "/backups/etc/fstab/20240102T030405.000000000Z" {
+		t.Errorf("Unexpected backup path: %v", backups[1].Path)
+	}
+}
+
+func TestRestoreInvalidBackupID(t *testing.T) {
+	manager := UnixFileManager{
+		CommandManager: &MockCommandManager{},
+	}
+
+	if err := manager.Restore("/etc/fstab", "../../etc/shadow"); err == nil {
+		t.Errorf("Expected an error for an invalid backup ID")
+	}
+}
+
+func TestTail(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: "first\nsecond\n"},
+	}
+	manager := UnixFileManager{
+		CommandManager: mockCmd,
+	}
+
+	lines, err := manager.Tail(context.Background(), "/var/log/syslog", 2, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var got []string
+	for line := range lines {
+		got = append(got, line)
+	}
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Errorf("Expected [first second], got: %v", got)
+	}
+	if args := strings.Join(mockCmd.Configs[0].Args, " "); args != "-n 2 /var/log/syslog" {
+		t.Errorf("Unexpected tail arguments: %s", args)
+	}
+}
+
+func TestTailFollowRequiresStreaming(t *testing.T) {
+	manager := UnixFileManager{
+		CommandManager: &MockCommandManager{},
+	}
+
+	if _, err := manager.Tail(context.Background(), "/var/log/syslog", 10, true); err == nil {
+		t.Errorf("Expected an error when the command manager cannot stream")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855  /etc/nginx/nginx.conf\n"},
+	}
+	manager := UnixFileManager{
+		CommandManager: mockCmd,
+	}
+
+	digest, err := manager.Checksum("/etc/nginx/nginx.conf", SHA256)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if digest != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("Unexpected digest: %s", digest)
+	}
+	if !mockCmd.Configs[0].Sudo {
+		t.Errorf("Expected the checksum to run with sudo")
+	}
+
+	if _, err := manager.ReadFile("/etc/nginx/nginx.conf"); err != nil || !mockCmd.Configs[1].Sudo {
+		t.Errorf("Expected the content to be read with sudo, got %v", err)
+	}
+
+	if _, err := manager.Checksum("/etc/nginx/nginx.conf", "crc32"); err == nil {
+		t.Errorf("Expected an error for an unsupported algorithm")
+	}
+}
+
+func TestReadFileIfExists(t *testing.T) {
+	mockCmd := &MockCommandManager{Result: cm.CommandResult{STDOUT: "vm.swappiness = 10\n"}}
+
+	content, err := ReadFileIfExists(mockCmd, "/etc/sysctl.d/90-steelcut.conf", true)
+	if err != nil || content != "vm.swappiness = 10\n" {
+		t.Errorf("Expected the file content, got %q and %v", content, err)
+	}
+	if script := mockCmd.Configs[0].Args[1]; script != "if test -e /etc/sysctl.d/90-steelcut.conf; then cat /etc/sysctl.d/90-steelcut.conf; fi" || !mockCmd.Configs[0].Sudo {
+		t.Errorf("Unexpected read: %+v", mockCmd.Configs[0])
+	}
+
+	mockCmd.Result = cm.CommandResult{STDERR: "cat: /etc/sysctl.d/90-steelcut.conf: Permission denied\n", ExitCode: 1}
+	mockCmd.Err = errors.New("exit status 1")
+	if _, err := ReadFileIfExists(mockCmd, "/etc/sysctl.d/90-steelcut.conf", false); err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("Expected a read failure to be an error, got %v", err)
+	}
+}
+
+func TestGetACL(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: "user::rwx\nuser:alice:r-x\t#effective:r--\ngroup::r-x\nmask::r--\nother::---\ndefault:user::rwx\n"},
+	}
+	manager := UnixFileManager{
+		CommandManager: mockCmd,
+	}
+
+	entries, err := manager.GetACL("/srv/app")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("Expected 6 entries, got: %v", entries)
+	}
+	if entries[1] != (ACLEntry{Tag: "user", Qualifier: "alice", Perms: "r-x"}) {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+	if !entries[5].Default || entries[5].String() != "default:user::rwx" {
+		t.Errorf("Expected a default entry, got: %+v", entries[5])
+	}
+}
+
+func TestEnsureACLAlreadyPresent(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Result: cm.CommandResult{STDOUT: "user::rw-\nuser:deploy:rw-\ngroup::r--\nother::r--\n"},
+	}
+	manager := UnixFileManager{
+		CommandManager: mockCmd,
+	}
+
+	err := manager.EnsureACL("/srv/app/config.yml", []ACLEntry{{Tag: "user", Qualifier: "deploy", Perms: "rw-"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(mockCmd.Configs) != 1 {
+		t.Errorf("Expected only getfacl to run, got %d commands", len(mockCmd.Configs))
+	}
+}
+
+func TestSetACLUnsupported(t *testing.T) {
+	manager := UnixFileManager{
+		CommandManager: &MockCommandManager{Result: cm.CommandResult{ExitCode: 127}},
+	}
+
+	err := manager.SetACL("/srv/app", []ACLEntry{{Tag: "user", Perms: "rwx"}})
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Tool != "setfacl" {
+		t.Errorf("Expected an UnsupportedError for setfacl, got: %v", err)
+	}
+}
+
+// Over SSH a failing command comes back with both its exit status and an
+// error, which must not hide the exit status.
+func TestRunToolRemoteExitCodes(t *testing.T) {
+	manager := UnixFileManager{
+		CommandManager: &MockCommandManager{
+			Result: cm.CommandResult{ExitCode: 127},
+			Err:    errors.New("Process exited with status 127"),
+		},
+	}
+	_, err := manager.GetACL("/srv/app")
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Tool != "getfacl" {
+		t.Errorf("Expected an UnsupportedError for getfacl, got: %v", err)
+	}
+
+	manager.CommandManager = &MockCommandManager{
+		Result: cm.CommandResult{ExitCode: 1, STDERR: "getfacl: /srv/app: No such file or directory\n"},
+		Err:    errors.New("Process exited with status 1"),
+	}
+	_, err = manager.GetACL("/srv/app")
+	if err == nil || errors.As(err, &unsupported) || !strings.Contains(err.Error(), "No such file or directory") {
+		t.Errorf("Expected the getfacl error, got: %v", err)
+	}
+}
+
+func TestCheckSELinuxRemote(t *testing.T) {
+	// selinuxenabled exits 1 when SELinux is disabled.
+	manager := UnixFileManager{
+		CommandManager: &MockCommandManager{
+			Result: cm.CommandResult{ExitCode: 1},
+			Err:    errors.New("Process exited with status 1"),
+		},
+	}
+	err := manager.SetSELinuxContext("/srv/app", SELinuxContext{User: "system_u", Role: "object_r", Type: "httpd_sys_content_t", Level: "s0"})
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Tool != "an enabled SELinux policy" {
+		t.Errorf("Expected an UnsupportedError for disabled SELinux, got: %v", err)
+	}
+
+	manager.CommandManager = &MockCommandManager{
+		Result: cm.CommandResult{ExitCode: 127},
+		Err:    errors.New("Process exited with status 127"),
+	}
+	if _, err := manager.GetSELinuxContext("/srv/app"); !errors.As(err, &unsupported) || unsupported.Tool != "selinuxenabled" {
+		t.Errorf("Expected an UnsupportedError for selinuxenabled, got: %v", err)
+	}
+
+	// A connection failure is not the same as SELinux being disabled.
+	manager.CommandManager = &MockCommandManager{Err: errors.New("dial tcp: connection refused")}
+	if _, err := manager.GetSELinuxContext("/srv/app"); err == nil || errors.As(err, &unsupported) {
+		t.Errorf("Expected the connection error, got: %v", err)
+	}
+}
+
+func TestParseSELinuxContext(t *testing.T) {
+	label, err := ParseSELinuxContext("system_u:object_r:httpd_sys_content_t:s0-s0:c0.c1023\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if label.Type != "httpd_sys_content_t" || label.Level != "s0-s0:c0.c1023" {
+		t.Errorf("Unexpected context: %+v", label)
+	}
+	if label.String() != "system_u:object_r:httpd_sys_content_t:s0-s0:c0.c1023" {
+		t.Errorf("Unexpected string form: %s", label)
+	}
+
+	if _, err := ParseSELinuxContext("?"); err == nil {
+		t.Errorf("Expected an error for an unlabelled file")
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	space := `Filesystem     Type     1-blocks        Used   Available Capacity Mounted on
+/dev/vda1      ext4     270553174016 18134515712 85747970048      18% /
+/dev/vdb1      btrfs       470974464   379809792    54689792      88% /srv/my data
+`
+	inodes := `Filesystem       Inodes  IUsed    IFree IUse% Mounted on
+/dev/vda1      16777216 748257 16028959    5% /
+/dev/vdb1             0      0        0     - /srv/my data
+`
+
+	mounts, err := ParseMounts(space, inodes)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(mounts) != 2 {
+		t.Fatalf("Expected 2 mounts, got: %v", mounts)
+	}
+	if mounts[0].Usage.Used != 18134515712 || mounts[0].Inodes.UsePercent != 5 {
+		t.Errorf("Unexpected root mount: %+v", mounts[0])
+	}
+	if mounts[1].MountPoint != "/srv/my data" || mounts[1].FSType != "btrfs" || mounts[1].Usage.UsePercent != 88 {
+		t.Errorf("Unexpected data mount: %+v", mounts[1])
+	}
+}
+
+func TestUpdateFstab(t *testing.T) {
+	fstab := "# /etc/fstab\nUUID=abc / ext4 errors=remount-ro 0 1\n/dev/vdb1 /data xfs defaults 0 2\n"
+	data := MountSpec{Device: "/dev/vdb1", MountPoint: "/data", FSType: "xfs", Pass: 2, State: MountMounted}
+
+	if _, _, changed := updateFstab(fstab, data); changed {
+		t.Errorf("Expected an identical entry to leave fstab unchanged")
+	}
+
+	data.Options = []string{"noatime", "nodev"}
+	updated, previous, changed := updateFstab(fstab, data)
+	if !changed || !strings.Contains(updated, "/dev/vdb1\t/data\txfs\tnoatime,nodev\t0\t2\n") {
+		t.Errorf("Expected the entry to be replaced, got:\n%s", updated)
+	}
+	if len(previous) != 6 || previous[3] != "defaults" {
+		t.Errorf("Expected the previous entry to be returned, got: %v", previous)
+	}
+
+	backups := MountSpec{Device: "nas:/backups", MountPoint: "/mnt/back ups", FSType: "nfs", State: MountPresent}
+	updated, _, changed = updateFstab(fstab, backups)
+	if !changed || !strings.HasSuffix(updated, "nas:/backups\t/mnt/back\\040ups\tnfs\tdefaults\t0\t0\n") {
+		t.Errorf("Expected a new entry to be appended, got:\n%s", updated)
+	}
+
+	updated, _, changed = updateFstab(fstab, MountSpec{MountPoint: "/data/", State: MountAbsent})
+	if !changed || strings.Contains(updated, "/data") || !strings.HasPrefix(updated, "# /etc/fstab\n") {
+		t.Errorf("Expected the entry to be removed, got:\n%s", updated)
+	}
+}
@@ -0,0 +1,222 @@
+package firewallmanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+var richRule = regexp.MustCompile(`^rule(?: family="ipv[46]")?(?: source address="([^"]+)")? port port="(\d+)" protocol="(tcp|udp)" (accept|drop|reject)(?: type="[^"]+")?$`)
+
+var firewalldActions = map[string]Action{"accept": Allow, "drop": Deny, "reject": Reject}
+
+var firewalldTargets = map[Action]string{Allow: "ACCEPT", Deny: "DROP", Reject: "REJECT"}
+
+// FirewalldFirewallManager manages rules with firewalld, as used on RHEL,
+// Fedora and openSUSE. Open ports without a source are plain ports; every
+// other rule is a rich rule. Changes are made to both the runtime and the
+// permanent configuration.
+type FirewalldFirewallManager struct {
+	CommandManager cm.CommandManager
+	SSHPort        int
+	Zone           string // the default zone when empty
+}
+
+// firewalldRule is a listed rule along with the rich rule text it was read
+// from, which is needed to remove it. Rich is empty for plain ports.
+type firewalldRule struct {
+	Rule
+	rich string
+}
+
+func (f *FirewalldFirewallManager) ListRules() ([]Rule, error) {
+	rules, err := f.rules()
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]Rule, len(rules))
+	for i, rule := range rules {
+		listed[i] = rule.Rule
+	}
+	return listed, nil
+}
+
+func (f *FirewalldFirewallManager) rules() ([]firewalldRule, error) {
+	output, err := run(f.CommandManager, "firewall-cmd", f.zoneArgs("--list-all")...)
+	if err != nil {
+		return nil, err
+	}
+	return parseFirewalldZone(output), nil
+}
+
+func (f *FirewalldFirewallManager) EnsureRule(port int, protocol, source string, action Action) (bool, error) {
+	rule, err := validateRule(port, protocol, source, action)
+	if err != nil {
+		return false, err
+	}
+	if err := checkLockout(f.CommandManager, rule, sshPortOrDefault(f.SSHPort)); err != nil {
+		return false, err
+	}
+
+	rules, err := f.rules()
+	if err != nil {
+		return false, err
+	}
+	if existing, found := findFirewalldRule(rules, rule); found {
+		if existing.Action == action {
+			return false, nil
+		}
+		if err := f.remove(existing); err != nil {
+			return false, err
+		}
+	}
+
+	if rule.Action == Allow && rule.Source == "" {
+		return true, f.apply("--add-port=" + strconv.Itoa(rule.Port) + "/" + rule.Protocol)
+	}
+	return true, f.apply("--add-rich-rule=" + formatRichRule(rule))
+}
+
+func (f *FirewalldFirewallManager) RemoveRule(port int, protocol, source string) (bool, error) {
+	rule, err := validateRule(port, protocol, source, Allow)
+	if err != nil {
+		return false, err
+	}
+
+	rules, err := f.rules()
+	if err != nil {
+		return false, err
+	}
+	existing, found := findFirewalldRule(rules, rule)
+	if !found {
+		return false, nil
+	}
+	if err := checkLockoutRemoval(existing.Rule, sshPortOrDefault(f.SSHPort)); err != nil {
+		return false, err
+	}
+	return true, f.remove(existing)
+}
+
+func (f *FirewalldFirewallManager) remove(existing firewalldRule) error {
+	if existing.rich == "" {
+		return f.apply("--remove-port=" + strconv.Itoa(existing.Port) + "/" + existing.Protocol)
+	}
+	return f.apply("--remove-rich-rule=" + existing.rich)
+}
+
+// SetDefaultPolicy sets the target of the zone. firewalld only accepts a new
+// target in the permanent configuration, so it is reloaded afterwards.
+func (f *FirewalldFirewallManager) SetDefaultPolicy(action Action) error {
+	target, ok := firewalldTargets[action]
+	if !ok {
+		return fmt.Errorf("invalid action: %q", action)
+	}
+	if err := allowSSH(f, action, sshPortOrDefault(f.SSHPort)); err != nil {
+		return err
+	}
+	if _, err := run(f.CommandManager, "firewall-cmd", f.zoneArgs("--permanent", "--set-target="+target)...); err != nil {
+		return err
+	}
+	return f.Reload()
+}
+
+func (f *FirewalldFirewallManager) Reload() error {
+	_, err := run(f.CommandManager, "firewall-cmd", "--reload")
+	return err
+}
+
+// apply makes a change to the permanent configuration and then to the
+// running firewall, so it neither waits for a reload nor is lost by one.
+func (f *FirewalldFirewallManager) apply(change string) error {
+	if _, err := run(f.CommandManager, "firewall-cmd", f.zoneArgs("--permanent", change)...); err != nil {
+		return err
+	}
+	_, err := run(f.CommandManager, "firewall-cmd", f.zoneArgs(change)...)
+	return err
+}
+
+func (f *FirewalldFirewallManager) zoneArgs(args ...string) []string {
+	if f.Zone == "" {
+		return args
+	}
+	return append([]string{"--zone=" + f.Zone}, args...)
+}
+
+func findFirewalldRule(rules []firewalldRule, rule Rule) (firewalldRule, bool) {
+	for _, r := range rules {
+		if r.Port == rule.Port && r.Protocol == rule.Protocol && r.Source == rule.Source {
+			return r, true
+		}
+	}
+	return firewalldRule{}, false
+}
+
+func formatRichRule(rule Rule) string {
+	var b strings.Builder
+	b.WriteString("rule")
+	if rule.Source != "" {
+		family := "ipv4"
+		if strings.Contains(rule.Source, ":") {
+			family = "ipv6"
+		}
+		b.WriteString(` family="` + family + `" source address="` + rule.Source + `"`)
+	}
+	b.WriteString(` port port="` + strconv.Itoa(rule.Port) + `" protocol="` + rule.Protocol + `" `)
+	switch rule.Action {
+	case Allow:
+		b.WriteString("accept")
+	case Deny:
+		b.WriteString("drop")
+	default:
+		b.WriteString("reject")
+	}
+	return b.String()
+}
+
+// parseFirewalldZone reads the ports and rich rules from firewall-cmd
+// --list-all. Port ranges and rich rules that do more than accept, drop or
+// reject a single port are left out.
+func parseFirewalldZone(output string) []firewalldRule {
+	var rules []firewalldRule
+	inRichRules := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if key, value, found := strings.Cut(trimmed, ":"); found && !strings.HasPrefix(trimmed, "rule ") {
+			inRichRules = key == "rich rules"
+			if key != "ports" {
+				continue
+			}
+			for _, port := range strings.Fields(value) {
+				number, protocol, _ := strings.Cut(port, "/")
+				n, err := strconv.Atoi(number)
+				if err != nil || (protocol != "tcp" && protocol != "udp") {
+					continue
+				}
+				rules = append(rules, firewalldRule{Rule: Rule{Port: n, Protocol: protocol, Action: Allow}})
+			}
+			continue
+		}
+
+		if !inRichRules {
+			continue
+		}
+		match := richRule.FindStringSubmatch(trimmed)
+		if match == nil {
+			continue
+		}
+		source, err := normalizeSource(match[1])
+		if err != nil {
+			continue
+		}
+		n, _ := strconv.Atoi(match[2])
+		rules = append(rules, firewalldRule{
+			Rule: Rule{Port: n, Protocol: match[3], Source: source, Action: firewalldActions[match[4]]},
+			rich: trimmed,
+		})
+	}
+	return rules
+}
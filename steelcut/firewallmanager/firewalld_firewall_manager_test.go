@@ -0,0 +1,105 @@
+package firewallmanager
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const firewalldZone = `public (active)
+  target: default
+  icmp-block-inversion: no
+  interfaces: eth0
+  sources: 
+  services: cockpit dhcpv6-client ssh
+  ports: 443/tcp 8000-8100/tcp 161/udp
+  protocols: 
+  forward: yes
+  masquerade: no
+  forward-ports: 
+  source-ports: 
+  icmp-blocks: 
+  rich rules: 
+	rule family="ipv4" source address="10.0.0.0/8" port port="5432" protocol="tcp" accept
+	rule family="ipv6" source address="fd00::/64" port port="53" protocol="udp" reject type="icmp6-port-unreachable"
+	rule family="ipv4" source address="192.0.2.0/24" service name="http" accept
+`
+
+func TestParseFirewalldZone(t *testing.T) {
+	rules := parseFirewalldZone(firewalldZone)
+
+	expected := []firewalldRule{
+		{Rule: Rule{Port: 443, Protocol: "tcp", Action: Allow}},
+		{Rule: Rule{Port: 161, Protocol: "udp", Action: Allow}},
+		{Rule: Rule{Port: 5432, Protocol: "tcp", Source: "10.0.0.0/8", Action: Allow}, rich: `rule family="ipv4" source address="10.0.0.0/8" port port="5432" protocol="tcp" accept`},
+		{Rule: Rule{Port: 53, Protocol: "udp", Source: "fd00::/64", Action: Reject}, rich: `rule family="ipv6" source address="fd00::/64" port port="53" protocol="udp" reject type="icmp6-port-unreachable"`},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, rules)
+	}
+}
+
+func TestFirewalldEnsureRule(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"firewall-cmd --zone=internal --list-all": firewalldZone}}
+	manager := &FirewalldFirewallManager{CommandManager: mockCmd, Zone: "internal"}
+
+	changed, err := manager.EnsureRule(5432, "tcp", "10.0.0.0/8", Deny)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	old := `rule family="ipv4" source address="10.0.0.0/8" port port="5432" protocol="tcp" accept`
+	updated := `rule family="ipv4" source address="10.0.0.0/8" port port="5432" protocol="tcp" drop`
+	expected := []string{
+		"firewall-cmd --zone=internal --list-all",
+		"firewall-cmd --zone=internal --permanent --remove-rich-rule=" + old,
+		"firewall-cmd --zone=internal --remove-rich-rule=" + old,
+		"firewall-cmd --zone=internal --permanent --add-rich-rule=" + updated,
+		"firewall-cmd --zone=internal --add-rich-rule=" + updated,
+	}
+	if commands := mockCmd.commands(); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected %q, got %q", expected, commands)
+	}
+}
+
+func TestFirewalldSetDefaultPolicy(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{"firewall-cmd --list-all": firewalldZone},
+		Updates: map[string]map[string]string{"firewall-cmd --add-port=22/tcp": {
+			"firewall-cmd --list-all": strings.Replace(firewalldZone, "ports: 443/tcp", "ports: 22/tcp 443/tcp", 1),
+		}},
+	}
+	manager := &FirewalldFirewallManager{CommandManager: mockCmd}
+
+	if err := manager.SetDefaultPolicy(Deny); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// The ssh service does not count as a port rule, so port 22 is opened
+	// before the zone starts dropping traffic.
+	expected := []string{
+		"firewall-cmd --list-all",
+		"firewall-cmd --permanent --add-port=22/tcp",
+		"firewall-cmd --add-port=22/tcp",
+		"firewall-cmd --list-all",
+		"firewall-cmd --permanent --set-target=DROP",
+		"firewall-cmd --reload",
+	}
+	if commands := mockCmd.commands(); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected %q, got %q", expected, commands)
+	}
+}
+
+func TestFirewalldSetDefaultPolicyUnconfirmed(t *testing.T) {
+	// The listing never shows port 22, as if the change had been lost.
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"firewall-cmd --list-all": firewalldZone}}
+	manager := &FirewalldFirewallManager{CommandManager: mockCmd}
+
+	if err := manager.SetDefaultPolicy(Deny); !errors.Is(err, ErrSSHLockout) {
+		t.Fatalf("Expected ErrSSHLockout, got %v", err)
+	}
+	for _, command := range mockCmd.commands() {
+		if strings.Contains(command, "--set-target") {
+			t.Errorf("Expected the policy to be left alone, got %q", command)
+		}
+	}
+}
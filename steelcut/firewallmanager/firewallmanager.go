@@ -0,0 +1,205 @@
+package firewallmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// DefaultSSHPort is the port protected from lockout when a backend has no
+// SSHPort set.
+const DefaultSSHPort = 22
+
+// Action is what a rule or the default policy does with matching traffic.
+type Action string
+
+const (
+	Allow  Action = "allow"
+	Deny   Action = "deny"   // drop silently
+	Reject Action = "reject" // refuse with an ICMP error or TCP reset
+)
+
+// ErrSSHLockout is returned for changes that would cut off SSH access, and
+// with it steelcut's own connection to the host.
+var ErrSSHLockout = errors.New("change would block SSH access to the host")
+
+// Rule is an incoming traffic rule for a single port.
+type Rule struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"` // "tcp" or "udp"
+	Source   string `json:"source,omitempty"`
+	Action   Action `json:"action"`
+}
+
+func (r Rule) String() string {
+	source := r.Source
+	if source == "" {
+		source = "anywhere"
+	}
+	return fmt.Sprintf("%s %d/%s from %s", r.Action, r.Port, r.Protocol, source)
+}
+
+// FirewallManager manages incoming traffic rules. Rules are keyed by port,
+// protocol and source; an empty source matches any address. EnsureRule and
+// RemoveRule are idempotent and report whether anything changed.
+//
+// Changes that would block the SSH port from everywhere, or from the address
+// steelcut connects from, return ErrSSHLockout. Tightening the default policy
+// first allows SSH and checks that the rule took.
+type FirewallManager interface {
+	// ListRules lists the port rules for incoming traffic.
+	ListRules() ([]Rule, error)
+
+	// EnsureRule adds the rule, replacing one for the same port, protocol
+	// and source with a different action.
+	EnsureRule(port int, protocol, source string, action Action) (bool, error)
+
+	// RemoveRule removes the rule for port, protocol and source.
+	RemoveRule(port int, protocol, source string) (bool, error)
+
+	// SetDefaultPolicy sets what happens to incoming traffic no rule matches.
+	SetDefaultPolicy(action Action) error
+
+	// Reload reapplies the saved configuration.
+	Reload() error
+}
+
+// validateRule checks a rule and normalizes its source, so that "any" and
+// host addresses compare equal to how the backends list them.
+func validateRule(port int, protocol, source string, action Action) (Rule, error) {
+	rule := Rule{Port: port, Protocol: strings.ToLower(protocol), Action: action}
+	if port < 1 || port > 65535 {
+		return rule, fmt.Errorf("invalid port: %d", port)
+	}
+	if rule.Protocol != "tcp" && rule.Protocol != "udp" {
+		return rule, fmt.Errorf("invalid protocol: %q", protocol)
+	}
+	switch action {
+	case Allow, Deny, Reject:
+	default:
+		return rule, fmt.Errorf("invalid action: %q", action)
+	}
+
+	var err error
+	rule.Source, err = normalizeSource(source)
+	return rule, err
+}
+
+func normalizeSource(source string) (string, error) {
+	switch source {
+	case "", "any", "anywhere", "0.0.0.0/0", "::/0":
+		return "", nil
+	}
+	if ip := net.ParseIP(source); ip != nil {
+		return ip.String(), nil
+	}
+	_, network, err := net.ParseCIDR(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid source: %q", source)
+	}
+	return network.String(), nil
+}
+
+// checkLockout refuses rules that would stop anyone from reaching sshPort,
+// or that block it for the address steelcut itself connects from. Denying
+// SSH from other sources is fine.
+func checkLockout(cmdManager cm.CommandManager, rule Rule, sshPort int) error {
+	if rule.Port != sshPort || rule.Protocol != "tcp" || rule.Action == Allow {
+		return nil
+	}
+	if rule.Source == "" {
+		return fmt.Errorf("%w: %s", ErrSSHLockout, rule)
+	}
+
+	client, err := sshClientAddress(cmdManager)
+	if err != nil {
+		return fmt.Errorf("failed to find the SSH client address: %w", err)
+	}
+	if client != nil && sourceContains(rule.Source, client) {
+		return fmt.Errorf("%w: %s covers the SSH client %s", ErrSSHLockout, rule, client)
+	}
+	return nil
+}
+
+// sshClientAddress returns the address the host sees steelcut connecting
+// from, taken from SSH_CLIENT. It is nil when the commands do not run over
+// SSH. sudo would reset the environment, so it is not used.
+func sshClientAddress(cmdManager cm.CommandManager) (net.IP, error) {
+	result, err := cm.RunChecked(context.TODO(), cmdManager, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", `echo "$SSH_CLIENT"`},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(result.STDOUT)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return net.ParseIP(fields[0]), nil
+}
+
+// sourceContains reports whether a normalized source covers ip.
+func sourceContains(source string, ip net.IP) bool {
+	if _, network, err := net.ParseCIDR(source); err == nil {
+		return network.Contains(ip)
+	}
+	sourceIP := net.ParseIP(source)
+	return sourceIP != nil && sourceIP.Equal(ip)
+}
+
+// checkLockoutRemoval refuses to remove the rule allowing SSH from anywhere.
+func checkLockoutRemoval(rule Rule, sshPort int) error {
+	if rule.Port == sshPort && rule.Protocol == "tcp" && rule.Source == "" && rule.Action == Allow {
+		return fmt.Errorf("%w: removing %s", ErrSSHLockout, rule)
+	}
+	return nil
+}
+
+// allowSSH makes sure SSH stays reachable before the default policy stops
+// allowing everything else. A rule it adds is read back before the policy is
+// changed, so a change that silently did not apply cannot lock the host out.
+func allowSSH(fm FirewallManager, action Action, sshPort int) error {
+	if action == Allow {
+		return nil
+	}
+	changed, err := fm.EnsureRule(sshPort, "tcp", "", Allow)
+	if err != nil {
+		return fmt.Errorf("failed to allow SSH before changing the default policy: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	rules, err := fm.ListRules()
+	if err != nil {
+		return fmt.Errorf("failed to confirm SSH is allowed: %w", err)
+	}
+	for _, rule := range rules {
+		if rule.Port == sshPort && rule.Protocol == "tcp" && rule.Source == "" && rule.Action == Allow {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: the rule allowing SSH is missing after adding it", ErrSSHLockout)
+}
+
+func sshPortOrDefault(port int) int {
+	if port == 0 {
+		return DefaultSSHPort
+	}
+	return port
+}
+
+// run runs a firewall command as root, folding its stderr into the error.
+func run(cmdManager cm.CommandManager, command string, args ...string) (string, error) {
+	result, err := cm.RunChecked(context.TODO(), cmdManager, cm.CommandConfig{
+		Command: command,
+		Args:    args,
+		Sudo:    true,
+	}, nil)
+	return result.STDOUT, err
+}
@@ -0,0 +1,152 @@
+package firewallmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// Backend names a firewall tool.
+type Backend string
+
+const (
+	Ufw       Backend = "ufw"
+	Firewalld Backend = "firewalld"
+	Nftables  Backend = "nftables"
+)
+
+// backendCommands are the commands whose presence shows a backend is installed.
+var backendCommands = map[string]Backend{"ufw": Ufw, "firewall-cmd": Firewalld, "nft": Nftables}
+
+// detectScript reports the installed firewall tools and which of the ones
+// with an on/off state are running. It runs as root, as ufw status needs it
+// and the tools live in sbin directories.
+const detectScript = `for t in ufw firewall-cmd nft; do command -v $t >/dev/null 2>&1 && echo "installed $t"; done
+ufw status 2>/dev/null | grep -q '^Status: active' && echo 'active ufw'
+firewall-cmd --state >/dev/null 2>&1 && echo 'active firewall-cmd'
+true`
+
+// LinuxFirewallManager delegates to the backend that manages the host's
+// firewall, found on first use. A running ufw or firewalld always wins, as
+// rules added behind its back would be lost on its next reload. Otherwise
+// Preferred is used when installed, then whichever backend is.
+type LinuxFirewallManager struct {
+	CommandManager cm.CommandManager
+	Preferred      Backend
+	SSHPort        int
+
+	once    sync.Once
+	backend FirewallManager
+	name    Backend
+	err     error
+}
+
+// Backend returns the backend in use, detecting it if needed.
+func (l *LinuxFirewallManager) Backend() (Backend, error) {
+	_, err := l.manager()
+	return l.name, err
+}
+
+func (l *LinuxFirewallManager) manager() (FirewallManager, error) {
+	l.once.Do(func() {
+		result, err := l.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "sh",
+			Args:    []string{"-c", detectScript},
+			Sudo:    true,
+		})
+		if err != nil {
+			l.err = fmt.Errorf("failed to detect firewall: %w: %s", err, strings.TrimSpace(result.STDERR))
+			return
+		}
+
+		l.name, l.err = chooseBackend(result.STDOUT, l.Preferred)
+		switch l.name {
+		case Ufw:
+			l.backend = &UfwFirewallManager{CommandManager: l.CommandManager, SSHPort: l.SSHPort}
+		case Firewalld:
+			l.backend = &FirewalldFirewallManager{CommandManager: l.CommandManager, SSHPort: l.SSHPort}
+		case Nftables:
+			l.backend = &NftablesFirewallManager{CommandManager: l.CommandManager, SSHPort: l.SSHPort}
+		}
+	})
+	return l.backend, l.err
+}
+
+func chooseBackend(output string, preferred Backend) (Backend, error) {
+	installed := make(map[Backend]bool)
+	var active []Backend
+	for _, line := range strings.Split(output, "\n") {
+		state, command, found := strings.Cut(strings.TrimSpace(line), " ")
+		backend, known := backendCommands[command]
+		if !found || !known {
+			continue
+		}
+		switch state {
+		case "installed":
+			installed[backend] = true
+		case "active":
+			active = append(active, backend)
+		}
+	}
+
+	for _, backend := range active {
+		if backend == preferred {
+			return backend, nil
+		}
+	}
+	if len(active) > 0 {
+		return active[0], nil
+	}
+	if installed[preferred] {
+		return preferred, nil
+	}
+	for _, backend := range []Backend{Ufw, Firewalld, Nftables} {
+		if installed[backend] {
+			return backend, nil
+		}
+	}
+	return "", fmt.Errorf("no supported firewall is installed (ufw, firewalld or nftables)")
+}
+
+func (l *LinuxFirewallManager) ListRules() ([]Rule, error) {
+	backend, err := l.manager()
+	if err != nil {
+		return nil, err
+	}
+	return backend.ListRules()
+}
+
+func (l *LinuxFirewallManager) EnsureRule(port int, protocol, source string, action Action) (bool, error) {
+	backend, err := l.manager()
+	if err != nil {
+		return false, err
+	}
+	return backend.EnsureRule(port, protocol, source, action)
+}
+
+func (l *LinuxFirewallManager) RemoveRule(port int, protocol, source string) (bool, error) {
+	backend, err := l.manager()
+	if err != nil {
+		return false, err
+	}
+	return backend.RemoveRule(port, protocol, source)
+}
+
+func (l *LinuxFirewallManager) SetDefaultPolicy(action Action) error {
+	backend, err := l.manager()
+	if err != nil {
+		return err
+	}
+	return backend.SetDefaultPolicy(action)
+}
+
+func (l *LinuxFirewallManager) Reload() error {
+	backend, err := l.manager()
+	if err != nil {
+		return err
+	}
+	return backend.Reload()
+}
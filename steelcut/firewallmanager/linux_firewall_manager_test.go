@@ -0,0 +1,112 @@
+package firewallmanager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// MockCommandManager answers by full command line and records what was run.
+// Commands listed in Failures exit non-zero with the given stderr. Running a
+// command listed in Updates replaces the outputs of other commands, so later
+// listings can show a change.
+type MockCommandManager struct {
+	Outputs  map[string]string
+	Failures map[string]string
+	Updates  map[string]map[string]string
+	Configs  []cm.CommandConfig
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Configs = append(m.Configs, config)
+	line := commandLine(config)
+	if stderr, failed := m.Failures[line]; failed {
+		return cm.CommandResult{STDERR: stderr, ExitCode: 1}, errors.New("exit status 1")
+	}
+	for updated, output := range m.Updates[line] {
+		m.Outputs[updated] = output
+	}
+	return cm.CommandResult{STDOUT: m.Outputs[line]}, nil
+}
+
+// commands returns the command lines run, in order.
+func (m *MockCommandManager) commands() []string {
+	lines := make([]string, len(m.Configs))
+	for i, config := range m.Configs {
+		lines[i] = commandLine(config)
+	}
+	return lines
+}
+
+func commandLine(config cm.CommandConfig) string {
+	return strings.Join(append([]string{config.Command}, config.Args...), " ")
+}
+
+func TestChooseBackend(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		preferred Backend
+		expected  Backend
+	}{
+		{"active wins over preferred", "installed ufw\ninstalled firewall-cmd\ninstalled nft\nactive firewall-cmd\n", Ufw, Firewalld},
+		{"preferred among installed", "installed ufw\ninstalled nft\n", Ufw, Ufw},
+		{"fallback when preferred is missing", "installed nft\n", Firewalld, Nftables},
+		{"preferred among active", "active ufw\nactive firewall-cmd\n", Firewalld, Firewalld},
+	}
+	for _, tt := range tests {
+		backend, err := chooseBackend(tt.output, tt.preferred)
+		if err != nil {
+			t.Errorf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if backend != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, backend)
+		}
+	}
+
+	if _, err := chooseBackend("", Ufw); err == nil {
+		t.Error("Expected an error when no firewall is installed")
+	}
+}
+
+func TestLinuxFirewallManagerDetectsOnce(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"sh -c " + detectScript: "installed ufw\ninstalled nft\n",
+		"ufw show added":        "Added user rules (see 'ufw status' for running firewall):\nufw allow 22/tcp\n",
+	}}
+	manager := &LinuxFirewallManager{CommandManager: mockCmd, Preferred: Ufw}
+
+	for i := 0; i < 2; i++ {
+		rules, err := manager.ListRules()
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(rules) != 1 {
+			t.Errorf("Expected one rule, got %+v", rules)
+		}
+	}
+
+	detections := 0
+	for _, line := range mockCmd.commands() {
+		if line == "sh -c "+detectScript {
+			detections++
+		}
+	}
+	if detections != 1 {
+		t.Errorf("Expected the backend to be detected once, got %d", detections)
+	}
+	if backend, _ := manager.Backend(); backend != Ufw {
+		t.Errorf("Expected ufw, got %s", backend)
+	}
+}
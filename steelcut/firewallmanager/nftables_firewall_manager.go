@@ -0,0 +1,243 @@
+package firewallmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// DefaultNftablesRulesFile is where the steelcut table is saved after every
+// change. Include it from /etc/nftables.conf to have it loaded at boot.
+const DefaultNftablesRulesFile = "/etc/nftables.d/steelcut.nft"
+
+const (
+	nftTable = "steelcut"
+	nftChain = "input"
+)
+
+// nftRule matches the rules steelcut adds, as printed by nft -a.
+var nftRule = regexp.MustCompile(`^(?:ip6? saddr (\S+) )?(tcp|udp) dport (\d+) (accept|drop|reject)(?: with [^#]*)? # handle (\d+)$`)
+
+var nftVerdicts = map[string]Action{"accept": Allow, "drop": Deny, "reject": Reject}
+
+// nftChainSpec declares the input chain. Return traffic and loopback are
+// always accepted, so a drop policy only affects new incoming connections.
+const nftChainSpec = `table inet steelcut {
+	chain input {
+		type filter hook input priority 0; policy accept;
+		ct state established,related accept
+		iif "lo" accept
+	}
+}`
+
+// NftablesFirewallManager manages rules in a table of its own, inet steelcut,
+// leaving any other tables alone. The table is created on first use and saved
+// to RulesFile after every change.
+type NftablesFirewallManager struct {
+	CommandManager cm.CommandManager
+	SSHPort        int
+	RulesFile      string // DefaultNftablesRulesFile when empty
+}
+
+// nftablesRule is a listed rule along with the handle needed to delete it.
+type nftablesRule struct {
+	Rule
+	handle string
+}
+
+func (n *NftablesFirewallManager) ListRules() ([]Rule, error) {
+	rules, err := n.rules()
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]Rule, len(rules))
+	for i, rule := range rules {
+		listed[i] = rule.Rule
+	}
+	return listed, nil
+}
+
+func (n *NftablesFirewallManager) rules() ([]nftablesRule, error) {
+	result, err := cm.RunChecked(context.TODO(), n.CommandManager, cm.CommandConfig{
+		Command: "nft",
+		Args:    []string{"-a", "list", "chain", "inet", nftTable, nftChain},
+		Sudo:    true,
+	}, nil)
+	if err != nil {
+		// The table only exists once the first rule has been added.
+		if strings.Contains(result.STDERR, "No such file or directory") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list nftables rules: %w", err)
+	}
+	return parseNftChain(result.STDOUT), nil
+}
+
+// EnsureRule appends allow rules and inserts deny and reject rules at the top
+// of the chain, as the first rule that matches decides.
+func (n *NftablesFirewallManager) EnsureRule(port int, protocol, source string, action Action) (bool, error) {
+	rule, err := validateRule(port, protocol, source, action)
+	if err != nil {
+		return false, err
+	}
+	if err := checkLockout(n.CommandManager, rule, sshPortOrDefault(n.SSHPort)); err != nil {
+		return false, err
+	}
+
+	rules, err := n.rules()
+	if err != nil {
+		return false, err
+	}
+
+	var script []string
+	if existing, found := findNftablesRule(rules, rule); found {
+		if existing.Action == action {
+			return false, nil
+		}
+		script = append(script, nftDelete(existing))
+	}
+
+	verb := "add"
+	if action != Allow {
+		verb = "insert"
+	}
+	script = append(script, verb+" rule inet "+nftTable+" "+nftChain+" "+nftMatch(rule)+" "+nftVerdict(action))
+	return true, n.change(script...)
+}
+
+func (n *NftablesFirewallManager) RemoveRule(port int, protocol, source string) (bool, error) {
+	rule, err := validateRule(port, protocol, source, Allow)
+	if err != nil {
+		return false, err
+	}
+
+	rules, err := n.rules()
+	if err != nil {
+		return false, err
+	}
+	existing, found := findNftablesRule(rules, rule)
+	if !found {
+		return false, nil
+	}
+	if err := checkLockoutRemoval(existing.Rule, sshPortOrDefault(n.SSHPort)); err != nil {
+		return false, err
+	}
+	return true, n.change(nftDelete(existing))
+}
+
+// SetDefaultPolicy sets the policy of the input chain. nftables has no reject
+// policy, so only Allow and Deny are accepted.
+func (n *NftablesFirewallManager) SetDefaultPolicy(action Action) error {
+	var policy string
+	switch action {
+	case Allow:
+		policy = "accept"
+	case Deny:
+		policy = "drop"
+	default:
+		return fmt.Errorf("nftables does not support a %q default policy", action)
+	}
+
+	if err := allowSSH(n, action, sshPortOrDefault(n.SSHPort)); err != nil {
+		return err
+	}
+	return n.change("add chain inet " + nftTable + " " + nftChain + " { type filter hook input priority 0; policy " + policy + "; }")
+}
+
+// Reload loads the table from RulesFile, undoing changes made since it was
+// last saved.
+func (n *NftablesFirewallManager) Reload() error {
+	_, err := run(n.CommandManager, "nft", "-f", n.rulesFile())
+	return err
+}
+
+// change creates the table if needed, applies the nft commands as a single
+// transaction and saves the table, all in one round trip. The saved file
+// flushes the table before declaring it, so it can be loaded repeatedly.
+func (n *NftablesFirewallManager) change(commands ...string) error {
+	path := cm.ShellQuote(n.rulesFile())
+	script := "set -e\n" +
+		"nft list table inet " + nftTable + " >/dev/null 2>&1 || nft -f - <<'EOF'\n" + nftChainSpec + "\nEOF\n" +
+		"nft -f - <<'EOF'\n" + strings.Join(commands, "\n") + "\nEOF\n" +
+		"mkdir -p \"$(dirname " + path + ")\"\n" +
+		"{ echo 'add table inet " + nftTable + "'; echo 'flush table inet " + nftTable + "'; nft list table inet " + nftTable + "; } > " + path + ".tmp\n" +
+		"mv " + path + ".tmp " + path + "\n"
+
+	_, err := cm.RunChecked(context.TODO(), n.CommandManager, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", script},
+		Sudo:    true,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update nftables rules: %w", err)
+	}
+	return nil
+}
+
+func (n *NftablesFirewallManager) rulesFile() string {
+	if n.RulesFile == "" {
+		return DefaultNftablesRulesFile
+	}
+	return n.RulesFile
+}
+
+func findNftablesRule(rules []nftablesRule, rule Rule) (nftablesRule, bool) {
+	for _, r := range rules {
+		if r.Port == rule.Port && r.Protocol == rule.Protocol && r.Source == rule.Source {
+			return r, true
+		}
+	}
+	return nftablesRule{}, false
+}
+
+func nftDelete(rule nftablesRule) string {
+	return "delete rule inet " + nftTable + " " + nftChain + " handle " + rule.handle
+}
+
+func nftMatch(rule Rule) string {
+	match := rule.Protocol + " dport " + strconv.Itoa(rule.Port)
+	if rule.Source == "" {
+		return match
+	}
+	family := "ip"
+	if strings.Contains(rule.Source, ":") {
+		family = "ip6"
+	}
+	return family + " saddr " + rule.Source + " " + match
+}
+
+func nftVerdict(action Action) string {
+	switch action {
+	case Allow:
+		return "accept"
+	case Deny:
+		return "drop"
+	}
+	return "reject"
+}
+
+// parseNftChain parses nft -a list chain output. The base rules of the chain
+// and rules steelcut does not write, such as port ranges, are left out.
+func parseNftChain(output string) []nftablesRule {
+	var rules []nftablesRule
+	for _, line := range strings.Split(output, "\n") {
+		match := nftRule.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		source, err := normalizeSource(match[1])
+		if err != nil {
+			continue
+		}
+		port, _ := strconv.Atoi(match[3])
+		rules = append(rules, nftablesRule{
+			Rule:   Rule{Port: port, Protocol: match[2], Source: source, Action: nftVerdicts[match[4]]},
+			handle: match[5],
+		})
+	}
+	return rules
+}
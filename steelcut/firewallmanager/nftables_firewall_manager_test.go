@@ -0,0 +1,63 @@
+package firewallmanager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nftChainListing = `table inet steelcut {
+	chain input { # handle 1
+		type filter hook input priority filter; policy drop;
+		ct state established,related accept # handle 2
+		iif "lo" accept # handle 3
+		ip saddr 198.51.100.0/24 tcp dport 22 drop # handle 7
+		ip6 saddr 2001:db8::1 udp dport 53 reject with icmpx port-unreachable # handle 8
+		tcp dport 22 accept # handle 5
+		tcp dport 8000-8100 accept # handle 6
+	}
+}
+`
+
+func TestParseNftChain(t *testing.T) {
+	rules := parseNftChain(nftChainListing)
+
+	expected := []nftablesRule{
+		{Rule: Rule{Port: 22, Protocol: "tcp", Source: "198.51.100.0/24", Action: Deny}, handle: "7"},
+		{Rule: Rule{Port: 53, Protocol: "udp", Source: "2001:db8::1", Action: Reject}, handle: "8"},
+		{Rule: Rule{Port: 22, Protocol: "tcp", Action: Allow}, handle: "5"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, rules)
+	}
+}
+
+func TestNftablesEnsureRule(t *testing.T) {
+	listCommand := "nft -a list chain inet steelcut input"
+	mockCmd := &MockCommandManager{
+		Outputs:  map[string]string{},
+		Failures: map[string]string{listCommand: "Error: No such file or directory"},
+	}
+	manager := &NftablesFirewallManager{CommandManager: mockCmd, RulesFile: "/etc/nftables/steelcut.nft"}
+
+	changed, err := manager.EnsureRule(443, "tcp", "", Allow)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+
+	script := mockCmd.Configs[len(mockCmd.Configs)-1].Args[1]
+	for _, part := range []string{
+		"add rule inet steelcut input tcp dport 443 accept\n",
+		nftChainSpec,
+		"> /etc/nftables/steelcut.nft.tmp\n",
+	} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Expected the script to contain %q, got:\n%s", part, script)
+		}
+	}
+
+	// A reject policy does not exist in nftables.
+	if err := manager.SetDefaultPolicy(Reject); err == nil {
+		t.Error("Expected an error for a reject policy")
+	}
+}
@@ -0,0 +1,235 @@
+package firewallmanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+var ufwPort = regexp.MustCompile(`^(\d+)(?:/(tcp|udp))?$`)
+
+// UfwFirewallManager manages rules with ufw, as used on Ubuntu and Debian.
+// Rules can be managed while ufw is inactive and take effect once it is
+// enabled.
+type UfwFirewallManager struct {
+	CommandManager cm.CommandManager
+	SSHPort        int
+}
+
+// ufwRule is a rule as ufw stores it. A rule without a protocol covers both
+// TCP and UDP and is listed once for each.
+type ufwRule struct {
+	Rule
+	verb     string // allow, deny, reject or limit
+	protocol string
+}
+
+func (u *UfwFirewallManager) ListRules() ([]Rule, error) {
+	rules, err := u.rules()
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]Rule, len(rules))
+	for i, rule := range rules {
+		listed[i] = rule.Rule
+	}
+	return listed, nil
+}
+
+// rules reads the rules ufw was given with ufw show added, which unlike
+// ufw status also works while the firewall is disabled.
+func (u *UfwFirewallManager) rules() ([]ufwRule, error) {
+	output, err := run(u.CommandManager, "ufw", "show", "added")
+	if err != nil {
+		return nil, err
+	}
+	return parseUfwAdded(output), nil
+}
+
+// EnsureRule adds allow rules at the end and deny and reject rules at the
+// top, as ufw applies the first rule that matches.
+func (u *UfwFirewallManager) EnsureRule(port int, protocol, source string, action Action) (bool, error) {
+	rule, err := validateRule(port, protocol, source, action)
+	if err != nil {
+		return false, err
+	}
+	if err := checkLockout(u.CommandManager, rule, sshPortOrDefault(u.SSHPort)); err != nil {
+		return false, err
+	}
+
+	rules, err := u.rules()
+	if err != nil {
+		return false, err
+	}
+	if existing, found := findUfwRule(rules, rule); found {
+		if existing.Action == action {
+			return false, nil
+		}
+		if err := u.delete(existing, rule.Protocol); err != nil {
+			return false, err
+		}
+	}
+
+	verb, position := string(action), []string{}
+	if action != Allow {
+		position = []string{"prepend"}
+	}
+	_, err = run(u.CommandManager, "ufw", append(position, ufwSpec(verb, rule.Protocol, rule)...)...)
+	return true, err
+}
+
+func (u *UfwFirewallManager) RemoveRule(port int, protocol, source string) (bool, error) {
+	rule, err := validateRule(port, protocol, source, Allow)
+	if err != nil {
+		return false, err
+	}
+
+	rules, err := u.rules()
+	if err != nil {
+		return false, err
+	}
+	existing, found := findUfwRule(rules, rule)
+	if !found {
+		return false, nil
+	}
+	if err := checkLockoutRemoval(existing.Rule, sshPortOrDefault(u.SSHPort)); err != nil {
+		return false, err
+	}
+	return true, u.delete(existing, rule.Protocol)
+}
+
+// delete removes existing for protocol. A rule covering both protocols is
+// replaced by one for the other protocol only.
+func (u *UfwFirewallManager) delete(existing ufwRule, protocol string) error {
+	if _, err := run(u.CommandManager, "ufw", append([]string{"--force", "delete"}, ufwSpec(existing.verb, existing.protocol, existing.Rule)...)...); err != nil {
+		return err
+	}
+	if existing.protocol != "" {
+		return nil
+	}
+	other := "udp"
+	if protocol == "udp" {
+		other = "tcp"
+	}
+	_, err := run(u.CommandManager, "ufw", ufwSpec(existing.verb, other, existing.Rule)...)
+	return err
+}
+
+func (u *UfwFirewallManager) SetDefaultPolicy(action Action) error {
+	if action != Allow && action != Deny && action != Reject {
+		return fmt.Errorf("invalid action: %q", action)
+	}
+	if err := allowSSH(u, action, sshPortOrDefault(u.SSHPort)); err != nil {
+		return err
+	}
+	_, err := run(u.CommandManager, "ufw", "default", string(action), "incoming")
+	return err
+}
+
+func (u *UfwFirewallManager) Reload() error {
+	_, err := run(u.CommandManager, "ufw", "reload")
+	return err
+}
+
+func findUfwRule(rules []ufwRule, rule Rule) (ufwRule, bool) {
+	for _, r := range rules {
+		if r.Port == rule.Port && r.Protocol == rule.Protocol && r.Source == rule.Source {
+			return r, true
+		}
+	}
+	return ufwRule{}, false
+}
+
+func ufwSpec(verb, protocol string, rule Rule) []string {
+	source := rule.Source
+	if source == "" {
+		source = "any"
+	}
+	args := []string{verb}
+	if protocol != "" {
+		args = append(args, "proto", protocol)
+	}
+	return append(args, "from", source, "to", "any", "port", strconv.Itoa(rule.Port))
+}
+
+// parseUfwAdded parses ufw show added, which prints each rule as the ufw
+// command that added it, such as "ufw allow 22/tcp" or
+// "ufw deny from 10.0.0.0/8 to any port 80 proto tcp". Outgoing, routed,
+// interface and application rules and port ranges are left out.
+func parseUfwAdded(output string) []ufwRule {
+	var rules []ufwRule
+	for _, line := range strings.Split(output, "\n") {
+		if before, _, found := strings.Cut(line, " comment "); found {
+			line = before
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "ufw" {
+			continue
+		}
+		fields = fields[1:]
+
+		verb := fields[0]
+		action, ok := map[string]Action{"allow": Allow, "limit": Allow, "deny": Deny, "reject": Reject}[verb]
+		if !ok {
+			continue
+		}
+		fields = fields[1:]
+		if fields[0] == "in" {
+			fields = fields[1:]
+		}
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "log") {
+			fields = fields[1:]
+		}
+
+		rule := ufwRule{Rule: Rule{Action: action}, verb: verb}
+		port := ""
+		if len(fields) == 1 {
+			// The short form: a port with an optional protocol.
+			match := ufwPort.FindStringSubmatch(fields[0])
+			if match == nil {
+				continue
+			}
+			port, rule.protocol = match[1], match[2]
+		} else {
+			valid := len(fields)%2 == 0
+			for i := 0; valid && i+1 < len(fields); i += 2 {
+				switch value := fields[i+1]; fields[i] {
+				case "from":
+					source, err := normalizeSource(value)
+					rule.Source, valid = source, err == nil
+				case "to":
+					valid = value == "any"
+				case "port":
+					port = value
+				case "proto":
+					rule.protocol = value
+				default:
+					valid = false
+				}
+			}
+			if !valid {
+				continue
+			}
+		}
+
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		rule.Port = n
+
+		protocols := []string{rule.protocol}
+		if rule.protocol == "" {
+			protocols = []string{"tcp", "udp"}
+		}
+		for _, protocol := range protocols {
+			r := rule
+			r.Protocol = protocol
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
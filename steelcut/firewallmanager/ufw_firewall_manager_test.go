@@ -0,0 +1,97 @@
+package firewallmanager
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const ufwAdded = `Added user rules (see 'ufw status' for running firewall):
+ufw limit 22/tcp
+ufw allow 53
+ufw deny from 10.0.0.0/8 to any port 80 proto tcp comment 'legacy network'
+ufw allow out 123/udp
+ufw allow in on eth1 to any port 5432
+ufw allow OpenSSH
+ufw allow 6000:6007/tcp
+`
+
+func TestParseUfwAdded(t *testing.T) {
+	expected := []Rule{
+		{Port: 22, Protocol: "tcp", Action: Allow},
+		{Port: 53, Protocol: "tcp", Action: Allow},
+		{Port: 53, Protocol: "udp", Action: Allow},
+		{Port: 80, Protocol: "tcp", Source: "10.0.0.0/8", Action: Deny},
+	}
+
+	manager := &UfwFirewallManager{CommandManager: &MockCommandManager{Outputs: map[string]string{"ufw show added": ufwAdded}}}
+	rules, err := manager.ListRules()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, rules)
+	}
+}
+
+func TestUfwEnsureRule(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"ufw show added": ufwAdded}}
+	manager := &UfwFirewallManager{CommandManager: mockCmd}
+
+	// Only UDP of the rule covering both protocols changes, so TCP is
+	// added back on its own.
+	changed, err := manager.EnsureRule(53, "udp", "any", Reject)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	expected := []string{
+		"ufw show added",
+		"ufw --force delete allow from any to any port 53",
+		"ufw allow proto tcp from any to any port 53",
+		"ufw prepend reject proto udp from any to any port 53",
+	}
+	if commands := mockCmd.commands(); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected %q, got %q", expected, commands)
+	}
+
+	changed, err = manager.EnsureRule(80, "tcp", "10.0.0.0/8", Deny)
+	if err != nil || changed {
+		t.Errorf("Expected an existing rule to be left alone, got %v, %v", changed, err)
+	}
+}
+
+func TestUfwLockout(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"ufw show added":           ufwAdded,
+		`sh -c echo "$SSH_CLIENT"`: "10.1.2.3 51234 22\n",
+	}}
+	manager := &UfwFirewallManager{CommandManager: mockCmd}
+
+	if _, err := manager.EnsureRule(22, "tcp", "", Deny); !errors.Is(err, ErrSSHLockout) {
+		t.Errorf("Expected ErrSSHLockout, got %v", err)
+	}
+	if _, err := manager.RemoveRule(22, "tcp", "0.0.0.0/0"); !errors.Is(err, ErrSSHLockout) {
+		t.Errorf("Expected ErrSSHLockout, got %v", err)
+	}
+	if _, err := manager.EnsureRule(22, "tcp", "192.0.2.7", Deny); err != nil {
+		t.Errorf("Expected denying a single source to be allowed, got %v", err)
+	}
+	// steelcut connects from 10.1.2.3, which the network covers.
+	if _, err := manager.EnsureRule(22, "tcp", "10.0.0.0/8", Deny); !errors.Is(err, ErrSSHLockout) {
+		t.Errorf("Expected ErrSSHLockout for the SSH client's network, got %v", err)
+	}
+	if _, err := manager.EnsureRule(22, "tcp", "10.1.2.3", Reject); !errors.Is(err, ErrSSHLockout) {
+		t.Errorf("Expected ErrSSHLockout for the SSH client, got %v", err)
+	}
+
+	// The SSH rule is already there, being a limit rule, so only the policy
+	// changes.
+	mockCmd.Configs = nil
+	if err := manager.SetDefaultPolicy(Deny); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []string{"ufw show added", "ufw default deny incoming"}
+	if commands := mockCmd.commands(); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected %q, got %q", expected, commands)
+	}
+}
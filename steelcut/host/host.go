@@ -12,8 +12,13 @@ import (
 
 	"github.com/steelcutops/steelcut/common"
 	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/cronmanager"
+	"github.com/steelcutops/steelcut/steelcut/factsmanager"
 	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/firewallmanager"
 	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+	"github.com/steelcutops/steelcut/steelcut/kernelmanager"
+	"github.com/steelcutops/steelcut/steelcut/logmanager"
 	"github.com/steelcutops/steelcut/steelcut/networkmanager"
 	"github.com/steelcutops/steelcut/steelcut/packagemanager"
 	"github.com/steelcutops/steelcut/steelcut/servicemanager"
@@ -26,12 +31,17 @@ type Host struct {
 	SSHClient SSHClient
 	Hostname  string
 
-	PackageManager packagemanager.PackageManager
-	NetworkManager networkmanager.NetworkManager
-	FileManager    filemanager.FileManager
-	HostManager    hostmanager.HostManager
-	ServiceManager servicemanager.ServiceManager
-	CommandManager commandmanager.CommandManager
+	PackageManager  packagemanager.PackageManager
+	NetworkManager  networkmanager.NetworkManager
+	FileManager     filemanager.FileManager
+	HostManager     hostmanager.HostManager
+	ServiceManager  servicemanager.ServiceManager
+	CommandManager  commandmanager.CommandManager
+	FactsManager    factsmanager.FactsManager
+	KernelManager   kernelmanager.KernelManager // only set for Linux hosts
+	CronManager     cronmanager.CronManager
+	LogManager      logmanager.LogManager
+	FirewallManager firewallmanager.FirewallManager // only set for Linux hosts
 }
 
 // SSHClient defines an interface for dialing and establishing an SSH connection.
@@ -50,9 +60,21 @@ func (c RealSSHClient) Dial(network, addr string, config *ssh.ClientConfig, time
 		return nil, err
 	}
 
+	// Bound the handshake too, as a host that is still booting can accept the
+	// connection before sshd is ready to answer it.
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
 	// Create an SSH client connection using the underlying network connection
 	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
 	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		sshConn.Close()
 		return nil, err
 	}
 	return ssh.NewClient(sshConn, chans, reqs), nil
@@ -125,25 +147,39 @@ func (h *Host) detectLinuxType(ctx context.Context) (OSType, error) {
 	osRelease := result.STDOUT
 	slog.Debug("Detecting Linux type", "hostname", h.Hostname, "osrelease", osRelease)
 
-	if strings.Contains(osRelease, "ID=ubuntu") {
+	switch id := osReleaseID(osRelease); {
+	case id == "ubuntu":
 		return LinuxUbuntu, nil
-	} else if strings.Contains(osRelease, "ID=debian") {
+	case id == "debian":
 		return LinuxDebian, nil
-	} else if strings.Contains(osRelease, "ID=fedora") {
+	case id == "fedora":
 		return LinuxFedora, nil
-	} else if strings.Contains(osRelease, "ID=rhel") {
+	case id == "rhel":
 		return LinuxRedHat, nil
-	} else if strings.Contains(osRelease, "ID=centos") {
+	case id == "centos":
 		return LinuxCentOS, nil
-	} else if strings.Contains(osRelease, "ID=arch") {
+	case id == "arch":
 		return LinuxArch, nil
-	} else if strings.Contains(osRelease, "ID=opensuse") {
+	case strings.HasPrefix(id, "opensuse"), id == "sles":
 		return LinuxOpenSUSE, nil
+	case id == "alpine":
+		return LinuxAlpine, nil
 	}
 
 	return Unknown, fmt.Errorf("unsupported Linux distribution detected on host: %s osRelease: %s", h.Hostname, osRelease)
 }
 
+// osReleaseID returns the ID field of /etc/os-release, which some
+// distributions quote, as in ID="rhel", and others do not.
+func osReleaseID(osRelease string) string {
+	for _, line := range strings.Split(osRelease, "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), "ID="); found {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return ""
+}
+
 // String method provides the string representation of the OSType.
 func (o OSType) String() string {
 	return [...]string{
@@ -156,5 +192,6 @@ func (o OSType) String() string {
 		"Linux_CentOS",
 		"Linux_Arch",
 		"Linux_OpenSUSE",
+		"Linux_Alpine",
 	}[o]
 }
@@ -8,8 +8,13 @@ import (
 	"os/user"
 
 	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/cronmanager"
+	"github.com/steelcutops/steelcut/steelcut/factsmanager"
 	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/firewallmanager"
 	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+	"github.com/steelcutops/steelcut/steelcut/kernelmanager"
+	"github.com/steelcutops/steelcut/steelcut/logmanager"
 	"github.com/steelcutops/steelcut/steelcut/networkmanager"
 	"github.com/steelcutops/steelcut/steelcut/packagemanager"
 	"github.com/steelcutops/steelcut/steelcut/servicemanager"
@@ -67,7 +72,7 @@ func NewHost(hostname string, options ...HostOption) (*Host, error) {
 	}
 
 	switch osType {
-	case LinuxUbuntu, LinuxDebian, LinuxFedora, LinuxRedHat, LinuxCentOS, LinuxArch, LinuxOpenSUSE:
+	case LinuxUbuntu, LinuxDebian, LinuxFedora, LinuxRedHat, LinuxCentOS, LinuxArch, LinuxOpenSUSE, LinuxAlpine:
 		configureLinuxHost(ch, ch.CommandManager, osType)
 
 	case Darwin:
@@ -91,24 +96,48 @@ func configureLinuxHost(ch *Host, cmdManager commandmanager.CommandManager, osTy
 		pkgManager = &packagemanager.YumPackageManager{CommandManager: cmdManager}
 	case LinuxAlpine:
 		pkgManager = &packagemanager.ApkPackageManager{CommandManager: cmdManager}
+	case LinuxArch:
+		pkgManager = &packagemanager.PacmanPackageManager{CommandManager: cmdManager}
+	case LinuxOpenSUSE:
+		pkgManager = &packagemanager.ZypperPackageManager{CommandManager: cmdManager}
 
 	default:
 		pkgManager = nil
 	}
 
+	// The distribution's own firewall tool is preferred, but the backend
+	// is only settled on first use, once the installed tools are known.
+	var firewall firewallmanager.Backend
+	switch osType {
+	case LinuxUbuntu, LinuxDebian:
+		firewall = firewallmanager.Ufw
+	case LinuxFedora, LinuxRedHat, LinuxCentOS, LinuxOpenSUSE:
+		firewall = firewallmanager.Firewalld
+	default:
+		firewall = firewallmanager.Nftables
+	}
+
 	ch.CommandManager = cmdManager
 	ch.FileManager = &filemanager.UnixFileManager{CommandManager: cmdManager}
-	ch.HostManager = &hostmanager.UnixHostManager{CommandManager: cmdManager}
-	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager}
+	ch.HostManager = &hostmanager.UnixHostManager{CommandManager: cmdManager, FileManager: ch.FileManager}
+	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager, FileManager: ch.FileManager}
 	ch.ServiceManager = &servicemanager.LinuxServiceManager{CommandManager: cmdManager}
 	ch.PackageManager = pkgManager
+	ch.FactsManager = &factsmanager.UnixFactsManager{CommandManager: cmdManager}
+	ch.KernelManager = &kernelmanager.LinuxKernelManager{CommandManager: cmdManager, FileManager: ch.FileManager}
+	ch.CronManager = &cronmanager.UnixCronManager{CommandManager: cmdManager, FileManager: ch.FileManager}
+	ch.LogManager = &logmanager.UnixLogManager{CommandManager: cmdManager}
+	ch.FirewallManager = &firewallmanager.LinuxFirewallManager{CommandManager: cmdManager, Preferred: firewall}
 }
 
 func configureMacHost(ch *Host, cmdManager commandmanager.CommandManager) {
 	ch.CommandManager = cmdManager
 	ch.FileManager = &filemanager.UnixFileManager{CommandManager: cmdManager}
-	ch.HostManager = &hostmanager.UnixHostManager{CommandManager: cmdManager}
-	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager}
+	ch.HostManager = &hostmanager.UnixHostManager{CommandManager: cmdManager, FileManager: ch.FileManager}
+	ch.NetworkManager = &networkmanager.UnixNetworkManager{CommandManager: cmdManager, FileManager: ch.FileManager}
 	ch.ServiceManager = &servicemanager.DarwinServiceManager{CommandManager: cmdManager}
 	ch.PackageManager = &packagemanager.BrewPackageManager{CommandManager: cmdManager}
+	ch.FactsManager = &factsmanager.UnixFactsManager{CommandManager: cmdManager}
+	ch.CronManager = &cronmanager.UnixCronManager{CommandManager: cmdManager, FileManager: ch.FileManager}
+	ch.LogManager = &logmanager.UnixLogManager{CommandManager: cmdManager}
 }
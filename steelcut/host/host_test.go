@@ -0,0 +1,47 @@
+package host
+
+import (
+	"context"
+	"testing"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// MockCommandManager answers every command with Output.
+type MockCommandManager struct {
+	commandmanager.CommandManager
+	Output string
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config commandmanager.CommandConfig) (commandmanager.CommandResult, error) {
+	return commandmanager.CommandResult{STDOUT: m.Output}, nil
+}
+
+func TestDetectLinuxType(t *testing.T) {
+	tests := []struct {
+		osRelease string
+		expected  OSType
+	}{
+		{"NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.19.1\n", LinuxAlpine},
+		{"NAME=\"Red Hat Enterprise Linux\"\nID=\"rhel\"\nID_LIKE=\"fedora\"\n", LinuxRedHat},
+		{"NAME=\"openSUSE Leap\"\nID=\"opensuse-leap\"\nID_LIKE=\"suse opensuse\"\n", LinuxOpenSUSE},
+		{"NAME=\"Ubuntu\"\nID=ubuntu\nID_LIKE=debian\n", LinuxUbuntu},
+		{"NAME=\"Arch Linux\"\nID=arch\n", LinuxArch},
+	}
+
+	for _, tt := range tests {
+		h := &Host{CommandManager: &MockCommandManager{Output: tt.osRelease}}
+		osType, err := h.detectLinuxType(context.TODO())
+		if err != nil {
+			t.Errorf("Error detecting %q: %v", tt.osRelease, err)
+			continue
+		}
+		if osType != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, osType)
+		}
+	}
+
+	if LinuxAlpine.String() != "Linux_Alpine" {
+		t.Errorf("Expected Linux_Alpine, got %s", LinuxAlpine)
+	}
+}
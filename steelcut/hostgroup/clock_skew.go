@@ -0,0 +1,53 @@
+package hostgroup
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+)
+
+// HostClockSkew is one host's clock compared with the controller's.
+type HostClockSkew struct {
+	Hostname string
+	hostmanager.ClockSkew
+	Err error
+}
+
+// ClockSkew reads the clock of every host in the group. The result is ordered
+// by the size of the offset, largest first, with unreachable hosts last.
+func (hg *HostGroup) ClockSkew() []HostClockSkew {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var skews []HostClockSkew
+
+	hg.RLock()
+	for _, h := range hg.Hosts {
+		wg.Add(1)
+		go func(hostInstance *host.Host) {
+			defer wg.Done()
+			skew, err := hostInstance.HostManager.ClockSkew()
+
+			mu.Lock()
+			defer mu.Unlock()
+			skews = append(skews, HostClockSkew{Hostname: hostInstance.Hostname, ClockSkew: skew, Err: err})
+		}(h)
+	}
+	hg.RUnlock()
+
+	wg.Wait()
+
+	sort.Slice(skews, func(i, j int) bool {
+		if (skews[i].Err == nil) != (skews[j].Err == nil) {
+			return skews[i].Err == nil
+		}
+		oi, oj := skews[i].Offset.Abs(), skews[j].Offset.Abs()
+		if oi != oj {
+			return oi > oj
+		}
+		return skews[i].Hostname < skews[j].Hostname
+	})
+
+	return skews
+}
@@ -0,0 +1,132 @@
+package hostgroup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// AllHosts as the source of a Dependency stands for every host in the group.
+const AllHosts = "*"
+
+// Dependency is a connection a host must be able to make, such as an app
+// server reaching its database.
+type Dependency struct {
+	From     string // hostname in the group, or AllHosts
+	Host     string
+	Port     int
+	Protocol string // tcp or udp
+}
+
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s -> %s/%s", d.From, net.JoinHostPort(d.Host, strconv.Itoa(d.Port)), d.Protocol)
+}
+
+// DependencyResult is the outcome of checking a dependency from one host.
+type DependencyResult struct {
+	Dependency // From is always a single host here
+	networkmanager.PortCheckResult
+	Err error // set when the check could not be run at all
+}
+
+// ParseDependencies reads one dependency per line in the form
+// "source target:port[/proto]", where source is a hostname or * for every
+// host and proto defaults to tcp. Blank lines and # comments are skipped.
+func ParseDependencies(r io.Reader) ([]Dependency, error) {
+	var deps []Dependency
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"source target:port[/proto]\", got %q", lineNumber, strings.TrimSpace(line))
+		}
+
+		target, proto, found := strings.Cut(fields[1], "/")
+		if !found {
+			proto = "tcp"
+		}
+		if proto != "tcp" && proto != "udp" {
+			return nil, fmt.Errorf("line %d: unsupported protocol %q", lineNumber, proto)
+		}
+		hostname, portText, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		port, err := strconv.Atoi(portText)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("line %d: invalid port %q", lineNumber, portText)
+		}
+
+		deps = append(deps, Dependency{From: fields[0], Host: hostname, Port: port, Protocol: proto})
+	}
+	return deps, scanner.Err()
+}
+
+// CheckDependencies checks every dependency from its source hosts. Sources
+// run concurrently, each checking its own dependencies one at a time. Results
+// follow the order of deps, with AllHosts expanded in hostname order.
+func (hg *HostGroup) CheckDependencies(deps []Dependency, timeout time.Duration) []DependencyResult {
+	hg.RLock()
+	hosts := make(map[string]*host.Host, len(hg.Hosts))
+	var hostnames []string
+	for name, h := range hg.Hosts {
+		hosts[name] = h
+		hostnames = append(hostnames, name)
+	}
+	hg.RUnlock()
+	sort.Strings(hostnames)
+
+	var results []DependencyResult
+	bySource := make(map[string][]int)
+	for _, dep := range deps {
+		sources := []string{dep.From}
+		if dep.From == AllHosts {
+			sources = hostnames
+		}
+		for _, source := range sources {
+			expanded := dep
+			expanded.From = source
+			bySource[source] = append(bySource[source], len(results))
+			results = append(results, DependencyResult{Dependency: expanded})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for source, indexes := range bySource {
+		h, ok := hosts[source]
+		if !ok {
+			for _, i := range indexes {
+				results[i].Err = fmt.Errorf("host %s is not in the group", source)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(h *host.Host, indexes []int) {
+			defer wg.Done()
+			// Each goroutine only writes its own indexes, so no lock is needed.
+			for _, i := range indexes {
+				dep := results[i].Dependency
+				results[i].PortCheckResult, results[i].Err = h.NetworkManager.CheckPort(dep.Host, dep.Port, dep.Protocol, timeout)
+			}
+		}(h, indexes)
+	}
+	wg.Wait()
+
+	return results
+}
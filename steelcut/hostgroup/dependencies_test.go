@@ -0,0 +1,71 @@
+package hostgroup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+)
+
+func TestParseDependencies(t *testing.T) {
+	input := `# app servers need the database
+app1 db1:5432
+
+*    [2001:db8::53]:53/udp  # resolvers
+`
+	deps, err := ParseDependencies(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Dependency{
+		{From: "app1", Host: "db1", Port: 5432, Protocol: "tcp"},
+		{From: "*", Host: "2001:db8::53", Port: 53, Protocol: "udp"},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, deps)
+	}
+
+	for _, bad := range []string{"app1", "app1 db1", "app1 db1:http", "app1 db1:70000", "app1 db1:80/sctp"} {
+		if _, err := ParseDependencies(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	hg := NewHostGroup(
+		&host.Host{Hostname: "app1", NetworkManager: &MockNetworkManager{Open: map[string]bool{"db1:5432": true, "cache:6379": true}}},
+		&host.Host{Hostname: "app2", NetworkManager: &MockNetworkManager{Open: map[string]bool{"cache:6379": true}}},
+	)
+	deps := []Dependency{
+		{From: "*", Host: "db1", Port: 5432, Protocol: "tcp"},
+		{From: "app2", Host: "cache", Port: 6379, Protocol: "tcp"},
+		{From: "batch1", Host: "db1", Port: 5432, Protocol: "tcp"},
+	}
+
+	results := hg.CheckDependencies(deps, time.Second)
+
+	var got []string
+	for _, result := range results {
+		status := "fail"
+		if result.Err == nil && result.Open {
+			status = "pass"
+		}
+		got = append(got, result.Dependency.String()+" "+status)
+	}
+	expected := []string{
+		"app1 -> db1:5432/tcp pass",
+		"app2 -> db1:5432/tcp fail",
+		"app2 -> cache:6379/tcp pass",
+		"batch1 -> db1:5432/tcp fail",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if results[3].Err == nil {
+		t.Error("Expected an error for a source outside the group")
+	}
+}
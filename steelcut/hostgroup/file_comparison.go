@@ -0,0 +1,285 @@
+package hostgroup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+
+	// maxDiffEdits caps the number of changed lines a diff is computed for,
+	// which bounds the memory the diff takes.
+	maxDiffEdits = 1000
+)
+
+// DigestGroup is a set of hosts whose copy of a file has the same digest.
+type DigestGroup struct {
+	Digest    string
+	Hostnames []string
+}
+
+// FileComparison is the result of comparing one file across a host group.
+type FileComparison struct {
+	Path      string
+	Algorithm filemanager.ChecksumAlgorithm
+
+	// Groups is ordered by size, so the first group holds the majority version.
+	Groups []DigestGroup
+
+	// Diffs maps each outlier host to a unified diff of its copy against the majority version.
+	Diffs map[string]string
+
+	// Errors holds the hosts the file could not be checksummed or read on.
+	Errors map[string]error
+}
+
+// Consistent reports whether every host that was checked has the same version of the file.
+func (fc FileComparison) Consistent() bool {
+	return len(fc.Groups) <= 1 && len(fc.Errors) == 0
+}
+
+// CompareFile checksums path on every host and groups the hosts by digest.
+// When diff is set the outlier hosts' content is diffed against the majority version.
+func (hg *HostGroup) CompareFile(path string, algo filemanager.ChecksumAlgorithm, diff bool) FileComparison {
+	comparison := FileComparison{
+		Path:      path,
+		Algorithm: algo,
+		Diffs:     make(map[string]string),
+		Errors:    make(map[string]error),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	digests := make(map[string][]string)
+	hosts := make(map[string]*host.Host)
+
+	hg.RLock()
+	for _, h := range hg.Hosts {
+		hosts[h.Hostname] = h
+		wg.Add(1)
+		go func(hostInstance *host.Host) {
+			defer wg.Done()
+			digest, err := hostInstance.FileManager.Checksum(path, algo)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				comparison.Errors[hostInstance.Hostname] = err
+				return
+			}
+			digests[digest] = append(digests[digest], hostInstance.Hostname)
+		}(h)
+	}
+	hg.RUnlock()
+
+	wg.Wait()
+
+	for digest, hostnames := range digests {
+		sort.Strings(hostnames)
+		comparison.Groups = append(comparison.Groups, DigestGroup{Digest: digest, Hostnames: hostnames})
+	}
+	sort.Slice(comparison.Groups, func(i, j int) bool {
+		if len(comparison.Groups[i].Hostnames) != len(comparison.Groups[j].Hostnames) {
+			return len(comparison.Groups[i].Hostnames) > len(comparison.Groups[j].Hostnames)
+		}
+		return comparison.Groups[i].Hostnames[0] < comparison.Groups[j].Hostnames[0]
+	})
+
+	if !diff || len(comparison.Groups) < 2 {
+		return comparison
+	}
+
+	majorityHost := comparison.Groups[0].Hostnames[0]
+	majority, err := hosts[majorityHost].FileManager.ReadFile(path)
+	if err != nil {
+		comparison.Errors[majorityHost] = fmt.Errorf("failed to read majority version: %w", err)
+		return comparison
+	}
+
+	// Every host in a group has identical content, so one read per group is enough.
+	for _, group := range comparison.Groups[1:] {
+		outlierHost := group.Hostnames[0]
+		content, err := hosts[outlierHost].FileManager.ReadFile(path)
+		if err != nil {
+			for _, hostname := range group.Hostnames {
+				comparison.Errors[hostname] = fmt.Errorf("failed to read file: %w", err)
+			}
+			continue
+		}
+
+		d := unifiedDiff(majorityHost+":"+path, outlierHost+":"+path, string(majority), string(content))
+		for _, hostname := range group.Hostnames {
+			comparison.Diffs[hostname] = d
+		}
+	}
+
+	return comparison
+}
+
+type diffOp struct {
+	kind byte // ' ' for unchanged, '-' for removed, '+' for added
+	aPos int  // index into a before this op
+	bPos int  // index into b before this op
+	text string
+}
+
+// unifiedDiff returns a unified diff turning a into b.
+func unifiedDiff(aName, bName, a, b string) string {
+	ops, ok := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	if !ok {
+		fmt.Fprintf(&out, "# more than %d lines differ, diff omitted\n", maxDiffEdits)
+		return out.String()
+	}
+
+	i := 0
+	for i < len(ops) {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk until the changes are separated by more unchanged
+		// lines than two contexts' worth.
+		lastChange := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				lastChange = j
+			} else if j-lastChange > 2*diffContext {
+				break
+			}
+		}
+		end := lastChange + diffContext + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		hunk := ops[start:end]
+		var aCount, bCount int
+		for _, op := range hunk {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		aStart, bStart := hunk[0].aPos+1, hunk[0].bPos+1
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, op := range hunk {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.text)
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+// diffLines computes a line diff between a and b with Myers' algorithm, which
+// takes time and memory in proportion to the number of changed lines rather
+// than the product of the file lengths. It gives up and returns false once
+// more than maxDiffEdits lines would have to change.
+func diffLines(a, b []string) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace[d] holds the furthest x reached on diagonals -d..d before step d.
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return nil, false
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace), true
+			}
+		}
+	}
+	return backtrack(a, b, trace), true
+}
+
+// backtrack walks the Myers trace back from the end of both files and
+// returns the edit script in order.
+func backtrack(a, b []string, trace [][]int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		prevX, prevY := 0, 0
+		if d > 0 {
+			vd := trace[d]
+			k := x - y
+			prevK := k - 1
+			if k == -d || (k != d && vd[d+k-1] < vd[d+k+1]) {
+				prevK = k + 1
+			}
+			prevX = vd[d+prevK]
+			prevY = prevX - prevK
+		}
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', aPos: x, bPos: y, text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{kind: '+', aPos: x, bPos: prevY, text: b[prevY]})
+			} else {
+				ops = append(ops, diffOp{kind: '-', aPos: prevX, bPos: y, text: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
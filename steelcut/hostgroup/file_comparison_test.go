@@ -0,0 +1,148 @@
+package hostgroup
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+)
+
+// MockFileManager serves a fixed file content, embedding the interface so only
+// the methods used by CompareFile need implementing.
+type MockFileManager struct {
+	filemanager.FileManager
+	Content string
+	Digest  string
+	Err     error
+}
+
+func (m *MockFileManager) Checksum(path string, algo filemanager.ChecksumAlgorithm) (string, error) {
+	return m.Digest, m.Err
+}
+
+func (m *MockFileManager) ReadFile(path string) ([]byte, error) {
+	return []byte(m.Content), m.Err
+}
+
+func newMockHost(hostname string, fm *MockFileManager) *host.Host {
+	return &host.Host{Hostname: hostname, FileManager: fm}
+}
+
+func TestCompareFile(t *testing.T) {
+	good := "worker_processes 4;\nevents {}\n"
+	bad := "worker_processes 8;\nevents {}\n"
+
+	hg := NewHostGroup(
+		newMockHost("web1", &MockFileManager{Content: good, Digest: "aaa"}),
+		newMockHost("web2", &MockFileManager{Content: good, Digest: "aaa"}),
+		newMockHost("web3", &MockFileManager{Content: bad, Digest: "bbb"}),
+		newMockHost("web4", &MockFileManager{Err: errors.New("no such file")}),
+	)
+
+	comparison := hg.CompareFile("/etc/nginx/nginx.conf", filemanager.SHA256, true)
+
+	if len(comparison.Groups) != 2 {
+		t.Fatalf("Expected 2 digest groups, got: %v", comparison.Groups)
+	}
+	if comparison.Groups[0].Digest != "aaa" || len(comparison.Groups[0].Hostnames) != 2 {
+		t.Errorf("Expected the majority group first, got: %v", comparison.Groups[0])
+	}
+	if _, ok := comparison.Errors["web4"]; !ok {
+		t.Errorf("Expected an error for web4")
+	}
+	if comparison.Consistent() {
+		t.Errorf("Expected the comparison to be inconsistent")
+	}
+
+	expected := `--- web1:/etc/nginx/nginx.conf
++++ web3:/etc/nginx/nginx.conf
+@@ -1,2 +1,2 @@
+-worker_processes 4;
++worker_processes 8;
+ events {}
+`
+	if comparison.Diffs["web3"] != expected {
+		t.Errorf("Unexpected diff:\n%s", comparison.Diffs["web3"])
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+	expected := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	if got := unifiedDiff("a", "b", a, b); got != expected {
+		t.Errorf("Unexpected diff:\n%s", got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		edits int
+	}{
+		{"a b c a b b a", "c b a b a c", 5},
+		{"", "x y", 2},
+		{"x y", "", 2},
+		{"same", "same", 0},
+	}
+
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		ops, ok := diffLines(a, b)
+		if !ok {
+			t.Fatalf("Expected a diff of %q and %q", tt.a, tt.b)
+		}
+
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				gotA = append(gotA, op.text)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.text)
+			}
+			if op.kind != ' ' {
+				edits++
+			}
+		}
+		if strings.Join(gotA, " ") != tt.a || strings.Join(gotB, " ") != tt.b {
+			t.Errorf("Diff of %q and %q does not reproduce them: %q, %q", tt.a, tt.b, gotA, gotB)
+		}
+		if edits != tt.edits {
+			t.Errorf("Expected %d edits between %q and %q, got %d", tt.edits, tt.a, tt.b, edits)
+		}
+	}
+}
+
+func TestUnifiedDiffTooLarge(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i <= maxDiffEdits; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+
+	got := unifiedDiff("a", "b", a.String(), b.String())
+	if !strings.HasSuffix(got, "diff omitted\n") {
+		t.Errorf("Expected the diff to be omitted, got %d bytes", len(got))
+	}
+}
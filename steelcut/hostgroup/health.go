@@ -0,0 +1,140 @@
+package hostgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// EndpointCheck is an HTTP endpoint a host has to serve to count as healthy.
+type EndpointCheck struct {
+	Name           string
+	Probe          networkmanager.HTTPProbe
+	ExpectStatus   []int         // any 2xx or 3xx status when empty
+	MinTLSValidity time.Duration // how long the certificate has to remain valid
+}
+
+// Verify returns why result does not pass the check, or nil if it does.
+func (c EndpointCheck) Verify(result networkmanager.HTTPProbeResult, now time.Time) error {
+	if result.StatusCode == 0 {
+		return fmt.Errorf("no response: %s", result.Error)
+	}
+
+	expected := result.StatusCode >= 200 && result.StatusCode < 400
+	if len(c.ExpectStatus) > 0 {
+		expected = false
+		for _, status := range c.ExpectStatus {
+			expected = expected || status == result.StatusCode
+		}
+	}
+	if !expected {
+		return fmt.Errorf("unexpected status %d", result.StatusCode)
+	}
+
+	if !result.BodyMatched {
+		return fmt.Errorf("body does not match %q", c.Probe.BodyMatch)
+	}
+	if c.MinTLSValidity > 0 {
+		if result.TLSExpiry.IsZero() {
+			// openssl is missing on the host or the URL is not https.
+			return errors.New("certificate expiry unknown")
+		}
+		if result.TLSExpiry.Sub(now) < c.MinTLSValidity {
+			return fmt.Errorf("certificate expires %s", result.TLSExpiry.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// EndpointHealth is the outcome of one endpoint check.
+type EndpointHealth struct {
+	Check  EndpointCheck
+	Result networkmanager.HTTPProbeResult
+	Err    error // set when the probe could not be run or the check failed
+}
+
+// HostHealth is the outcome of checking one host. When SSH fails, nothing
+// else is checked.
+type HostHealth struct {
+	Hostname  string
+	SSHErr    error
+	Ping      networkmanager.PingResult
+	PingErr   error
+	Endpoints []EndpointHealth
+}
+
+// Healthy reports whether SSH works, the host answers ping and every
+// endpoint passed its check.
+func (h HostHealth) Healthy() bool {
+	if h.SSHErr != nil || h.PingErr != nil || !h.Ping.Success {
+		return false
+	}
+	for _, endpoint := range h.Endpoints {
+		if endpoint.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHealth checks every host in the group: that a command can be run on
+// it over SSH, that it answers ping from the controller, through pinger, and
+// that the endpoints checks lists for its hostname respond as expected. At
+// most concurrency hosts are checked at a time. Results are sorted by
+// hostname.
+func (hg *HostGroup) CheckHealth(pinger networkmanager.NetworkManager, checks map[string][]EndpointCheck, pingTimeout time.Duration, concurrency int) []HostHealth {
+	hg.RLock()
+	hosts := make([]*host.Host, 0, len(hg.Hosts))
+	for _, h := range hg.Hosts {
+		hosts = append(hosts, h)
+	}
+	hg.RUnlock()
+
+	if concurrency < 1 {
+		concurrency = len(hosts)
+	}
+	sem := make(chan struct{}, concurrency)
+	results := make([]HostHealth, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h *host.Host) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = checkHostHealth(h, pinger, checks[h.Hostname], pingTimeout)
+		}(i, h)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Hostname < results[j].Hostname })
+	return results
+}
+
+func checkHostHealth(h *host.Host, pinger networkmanager.NetworkManager, checks []EndpointCheck, pingTimeout time.Duration) HostHealth {
+	health := HostHealth{Hostname: h.Hostname}
+
+	if _, err := h.CommandManager.Run(context.TODO(), commandmanager.CommandConfig{Command: "true"}); err != nil {
+		health.SSHErr = err
+		return health
+	}
+
+	health.Ping, health.PingErr = pinger.Ping(h.Hostname, 1, pingTimeout)
+
+	for _, check := range checks {
+		endpoint := EndpointHealth{Check: check}
+		endpoint.Result, endpoint.Err = h.NetworkManager.ProbeHTTP(check.Probe)
+		if endpoint.Err == nil {
+			endpoint.Err = check.Verify(endpoint.Result, time.Now())
+		}
+		health.Endpoints = append(health.Endpoints, endpoint)
+	}
+	return health
+}
@@ -0,0 +1,117 @@
+package hostgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// MockCommandManager fails every command with Err, as when SSH is broken.
+type MockCommandManager struct {
+	commandmanager.CommandManager
+	Err error
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config commandmanager.CommandConfig) (commandmanager.CommandResult, error) {
+	return commandmanager.CommandResult{}, m.Err
+}
+
+func TestCheckHealth(t *testing.T) {
+	now := time.Now()
+	checks := []EndpointCheck{
+		{Name: "api", Probe: networkmanager.HTTPProbe{URL: "https://localhost/healthz"}, MinTLSValidity: 14 * 24 * time.Hour},
+		{Name: "metrics", Probe: networkmanager.HTTPProbe{URL: "http://localhost:9100/metrics"}, ExpectStatus: []int{200}},
+	}
+	healthy := map[string]networkmanager.HTTPProbeResult{
+		"https://localhost/healthz":     {StatusCode: 200, BodyMatched: true, TLSExpiry: now.Add(90 * 24 * time.Hour)},
+		"http://localhost:9100/metrics": {StatusCode: 200, BodyMatched: true},
+	}
+	// web2's certificate is about to expire and its exporter redirects.
+	failing := map[string]networkmanager.HTTPProbeResult{
+		"https://localhost/healthz":     {StatusCode: 200, BodyMatched: true, TLSExpiry: now.Add(3 * 24 * time.Hour)},
+		"http://localhost:9100/metrics": {StatusCode: 302, BodyMatched: true},
+	}
+
+	hg := NewHostGroup(
+		&host.Host{Hostname: "web1", CommandManager: &MockCommandManager{}, NetworkManager: &MockNetworkManager{Responses: healthy}},
+		&host.Host{Hostname: "web2", CommandManager: &MockCommandManager{}, NetworkManager: &MockNetworkManager{Responses: failing}},
+		&host.Host{Hostname: "web3", CommandManager: &MockCommandManager{Err: errors.New("handshake failed")}, NetworkManager: &MockNetworkManager{}},
+	)
+	// The hosts are pinged from the controller, not from themselves.
+	controller := &MockNetworkManager{Reachable: map[string]bool{"web1": true, "web2": true}}
+
+	results := hg.CheckHealth(controller, map[string][]EndpointCheck{"web1": checks, "web2": checks, "web3": checks}, time.Second, 2)
+
+	if len(results) != 3 || !results[0].Healthy() || results[1].Healthy() || results[2].Healthy() {
+		t.Fatalf("Expected only web1 to be healthy, got %+v", results)
+	}
+	for i, endpoint := range results[1].Endpoints {
+		if endpoint.Err == nil {
+			t.Errorf("Expected web2 to fail the %s check", checks[i].Name)
+		}
+	}
+	if results[2].SSHErr == nil || len(results[2].Endpoints) != 0 {
+		t.Errorf("Expected web3 to stop at the SSH check, got %+v", results[2])
+	}
+}
+
+func TestEndpointCheckUnknownTLSExpiry(t *testing.T) {
+	check := EndpointCheck{Probe: networkmanager.HTTPProbe{URL: "https://localhost/healthz"}, MinTLSValidity: 24 * time.Hour}
+
+	// Without openssl on the host the expiry cannot be read.
+	if err := check.Verify(networkmanager.HTTPProbeResult{StatusCode: 200, BodyMatched: true}, time.Now()); err == nil {
+		t.Error("Expected an error when the certificate expiry is unknown")
+	}
+
+	check.MinTLSValidity = 0
+	if err := check.Verify(networkmanager.HTTPProbeResult{StatusCode: 200, BodyMatched: true}, time.Now()); err != nil {
+		t.Errorf("Expected no error without a minimum validity, got: %v", err)
+	}
+}
+
+// concurrencyCommandManager records the most commands it ran at once.
+type concurrencyCommandManager struct {
+	commandmanager.CommandManager
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (m *concurrencyCommandManager) Run(ctx context.Context, config commandmanager.CommandConfig) (commandmanager.CommandResult, error) {
+	m.mu.Lock()
+	m.running++
+	if m.running > m.max {
+		m.max = m.running
+	}
+	m.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	m.mu.Lock()
+	m.running--
+	m.mu.Unlock()
+	return commandmanager.CommandResult{}, nil
+}
+
+func TestCheckHealthConcurrency(t *testing.T) {
+	cmdManager := &concurrencyCommandManager{}
+	hg := NewHostGroup()
+	for i := 0; i < 6; i++ {
+		hg.AddHost(&host.Host{Hostname: fmt.Sprintf("web%d", i), CommandManager: cmdManager, NetworkManager: &MockNetworkManager{}})
+	}
+
+	results := hg.CheckHealth(&MockNetworkManager{}, nil, time.Second, 2)
+	if len(results) != 6 {
+		t.Fatalf("Expected every host to be checked, got %d", len(results))
+	}
+	if cmdManager.max > 2 {
+		t.Errorf("Expected at most 2 hosts to be checked at once, got %d", cmdManager.max)
+	}
+}
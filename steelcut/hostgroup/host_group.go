@@ -2,6 +2,8 @@ package hostgroup
 
 import (
 	"context"
+	"log/slog"
+	"sort"
 	"sync"
 
 	"github.com/steelcutops/steelcut/steelcut/commandmanager"
@@ -44,6 +46,18 @@ func (hg *HostGroup) HasHost(hostname string) bool {
 	return exists
 }
 
+// Hostnames returns the hostnames in the HostGroup, sorted.
+func (hg *HostGroup) Hostnames() []string {
+	hg.RLock()
+	defer hg.RUnlock()
+	hostnames := make([]string, 0, len(hg.Hosts))
+	for hostname := range hg.Hosts {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
 func (hg *HostGroup) Run(ctx context.Context, cmd string, args ...string) []commandmanager.CommandResult {
 	var wg sync.WaitGroup
 	results := make([]commandmanager.CommandResult, len(hg.Hosts))
@@ -61,7 +75,9 @@ func (hg *HostGroup) Run(ctx context.Context, cmd string, args ...string) []comm
 		go func(hostInstance *host.Host, index int) {
 			defer wg.Done()
 			result, err := hostInstance.CommandManager.Run(ctx, config)
-			if err != nil {
+			// A non-zero exit is carried by ExitCode and keeps the command's
+			// own stderr; only a command that did not run gets the error.
+			if err != nil && result.ExitCode == 0 {
 				result.STDERR = err.Error()
 			}
 			result.Command = cmd // Store the command in the result
@@ -75,3 +91,45 @@ func (hg *HostGroup) Run(ctx context.Context, cmd string, args ...string) []comm
 
 	return results
 }
+
+// HostLine is a line of output tagged with the host that produced it.
+type HostLine struct {
+	Hostname string
+	Line     string
+}
+
+// Tail tails path on every host in the group and merges the lines into a single
+// channel. Hosts where tail cannot be started are logged and skipped. The
+// channel is closed once every host has finished or ctx is cancelled.
+func (hg *HostGroup) Tail(ctx context.Context, path string, lines int, follow bool) <-chan HostLine {
+	var wg sync.WaitGroup
+	out := make(chan HostLine)
+
+	hg.RLock()
+	for _, h := range hg.Hosts {
+		wg.Add(1)
+		go func(hostInstance *host.Host) {
+			defer wg.Done()
+			hostLines, err := hostInstance.FileManager.Tail(ctx, path, lines, follow)
+			if err != nil {
+				slog.Error("Failed to tail file", "host", hostInstance.Hostname, "path", path, "error", err)
+				return
+			}
+			for line := range hostLines {
+				select {
+				case out <- HostLine{Hostname: hostInstance.Hostname, Line: line}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(h)
+	}
+	hg.RUnlock()
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
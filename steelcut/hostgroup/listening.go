@@ -0,0 +1,102 @@
+package hostgroup
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// PortAllowlist is a set of ports that may be listened on, keyed as
+// "port/protocol", e.g. "22/tcp".
+type PortAllowlist map[string]bool
+
+// ParsePortAllowlist parses a comma or space separated list of ports such as
+// "22/tcp, 53/udp, 443". A port without a protocol allows both.
+func ParsePortAllowlist(value string) (PortAllowlist, error) {
+	allowlist := make(PortAllowlist)
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		number, protocol, found := strings.Cut(strings.ToLower(entry), "/")
+		port, err := strconv.Atoi(number)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", entry)
+		}
+		switch {
+		case !found:
+			allowlist[number+"/tcp"] = true
+			allowlist[number+"/udp"] = true
+		case protocol == "tcp" || protocol == "udp":
+			allowlist[number+"/"+protocol] = true
+		default:
+			return nil, fmt.Errorf("invalid protocol in %q", entry)
+		}
+	}
+	return allowlist, nil
+}
+
+// Allows reports whether socket's port is on the allowlist.
+func (a PortAllowlist) Allows(socket networkmanager.Socket) bool {
+	return a[strconv.Itoa(socket.LocalPort)+"/"+socket.Protocol]
+}
+
+// HostSockets is the listening sockets of one host.
+type HostSockets struct {
+	Hostname string
+	Sockets  []networkmanager.Socket
+	Err      error // set when the sockets could not be listed
+}
+
+// UnexpectedSocket is a listening socket whose port is not allowed on its host.
+type UnexpectedSocket struct {
+	Hostname string
+	networkmanager.Socket
+}
+
+// ListeningSockets lists the listening sockets of every host in the group,
+// sorted by hostname.
+func (hg *HostGroup) ListeningSockets() []HostSockets {
+	hg.RLock()
+	hosts := make([]*host.Host, 0, len(hg.Hosts))
+	for _, h := range hg.Hosts {
+		hosts = append(hosts, h)
+	}
+	hg.RUnlock()
+
+	results := make([]HostSockets, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h *host.Host) {
+			defer wg.Done()
+			sockets, err := h.NetworkManager.ListeningSockets()
+			results[i] = HostSockets{Hostname: h.Hostname, Sockets: sockets, Err: err}
+		}(i, h)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Hostname < results[j].Hostname })
+	return results
+}
+
+// UnexpectedSockets returns the sockets whose port is not on their host's
+// allowlist. Hosts without an allowlist allow nothing. Sockets bound to a
+// loopback address cannot be reached from other hosts and are not reported.
+func UnexpectedSockets(hosts []HostSockets, allowlists map[string]PortAllowlist) []UnexpectedSocket {
+	var unexpected []UnexpectedSocket
+	for _, h := range hosts {
+		for _, socket := range h.Sockets {
+			if ip := net.ParseIP(socket.LocalAddress); ip != nil && ip.IsLoopback() {
+				continue
+			}
+			if !allowlists[h.Hostname].Allows(socket) {
+				unexpected = append(unexpected, UnexpectedSocket{Hostname: h.Hostname, Socket: socket})
+			}
+		}
+	}
+	return unexpected
+}
@@ -0,0 +1,49 @@
+package hostgroup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+func TestParsePortAllowlist(t *testing.T) {
+	allowlist, err := ParsePortAllowlist("22/tcp, 53 443/TCP")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := PortAllowlist{"22/tcp": true, "53/tcp": true, "53/udp": true, "443/tcp": true}
+	if !reflect.DeepEqual(allowlist, expected) {
+		t.Errorf("Expected %v, got %v", expected, allowlist)
+	}
+
+	for _, invalid := range []string{"ssh", "70000/tcp", "22/sctp"} {
+		if _, err := ParsePortAllowlist(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestUnexpectedSockets(t *testing.T) {
+	hosts := []HostSockets{
+		{Hostname: "web1", Sockets: []networkmanager.Socket{
+			{Protocol: "tcp", LocalAddress: "0.0.0.0", LocalPort: 22},
+			{Protocol: "tcp", LocalAddress: "*", LocalPort: 8080, PID: 4242, Process: "java"},
+			{Protocol: "tcp", LocalAddress: "127.0.0.1", LocalPort: 6379},
+			{Protocol: "udp", LocalAddress: "::", LocalPort: 22},
+		}},
+		{Hostname: "unlisted", Sockets: []networkmanager.Socket{
+			{Protocol: "tcp", LocalAddress: "::", LocalPort: 22},
+		}},
+	}
+	allowlists := map[string]PortAllowlist{"web1": {"22/tcp": true}}
+
+	expected := []UnexpectedSocket{
+		{Hostname: "web1", Socket: networkmanager.Socket{Protocol: "tcp", LocalAddress: "*", LocalPort: 8080, PID: 4242, Process: "java"}},
+		{Hostname: "web1", Socket: networkmanager.Socket{Protocol: "udp", LocalAddress: "::", LocalPort: 22}},
+		{Hostname: "unlisted", Socket: networkmanager.Socket{Protocol: "tcp", LocalAddress: "::", LocalPort: 22}},
+	}
+	if unexpected := UnexpectedSockets(hosts, allowlists); !reflect.DeepEqual(unexpected, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, unexpected)
+	}
+}
@@ -0,0 +1,58 @@
+package hostgroup
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/logmanager"
+)
+
+// HostLogEntry is a log entry tagged with the host it was read from.
+type HostLogEntry struct {
+	Hostname string `json:"host"`
+	logmanager.Entry
+}
+
+// QueryLogs runs query on every host in the group and merges the results into
+// one stream ordered by time. Hosts that fail are left out and reported in
+// the returned error, alongside the entries from the others.
+func (hg *HostGroup) QueryLogs(query logmanager.Query) ([]HostLogEntry, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var entries []HostLogEntry
+	var errs *multierror.Error
+
+	hg.RLock()
+	for _, h := range hg.Hosts {
+		wg.Add(1)
+		go func(hostInstance *host.Host) {
+			defer wg.Done()
+			hostEntries, err := hostInstance.LogManager.Query(query)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%s: %w", hostInstance.Hostname, err))
+				return
+			}
+			for _, entry := range hostEntries {
+				entries = append(entries, HostLogEntry{Hostname: hostInstance.Hostname, Entry: entry})
+			}
+		}(h)
+	}
+	hg.RUnlock()
+
+	wg.Wait()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.Before(entries[j].Time)
+		}
+		return entries[i].Hostname < entries[j].Hostname
+	})
+
+	return entries, errs.ErrorOrNil()
+}
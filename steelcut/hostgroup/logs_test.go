@@ -0,0 +1,46 @@
+package hostgroup
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/logmanager"
+)
+
+type MockLogManager struct {
+	Entries []logmanager.Entry
+	Err     error
+}
+
+func (m *MockLogManager) Query(query logmanager.Query) ([]logmanager.Entry, error) {
+	return m.Entries, m.Err
+}
+
+func TestQueryLogs(t *testing.T) {
+	base := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	hg := NewHostGroup(
+		&host.Host{Hostname: "web1", LogManager: &MockLogManager{Entries: []logmanager.Entry{
+			{Time: base, Message: "a"},
+			{Time: base.Add(2 * time.Second), Message: "c"},
+		}}},
+		&host.Host{Hostname: "web2", LogManager: &MockLogManager{Entries: []logmanager.Entry{
+			{Time: base.Add(time.Second), Message: "b"},
+		}}},
+		&host.Host{Hostname: "web3", LogManager: &MockLogManager{Err: errors.New("connection refused")}},
+	)
+
+	entries, err := hg.QueryLogs(logmanager.Query{})
+	if err == nil {
+		t.Error("Expected an error for web3")
+	}
+
+	var got string
+	for _, entry := range entries {
+		got += entry.Hostname + ":" + entry.Message + " "
+	}
+	if got != "web1:a web2:b web1:c " {
+		t.Errorf("Expected a time-ordered stream, got %q", got)
+	}
+}
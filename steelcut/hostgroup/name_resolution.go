@@ -0,0 +1,76 @@
+package hostgroup
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AnswerGroup is a set of hosts that resolve a name to the same addresses.
+type AnswerGroup struct {
+	Addresses []string // sorted; empty when the name does not resolve
+	Hostnames []string
+}
+
+// NameResolution is the result of resolving one name on every host.
+type NameResolution struct {
+	Name string
+
+	// Groups is ordered by size, so the first group holds the majority answer.
+	Groups []AnswerGroup
+
+	// Errors holds the hosts the name could not be looked up on.
+	Errors map[string]error
+}
+
+// Consistent reports whether every host that was checked got the same answer.
+func (nr NameResolution) Consistent() bool {
+	return len(nr.Groups) <= 1 && len(nr.Errors) == 0
+}
+
+// ResolveName resolves name on every host and groups the hosts by the
+// addresses they got, regardless of order.
+func (hg *HostGroup) ResolveName(name string) NameResolution {
+	resolution := NameResolution{Name: name, Errors: make(map[string]error)}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	answers := make(map[string]*AnswerGroup)
+
+	hg.RLock()
+	for _, h := range hg.Hosts {
+		wg.Add(1)
+		go func(hostname string, resolve func(string) ([]string, error)) {
+			defer wg.Done()
+			addresses, err := resolve(name)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				resolution.Errors[hostname] = err
+				return
+			}
+			sort.Strings(addresses)
+			key := strings.Join(addresses, " ")
+			if answers[key] == nil {
+				answers[key] = &AnswerGroup{Addresses: addresses}
+			}
+			answers[key].Hostnames = append(answers[key].Hostnames, hostname)
+		}(h.Hostname, h.NetworkManager.ResolveName)
+	}
+	hg.RUnlock()
+	wg.Wait()
+
+	for _, group := range answers {
+		sort.Strings(group.Hostnames)
+		resolution.Groups = append(resolution.Groups, *group)
+	}
+	sort.Slice(resolution.Groups, func(i, j int) bool {
+		a, b := resolution.Groups[i], resolution.Groups[j]
+		if len(a.Hostnames) != len(b.Hostnames) {
+			return len(a.Hostnames) > len(b.Hostnames)
+		}
+		return a.Hostnames[0] < b.Hostnames[0]
+	})
+	return resolution
+}
@@ -0,0 +1,32 @@
+package hostgroup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+)
+
+func TestResolveName(t *testing.T) {
+	// web2 still has a stale /etc/hosts entry and web3 cannot resolve the name.
+	hg := NewHostGroup(
+		&host.Host{Hostname: "web1", NetworkManager: &MockNetworkManager{Names: map[string][]string{"db": {"2001:db8::6", "10.0.0.6"}}}},
+		&host.Host{Hostname: "web2", NetworkManager: &MockNetworkManager{Names: map[string][]string{"db": {"10.0.0.5"}}}},
+		&host.Host{Hostname: "web3", NetworkManager: &MockNetworkManager{}},
+		&host.Host{Hostname: "web4", NetworkManager: &MockNetworkManager{Names: map[string][]string{"db": {"10.0.0.6", "2001:db8::6"}}}},
+	)
+
+	resolution := hg.ResolveName("db")
+
+	expected := []AnswerGroup{
+		{Addresses: []string{"10.0.0.6", "2001:db8::6"}, Hostnames: []string{"web1", "web4"}},
+		{Addresses: []string{"10.0.0.5"}, Hostnames: []string{"web2"}},
+		{Addresses: []string{}, Hostnames: []string{"web3"}},
+	}
+	if !reflect.DeepEqual(resolution.Groups, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, resolution.Groups)
+	}
+	if resolution.Consistent() {
+		t.Error("Expected the hosts to disagree")
+	}
+}
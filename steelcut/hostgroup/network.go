@@ -0,0 +1,117 @@
+package hostgroup
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// HostNetwork is the network configuration read from one host.
+type HostNetwork struct {
+	Hostname   string
+	Interfaces []networkmanager.Interface
+	Routes     []networkmanager.Route
+	Err        error // set when the configuration could not be read
+}
+
+// NetworkIssue is a problem found by comparing network configurations.
+type NetworkIssue struct {
+	Hostname  string
+	Interface string // empty for host-wide issues
+	Problem   string
+}
+
+// Networks reads the interfaces and routes of every host in the group,
+// sorted by hostname.
+func (hg *HostGroup) Networks() []HostNetwork {
+	hg.RLock()
+	hosts := make([]*host.Host, 0, len(hg.Hosts))
+	for _, h := range hg.Hosts {
+		hosts = append(hosts, h)
+	}
+	hg.RUnlock()
+
+	networks := make([]HostNetwork, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h *host.Host) {
+			defer wg.Done()
+			network := HostNetwork{Hostname: h.Hostname}
+			network.Interfaces, network.Err = h.NetworkManager.Interfaces()
+			if network.Err == nil {
+				network.Routes, network.Err = h.NetworkManager.Routes()
+			}
+			networks[i] = network
+		}(i, h)
+	}
+	wg.Wait()
+
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Hostname < networks[j].Hostname })
+	return networks
+}
+
+// CheckNetworks flags hosts without an IPv4 default route and interfaces
+// whose MTU differs from the one most common across the fleet. Only
+// interfaces that are up and carry a global address are compared, so
+// loopback devices and bridges without addresses do not skew the result.
+func CheckNetworks(networks []HostNetwork) []NetworkIssue {
+	counts := make(map[int]int)
+	for _, network := range networks {
+		for _, iface := range network.Interfaces {
+			if comparesMTU(iface) {
+				counts[iface.MTU]++
+			}
+		}
+	}
+	common := 0
+	for mtu, count := range counts {
+		if count > counts[common] || (count == counts[common] && mtu < common) {
+			common = mtu
+		}
+	}
+
+	var issues []NetworkIssue
+	for _, network := range networks {
+		if network.Err != nil {
+			continue
+		}
+		if !hasDefaultRoute(network.Routes) {
+			issues = append(issues, NetworkIssue{Hostname: network.Hostname, Problem: "no IPv4 default route"})
+		}
+		for _, iface := range network.Interfaces {
+			if comparesMTU(iface) && iface.MTU != common {
+				issues = append(issues, NetworkIssue{
+					Hostname:  network.Hostname,
+					Interface: iface.Name,
+					Problem:   fmt.Sprintf("MTU %d differs from the fleet's %d", iface.MTU, common),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+func comparesMTU(iface networkmanager.Interface) bool {
+	if iface.State != "up" {
+		return false
+	}
+	for _, addr := range iface.Addresses {
+		if addr.Scope == "global" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDefaultRoute(routes []networkmanager.Route) bool {
+	for _, route := range routes {
+		if route.Family == "inet" && route.IsDefault() {
+			return true
+		}
+	}
+	return false
+}
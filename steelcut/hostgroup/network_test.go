@@ -0,0 +1,37 @@
+package hostgroup
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+func TestCheckNetworks(t *testing.T) {
+	global := func(ip string) []networkmanager.Address {
+		return []networkmanager.Address{{Family: "inet", IP: ip, PrefixLen: 24, Scope: "global"}}
+	}
+	defaultRoute := []networkmanager.Route{{Family: "inet", Destination: "default", Gateway: "10.0.0.1", Interface: "eth0"}}
+
+	networks := []HostNetwork{
+		{Hostname: "db1", Routes: defaultRoute, Interfaces: []networkmanager.Interface{
+			{Name: "lo", MTU: 65536, State: "up", Addresses: []networkmanager.Address{{Family: "inet", IP: "127.0.0.1", PrefixLen: 8, Scope: "host"}}},
+			{Name: "eth0", MTU: 1500, State: "up", Addresses: global("10.0.0.2")},
+		}},
+		{Hostname: "db2", Routes: defaultRoute, Interfaces: []networkmanager.Interface{
+			{Name: "eth0", MTU: 9000, State: "up", Addresses: global("10.0.0.3")},
+			{Name: "eth1", MTU: 1400, State: "down", Addresses: global("10.0.1.3")},
+		}},
+		{Hostname: "web1", Interfaces: []networkmanager.Interface{
+			{Name: "eth0", MTU: 1500, State: "up", Addresses: global("10.0.0.4")},
+		}},
+	}
+
+	expected := []NetworkIssue{
+		{Hostname: "db2", Interface: "eth0", Problem: "MTU 9000 differs from the fleet's 1500"},
+		{Hostname: "web1", Problem: "no IPv4 default route"},
+	}
+	if issues := CheckNetworks(networks); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, issues)
+	}
+}
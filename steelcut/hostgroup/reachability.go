@@ -0,0 +1,89 @@
+package hostgroup
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// PingOutcome is the result of pinging one host from another.
+type PingOutcome struct {
+	networkmanager.PingResult
+	Err error // set when the ping could not be run at all
+}
+
+// Reachability holds the outcome of pinging every host from every other host.
+type Reachability struct {
+	Hosts   []string                          // sorted hostnames
+	Results map[string]map[string]PingOutcome // source hostname to target hostname
+}
+
+// HostPair is a directed connection between two hosts.
+type HostPair struct {
+	From string
+	To   string
+}
+
+// Reachable reports whether from got at least one reply from to.
+func (r *Reachability) Reachable(from, to string) bool {
+	outcome, ok := r.Results[from][to]
+	return ok && outcome.Err == nil && outcome.Success
+}
+
+// Asymmetric returns the pairs where From reaches To but To cannot reach From,
+// which usually points at a one-sided firewall rule or a routing problem.
+func (r *Reachability) Asymmetric() []HostPair {
+	var pairs []HostPair
+	for _, from := range r.Hosts {
+		for _, to := range r.Hosts {
+			if from != to && r.Reachable(from, to) && !r.Reachable(to, from) {
+				pairs = append(pairs, HostPair{From: from, To: to})
+			}
+		}
+	}
+	return pairs
+}
+
+// Reachability pings every host in the group from every other host, by the
+// hostname the group knows it as. Sources run concurrently; each source pings
+// its targets one at a time to keep the number of connections down.
+func (hg *HostGroup) Reachability(count int, timeout time.Duration) *Reachability {
+	hg.RLock()
+	hosts := make([]*host.Host, 0, len(hg.Hosts))
+	for _, h := range hg.Hosts {
+		hosts = append(hosts, h)
+	}
+	hg.RUnlock()
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Hostname < hosts[j].Hostname })
+
+	reachability := &Reachability{Results: make(map[string]map[string]PingOutcome)}
+	for _, h := range hosts {
+		reachability.Hosts = append(reachability.Hosts, h.Hostname)
+		reachability.Results[h.Hostname] = make(map[string]PingOutcome)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, source := range hosts {
+		wg.Add(1)
+		go func(source *host.Host) {
+			defer wg.Done()
+			for _, target := range hosts {
+				if target == source {
+					continue
+				}
+				result, err := source.NetworkManager.Ping(target.Hostname, count, timeout)
+
+				mu.Lock()
+				reachability.Results[source.Hostname][target.Hostname] = PingOutcome{PingResult: result, Err: err}
+				mu.Unlock()
+			}
+		}(source)
+	}
+	wg.Wait()
+
+	return reachability
+}
@@ -0,0 +1,73 @@
+package hostgroup
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/networkmanager"
+)
+
+// MockNetworkManager answers pings from a set of reachable targets, port
+// checks from a set of open host:port pairs, and lookups and HTTP probes from
+// maps of names and URLs.
+type MockNetworkManager struct {
+	networkmanager.NetworkManager
+	Reachable map[string]bool
+	Open      map[string]bool
+	Names     map[string][]string
+	Responses map[string]networkmanager.HTTPProbeResult
+}
+
+func (m *MockNetworkManager) ProbeHTTP(probe networkmanager.HTTPProbe) (networkmanager.HTTPProbeResult, error) {
+	result, found := m.Responses[probe.URL]
+	if !found {
+		return networkmanager.HTTPProbeResult{URL: probe.URL, Error: "connection refused"}, nil
+	}
+	result.URL = probe.URL
+	return result, nil
+}
+
+func (m *MockNetworkManager) ResolveName(name string) ([]string, error) {
+	return append([]string{}, m.Names[name]...), nil
+}
+
+func (m *MockNetworkManager) Ping(address string, count int, timeout time.Duration) (networkmanager.PingResult, error) {
+	if m.Reachable[address] {
+		return networkmanager.PingResult{Address: address, Transmitted: count, Received: count, Success: true}, nil
+	}
+	return networkmanager.PingResult{Address: address, Transmitted: count, PacketLoss: 100}, nil
+}
+
+func (m *MockNetworkManager) CheckPort(host string, port int, proto string, timeout time.Duration) (networkmanager.PortCheckResult, error) {
+	result := networkmanager.PortCheckResult{Host: host, Port: port, Protocol: proto, Open: m.Open[fmt.Sprintf("%s:%d", host, port)]}
+	if !result.Open {
+		result.Error = "connection refused"
+	}
+	return result, nil
+}
+
+func TestReachability(t *testing.T) {
+	// db can be reached by app but a firewall stops db from reaching app.
+	hg := NewHostGroup(
+		&host.Host{Hostname: "app", NetworkManager: &MockNetworkManager{Reachable: map[string]bool{"db": true, "web": true}}},
+		&host.Host{Hostname: "db", NetworkManager: &MockNetworkManager{Reachable: map[string]bool{"web": true}}},
+		&host.Host{Hostname: "web", NetworkManager: &MockNetworkManager{Reachable: map[string]bool{"app": true, "db": true}}},
+	)
+
+	reachability := hg.Reachability(3, time.Second)
+
+	if !reflect.DeepEqual(reachability.Hosts, []string{"app", "db", "web"}) {
+		t.Errorf("Expected sorted hosts, got %v", reachability.Hosts)
+	}
+	if _, pinged := reachability.Results["app"]["app"]; pinged {
+		t.Error("Expected hosts not to ping themselves")
+	}
+
+	expected := []HostPair{{From: "app", To: "db"}}
+	if got := reachability.Asymmetric(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
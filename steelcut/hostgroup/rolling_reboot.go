@@ -0,0 +1,97 @@
+package hostgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+)
+
+// HostCheck verifies a host after it has been rebooted.
+type HostCheck func(ctx context.Context, h *host.Host) error
+
+// RollingReboot reboots the group batchSize hosts at a time, in hostname order.
+// Each batch must come back and pass checks within timeout before the next one
+// starts. The rollout stops at the first batch with a failure, so a host that
+// does not come back cannot take the rest of the group down with it.
+func (hg *HostGroup) RollingReboot(ctx context.Context, batchSize int, timeout time.Duration, checks ...HostCheck) error {
+	if batchSize < 1 {
+		return errors.New("batch size must be at least 1")
+	}
+
+	hg.RLock()
+	hosts := make([]*host.Host, 0, len(hg.Hosts))
+	for _, h := range hg.Hosts {
+		hosts = append(hosts, h)
+	}
+	hg.RUnlock()
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Hostname < hosts[j].Hostname })
+
+	for start := 0; start < len(hosts); start += batchSize {
+		end := start + batchSize
+		if end > len(hosts) {
+			end = len(hosts)
+		}
+		batch := hosts[start:end]
+
+		slog.Info("Rebooting batch", "hosts", hostnames(batch), "remaining", len(hosts)-end)
+		if err := rebootBatch(ctx, batch, timeout, checks); err != nil {
+			if end < len(hosts) {
+				err = multierror.Append(err, fmt.Errorf("rolling reboot stopped, not rebooted: %s", strings.Join(hostnames(hosts[end:]), ", ")))
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rebootBatch(ctx context.Context, batch []*host.Host, timeout time.Duration, checks []HostCheck) error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var result error
+
+	for _, h := range batch {
+		wg.Add(1)
+		go func(hostInstance *host.Host) {
+			defer wg.Done()
+
+			rebootChecks := make([]hostmanager.RebootCheck, len(checks))
+			for i, check := range checks {
+				check := check
+				rebootChecks[i] = func(ctx context.Context) error {
+					return check(ctx, hostInstance)
+				}
+			}
+
+			err := hostInstance.HostManager.RebootAndWait(ctx, timeout, rebootChecks...)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result = multierror.Append(result, fmt.Errorf("%s: %w", hostInstance.Hostname, err))
+				return
+			}
+			slog.Info("Host rebooted", "host", hostInstance.Hostname)
+		}(h)
+	}
+
+	wg.Wait()
+	return result
+}
+
+func hostnames(hosts []*host.Host) []string {
+	names := make([]string, len(hosts))
+	for i, h := range hosts {
+		names[i] = h.Hostname
+	}
+	return names
+}
@@ -0,0 +1,81 @@
+package hostgroup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/host"
+	"github.com/steelcutops/steelcut/steelcut/hostmanager"
+)
+
+// MockHostManager records reboots, embedding the interface so only
+// RebootAndWait needs implementing.
+type MockHostManager struct {
+	hostmanager.HostManager
+	Err error
+
+	mu      *sync.Mutex
+	order   *[]string
+	name    string
+	checked bool
+}
+
+func (m *MockHostManager) RebootAndWait(ctx context.Context, timeout time.Duration, checks ...hostmanager.RebootCheck) error {
+	m.mu.Lock()
+	*m.order = append(*m.order, m.name)
+	m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	for _, check := range checks {
+		if err := check(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRollingReboot(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+
+	newHost := func(name string, err error) *host.Host {
+		return &host.Host{Hostname: name, HostManager: &MockHostManager{Err: err, mu: &mu, order: &order, name: name}}
+	}
+
+	hg := NewHostGroup(
+		newHost("web4", nil),
+		newHost("web1", nil),
+		newHost("web3", nil),
+		newHost("web2", errors.New("host did not come back")),
+		newHost("web5", nil),
+	)
+
+	var checked []string
+	err := hg.RollingReboot(context.Background(), 2, time.Minute, func(ctx context.Context, h *host.Host) error {
+		mu.Lock()
+		defer mu.Unlock()
+		checked = append(checked, h.Hostname)
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("Expected an error from the failed batch")
+	}
+	if !strings.Contains(err.Error(), "web2: host did not come back") || !strings.Contains(err.Error(), "not rebooted: web3, web4, web5") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(order) != 2 || len(checked) != 1 || checked[0] != "web1" {
+		t.Errorf("Expected only the first batch to be rebooted, got: %v (checked %v)", order, checked)
+	}
+}
+
+func TestRollingRebootInvalidBatch(t *testing.T) {
+	if err := NewHostGroup().RollingReboot(context.Background(), 0, time.Minute); err == nil {
+		t.Errorf("Expected an error for a zero batch size")
+	}
+}
@@ -1,6 +1,12 @@
 package hostmanager
 
-import "time"
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
 
 type HostInfo struct {
 	Hostname      string
@@ -10,16 +16,205 @@ type HostInfo struct {
 	NumberOfCores int
 }
 
+// HostStats is a point-in-time view of resource usage, gathered in a single round trip.
+type HostStats struct {
+	Metrics
+	Mounts    []filemanager.Mount
+	Processes []Process
+}
+
+// Metrics are the host's load, CPU and memory figures.
+type Metrics struct {
+	Load   LoadAverage   `json:"load"`
+	CPU    CPUStat       `json:"cpu"`   // all cores combined
+	Cores  []CPUStat     `json:"cores"` // one entry per core
+	Memory MemoryStats   `json:"memory"`
+	Uptime time.Duration `json:"uptime"`
+}
+
+// LoadAverage is the run queue length averaged over 1, 5 and 15 minutes.
+type LoadAverage struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// CPUStat is the share of CPU time spent in each state between two samples, in percent.
+type CPUStat struct {
+	Name   string  `json:"name"` // cpu for the aggregate, cpu0, cpu1, ... per core
+	Usage  float64 `json:"usage"`
+	User   float64 `json:"user"`
+	System float64 `json:"system"`
+	IOWait float64 `json:"iowait"`
+	Steal  float64 `json:"steal"`
+}
+
+// MemoryStats describes physical memory and swap. Sizes are in bytes.
+type MemoryStats struct {
+	Total            int64   `json:"total"`
+	Used             int64   `json:"used"` // Total minus Available
+	Available        int64   `json:"available"`
+	Free             int64   `json:"free"`
+	Cached           int64   `json:"cached"` // page cache and buffers
+	SwapTotal        int64   `json:"swapTotal"`
+	SwapUsed         int64   `json:"swapUsed"`
+	UsedPercent      float64 `json:"usedPercent"`
+	AvailablePercent float64 `json:"availablePercent"`
+	SwapUsedPercent  float64 `json:"swapUsedPercent"`
+}
+
+// Process is one entry of the host's process table.
+type Process struct {
+	PID        int       `json:"pid"`
+	PPID       int       `json:"ppid"`
+	User       string    `json:"user"`
+	State      string    `json:"state"` // ps STAT column, e.g. S, R or Z
+	CPUPercent float64   `json:"cpuPercent"`
+	RSS        int64     `json:"rss"` // bytes
+	StartTime  time.Time `json:"startTime"`
+	Command    string    `json:"command"` // full command line
+}
+
+// ProcessFilter selects processes. Zero-valued fields match every process.
+type ProcessFilter struct {
+	User    string
+	State   string // matched against the first letter of the ps STAT column
+	Pattern string // regular expression matched against the full command line
+	MinCPU  float64
+	MinRSS  int64 // bytes
+}
+
+// ProcessNode is a process and its descendants.
+type ProcessNode struct {
+	Process
+	Children []*ProcessNode `json:"children,omitempty"`
+}
+
+// ClockSkew is how far a host's clock is ahead of the controller's.
+type ClockSkew struct {
+	Offset    time.Duration `json:"offset"`    // negative when the host is behind
+	RoundTrip time.Duration `json:"roundTrip"` // Offset is accurate to within half of this
+}
+
+// HardwareInventory describes the machine a host runs on. Sources that are
+// missing, as is common in VMs and containers, leave their fields empty and
+// are noted in Warnings instead of failing the inventory.
+type HardwareInventory struct {
+	System   SystemHardware `json:"system"`
+	CPU      CPUHardware    `json:"cpu"`
+	Memory   MemoryHardware `json:"memory"`
+	Disks    []Disk         `json:"disks"`
+	NICs     []NIC          `json:"nics"`
+	Warnings []string       `json:"warnings,omitempty"`
+}
+
+// SystemHardware identifies the machine, mostly from /sys/class/dmi/id.
+type SystemHardware struct {
+	Vendor      string `json:"vendor"`
+	Product     string `json:"product"`
+	Serial      string `json:"serial"`
+	BIOSVersion string `json:"biosVersion"`
+}
+
+// CPUHardware describes the processors. Cores and Threads are totals across all sockets.
+type CPUHardware struct {
+	Model   string `json:"model"`
+	Sockets int    `json:"sockets"`
+	Cores   int    `json:"cores"`
+	Threads int    `json:"threads"`
+}
+
+// MemoryHardware is the installed memory. DIMMs are only known where
+// dmidecode can read the SMBIOS tables.
+type MemoryHardware struct {
+	Total int64  `json:"total"` // bytes, as seen by the kernel
+	DIMMs []DIMM `json:"dimms,omitempty"`
+}
+
+// DIMM is one populated memory slot.
+type DIMM struct {
+	Locator      string `json:"locator"`
+	Size         int64  `json:"size"` // bytes
+	Type         string `json:"type"`
+	Speed        string `json:"speed"`
+	Manufacturer string `json:"manufacturer"`
+	PartNumber   string `json:"partNumber"`
+	Serial       string `json:"serial"`
+}
+
+// Disk is a whole block device, as listed by lsblk.
+type Disk struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"` // bytes
+	Model      string `json:"model"`
+	Serial     string `json:"serial"`
+	Transport  string `json:"transport"` // e.g. sata, nvme or usb
+	Rotational bool   `json:"rotational"`
+}
+
+// NIC is a network interface.
+type NIC struct {
+	Name    string `json:"name"`
+	MAC     string `json:"mac"`
+	Speed   int    `json:"speed"`   // Mbit/s, zero when unknown or the link is down
+	Virtual bool   `json:"virtual"` // bridges, veths, tunnels and the like
+}
+
+// RebootCheck verifies a host after it has come back from a reboot, for
+// example that a service is listening again.
+type RebootCheck func(ctx context.Context) error
+
 // HostManager encompasses operations related to host management.
 type HostManager interface {
 	Info() (HostInfo, error)
 	Hostname() (string, error)
+	SetHostname(name string) error // Also makes /etc/hosts resolve the new name
+	Timezone() (string, error)
+	SetTimezone(timezone string) error
+	SetLocale(locale string) error
+	EnsureTimeSync(servers []string) error
+	ClockSkew() (ClockSkew, error)
 	Uptime() (time.Duration, error)
 	CPUCount() (int, error)
 	TotalMemory() (int64, error) // Return memory in bytes
 	FreeMemory() (int64, error)  // Return free memory in bytes
 	Reboot() error
+	BootID() (string, error) // Return an identifier that changes on every boot
+	// RebootRequired reports whether updates are waiting on a reboot, and why.
+	RebootRequired() (bool, []string, error)
+	// RebootAndWait reboots the host, waits for it to come back with a new boot
+	// ID and then runs checks until they pass, all within timeout.
+	RebootAndWait(ctx context.Context, timeout time.Duration, checks ...RebootCheck) error
 	Shutdown() error
-	CPUUsage() (float64, error)   // Return CPU usage as a percentage
-	Processes() ([]string, error) // Return a list of running processes
+	CPUUsage() (float64, error) // Return CPU usage as a percentage
+	LoadAverage() (LoadAverage, error)
+	Memory() (MemoryStats, error)
+	Metrics() (Metrics, error)
+	Processes() ([]Process, error) // Return the running processes
+	FindProcesses(filter ProcessFilter) ([]Process, error)
+	Signal(pid int, sig string) error             // sig is a name such as TERM, HUP or KILL
+	KillByName(pattern string) ([]Process, error) // Send TERM to processes whose command line matches pattern
+	ProcessTree(pid int) (*ProcessNode, error)    // Return pid and all of its descendants
+	Stats() (HostStats, error)
+	HardwareInventory() (HardwareInventory, error)
+}
+
+// TopByCPU returns up to n of processes, highest CPU usage first.
+func TopByCPU(processes []Process, n int) []Process {
+	return top(processes, n, func(a, b Process) bool { return a.CPUPercent > b.CPUPercent })
+}
+
+// TopByMemory returns up to n of processes, largest resident set first.
+func TopByMemory(processes []Process, n int) []Process {
+	return top(processes, n, func(a, b Process) bool { return a.RSS > b.RSS })
+}
+
+func top(processes []Process, n int, less func(a, b Process) bool) []Process {
+	sorted := make([]Process, len(processes))
+	copy(sorted, processes)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
 }
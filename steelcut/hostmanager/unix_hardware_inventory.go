@@ -0,0 +1,418 @@
+package hostmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const dmiDir = "/sys/class/dmi/id"
+
+// Inventory sources, gathered in one batch. Linux and macOS each only have
+// some of them, the rest fail and are skipped.
+var inventoryConfigs = []cm.CommandConfig{
+	{Command: "lscpu", Env: []string{"LC_ALL=C"}},
+	{Command: "cat", Args: []string{"/proc/cpuinfo"}},
+	procMeminfoConfig,
+	{Command: "lsblk", Args: []string{"-J", "-b", "-d", "-o", "NAME,SIZE,TYPE,MODEL,SERIAL,ROTA,TRAN"}},
+	{Command: "sh", Args: []string{"-c", `for d in /sys/class/net/*; do n=${d##*/}; v=0; [ -e /sys/devices/virtual/net/$n ] && v=1; printf '%s|%s|%s|%s\n' "$n" "$(cat $d/address 2>/dev/null)" "$(cat $d/speed 2>/dev/null)" "$v"; done`}},
+	{Command: "sh", Args: []string{"-c", "cd " + dmiDir + " && for f in sys_vendor product_name bios_version; do printf '%s=%s\\n' $f \"$(cat $f 2>/dev/null)\"; done"}},
+	{Command: "sysctl", Args: []string{"machdep.cpu.brand_string", "hw.packages", "hw.physicalcpu", "hw.logicalcpu", "hw.memsize"}},
+	{Command: "networksetup", Args: []string{"-listallhardwareports"}},
+	{Command: "ioreg", Args: []string{"-rd1", "-c", "IOPlatformExpertDevice"}},
+}
+
+const (
+	invLscpu = iota
+	invCPUInfo
+	invMeminfo
+	invLsblk
+	invNet
+	invDMI
+	invSysctl
+	invNetworksetup
+	invIoreg
+)
+
+// Sources that need root, gathered in a second batch.
+var privilegedInventoryConfigs = []cm.CommandConfig{
+	{Command: "dmidecode", Args: []string{"-t", "17"}, Sudo: true},
+	{Command: "cat", Args: []string{dmiDir + "/product_serial"}, Sudo: true},
+}
+
+// placeholderValues are what firmware reports for fields the vendor left blank.
+var placeholderValues = map[string]bool{
+	"":                       true,
+	"0":                      true,
+	"none":                   true,
+	"unknown":                true,
+	"not specified":          true,
+	"not provided":           true,
+	"not available":          true,
+	"default string":         true,
+	"system serial number":   true,
+	"to be filled by o.e.m.": true,
+	"no module installed":    true,
+}
+
+var ioregProperty = regexp.MustCompile(`"(\w+)" = <?"([^"]*)"`)
+
+// HardwareInventory collects CPU, memory, disk, NIC and system identity data.
+// Only failing to reach the host is an error; missing sources, such as DMI in
+// containers or dmidecode without sudo, are reported as warnings.
+func (uhm *UnixHostManager) HardwareInventory() (HardwareInventory, error) {
+	results, err := cm.RunBatch(context.TODO(), uhm.CommandManager, inventoryConfigs)
+	if err != nil {
+		return HardwareInventory{}, err
+	}
+
+	ok := func(i int) bool {
+		return results[i].ExitCode == 0 && strings.TrimSpace(results[i].STDOUT) != ""
+	}
+
+	var inventory HardwareInventory
+	warn := func(format string, args ...interface{}) {
+		inventory.Warnings = append(inventory.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	darwin := ok(invSysctl)
+	switch {
+	case ok(invLscpu):
+		inventory.CPU = parseLscpu(results[invLscpu].STDOUT)
+	case ok(invCPUInfo):
+		inventory.CPU = parseCPUInfo(results[invCPUInfo].STDOUT)
+	case darwin:
+		inventory.CPU = parseDarwinCPU(results[invSysctl].STDOUT)
+	default:
+		warn("no CPU information available")
+	}
+
+	switch {
+	case ok(invMeminfo):
+		if total, err := parseMeminfo(results[invMeminfo].STDOUT, "MemTotal"); err == nil {
+			inventory.Memory.Total = total
+		}
+	case darwin:
+		inventory.Memory.Total, _ = strconv.ParseInt(parseColonValues(results[invSysctl].STDOUT)["hw.memsize"], 10, 64)
+	}
+	if inventory.Memory.Total == 0 {
+		warn("total memory unknown")
+	}
+
+	if ok(invLsblk) {
+		disks, err := parseLsblk(results[invLsblk].STDOUT)
+		if err != nil {
+			warn("failed to parse lsblk output: %v", err)
+		}
+		inventory.Disks = disks
+	} else if !darwin {
+		warn("lsblk unavailable, disks unknown")
+	}
+
+	switch {
+	case ok(invNet):
+		inventory.NICs = parseSysfsNICs(results[invNet].STDOUT)
+	case ok(invNetworksetup):
+		inventory.NICs = parseNetworksetup(results[invNetworksetup].STDOUT)
+	default:
+		warn("no network interface information available")
+	}
+
+	switch {
+	case ok(invDMI):
+		inventory.System = parseDMI(results[invDMI].STDOUT)
+	case ok(invIoreg):
+		inventory.System = parseIoreg(results[invIoreg].STDOUT)
+	default:
+		warn("%s unavailable, system vendor and serial unknown", dmiDir)
+	}
+
+	// dmidecode and the DMI serial are Linux only and need root.
+	if !darwin {
+		uhm.privilegedInventory(&inventory, ok(invDMI), warn)
+	}
+
+	return inventory, nil
+}
+
+func (uhm *UnixHostManager) privilegedInventory(inventory *HardwareInventory, hasDMI bool, warn func(string, ...interface{})) {
+	results, err := cm.RunBatch(context.TODO(), uhm.CommandManager, privilegedInventoryConfigs)
+	if err != nil {
+		warn("could not read DIMMs and serial number with sudo: %v", err)
+		return
+	}
+
+	if results[0].ExitCode == 0 {
+		inventory.Memory.DIMMs = parseDmidecodeMemory(results[0].STDOUT)
+	} else {
+		warn("dmidecode unavailable, DIMMs unknown")
+	}
+
+	if results[1].ExitCode == 0 {
+		inventory.System.Serial = cleanHardwareValue(results[1].STDOUT)
+	} else if hasDMI {
+		warn("could not read the system serial number")
+	}
+}
+
+// cleanHardwareValue trims value and blanks out firmware placeholders.
+func cleanHardwareValue(value string) string {
+	value = strings.TrimSpace(value)
+	if placeholderValues[strings.ToLower(value)] {
+		return ""
+	}
+	return value
+}
+
+// parseColonValues parses "key: value" lines, as printed by lscpu,
+// /proc/cpuinfo, dmidecode and BSD sysctl. Later duplicates are ignored.
+func parseColonValues(content string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, seen := values[key]; !seen {
+			values[key] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+func atoi(value string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n
+}
+
+func parseLscpu(output string) CPUHardware {
+	values := parseColonValues(output)
+
+	cpu := CPUHardware{
+		Model:   values["Model name"],
+		Sockets: atoi(values["Socket(s)"]),
+		Threads: atoi(values["CPU(s)"]),
+	}
+	// ARM systems may report clusters rather than sockets.
+	sockets := cpu.Sockets
+	if sockets == 0 {
+		sockets = atoi(values["Cluster(s)"])
+	}
+	if sockets == 0 {
+		sockets = 1
+	}
+	cpu.Cores = sockets * atoi(values["Core(s) per socket"])
+	if cpu.Cores == 0 {
+		cpu.Cores = atoi(values["Core(s) per cluster"]) * sockets
+	}
+	return cpu
+}
+
+// parseCPUInfo is the fallback for hosts without lscpu, such as busybox systems.
+func parseCPUInfo(content string) CPUHardware {
+	var cpu CPUHardware
+	sockets := make(map[string]bool)
+	cores := make(map[string]bool)
+
+	for _, block := range strings.Split(content, "\n\n") {
+		values := parseColonValues(block)
+		if _, found := values["processor"]; !found {
+			continue
+		}
+		cpu.Threads++
+		if cpu.Model == "" {
+			cpu.Model = values["model name"]
+		}
+		if id, found := values["physical id"]; found {
+			sockets[id] = true
+			cores[id+"/"+values["core id"]] = true
+		}
+	}
+
+	cpu.Sockets = len(sockets)
+	cpu.Cores = len(cores)
+	if cpu.Cores == 0 {
+		cpu.Cores = cpu.Threads
+	}
+	if cpu.Model == "" {
+		cpu.Model = parseColonValues(content)["Hardware"]
+	}
+	return cpu
+}
+
+func parseDarwinCPU(output string) CPUHardware {
+	values := parseColonValues(output)
+	return CPUHardware{
+		Model:   values["machdep.cpu.brand_string"],
+		Sockets: atoi(values["hw.packages"]),
+		Cores:   atoi(values["hw.physicalcpu"]),
+		Threads: atoi(values["hw.logicalcpu"]),
+	}
+}
+
+// parseLsblk parses lsblk -J output. Older lsblk releases print every value
+// as a string, newer ones use numbers and booleans, so both are accepted.
+func parseLsblk(output string) ([]Disk, error) {
+	var listing struct {
+		BlockDevices []map[string]interface{} `json:"blockdevices"`
+	}
+	if err := json.Unmarshal([]byte(output), &listing); err != nil {
+		return nil, err
+	}
+
+	text := func(device map[string]interface{}, key string) string {
+		switch v := device[key].(type) {
+		case string:
+			return strings.TrimSpace(v)
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+		return ""
+	}
+
+	var disks []Disk
+	for _, device := range listing.BlockDevices {
+		if text(device, "type") != "disk" {
+			continue
+		}
+		size, _ := strconv.ParseInt(text(device, "size"), 10, 64)
+		if size == 0 {
+			// Unconfigured zram and empty card readers.
+			continue
+		}
+		rota := text(device, "rota")
+		disks = append(disks, Disk{
+			Name:       text(device, "name"),
+			Size:       size,
+			Model:      text(device, "model"),
+			Serial:     text(device, "serial"),
+			Transport:  text(device, "tran"),
+			Rotational: rota == "true" || rota == "1",
+		})
+	}
+	return disks, nil
+}
+
+// parseSysfsNICs parses the name|address|speed|virtual lines built from /sys/class/net.
+func parseSysfsNICs(output string) []NIC {
+	var nics []NIC
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) != 4 || fields[0] == "lo" {
+			continue
+		}
+		speed := atoi(fields[2])
+		if speed < 0 {
+			// Reported as -1 while the link is down.
+			speed = 0
+		}
+		nics = append(nics, NIC{
+			Name:    fields[0],
+			MAC:     fields[1],
+			Speed:   speed,
+			Virtual: fields[3] == "1",
+		})
+	}
+	return nics
+}
+
+// parseNetworksetup parses macOS networksetup -listallhardwareports output.
+func parseNetworksetup(output string) []NIC {
+	var nics []NIC
+	for _, block := range strings.Split(output, "\n\n") {
+		values := parseColonValues(block)
+		name := values["Device"]
+		if name == "" {
+			continue
+		}
+		mac := values["Ethernet Address"]
+		if mac == "N/A" {
+			mac = ""
+		}
+		nics = append(nics, NIC{Name: name, MAC: mac})
+	}
+	return nics
+}
+
+func parseDMI(output string) SystemHardware {
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, found := strings.Cut(line, "="); found {
+			values[key] = cleanHardwareValue(value)
+		}
+	}
+	return SystemHardware{
+		Vendor:      values["sys_vendor"],
+		Product:     values["product_name"],
+		BIOSVersion: values["bios_version"],
+	}
+}
+
+// parseIoreg parses the IOPlatformExpertDevice entry on macOS.
+func parseIoreg(output string) SystemHardware {
+	values := make(map[string]string)
+	for _, match := range ioregProperty.FindAllStringSubmatch(output, -1) {
+		values[match[1]] = strings.TrimSpace(match[2])
+	}
+	return SystemHardware{
+		Vendor:  values["manufacturer"],
+		Product: values["model"],
+		Serial:  values["IOPlatformSerialNumber"],
+	}
+}
+
+// parseDmidecodeMemory parses dmidecode -t 17, skipping empty slots.
+func parseDmidecodeMemory(output string) []DIMM {
+	var dimms []DIMM
+	for _, block := range strings.Split(output, "\n\n") {
+		if !strings.Contains(block, "Memory Device") {
+			continue
+		}
+		values := parseColonValues(block)
+		size := parseDIMMSize(values["Size"])
+		if size == 0 {
+			continue
+		}
+		dimms = append(dimms, DIMM{
+			Locator:      values["Locator"],
+			Size:         size,
+			Type:         cleanHardwareValue(values["Type"]),
+			Speed:        cleanHardwareValue(values["Speed"]),
+			Manufacturer: cleanHardwareValue(values["Manufacturer"]),
+			PartNumber:   cleanHardwareValue(values["Part Number"]),
+			Serial:       cleanHardwareValue(values["Serial Number"]),
+		})
+	}
+	return dimms
+}
+
+// parseDIMMSize parses sizes such as "16 GB" or "8192 MB" into bytes.
+func parseDIMMSize(size string) int64 {
+	fields := strings.Fields(size)
+	if len(fields) != 2 {
+		return 0
+	}
+	n, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToUpper(fields[1]) {
+	case "KB":
+		return n << 10
+	case "MB":
+		return n << 20
+	case "GB":
+		return n << 30
+	case "TB":
+		return n << 40
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package hostmanager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+func configLine(config cm.CommandConfig) string {
+	return strings.Join(append([]string{config.Command}, config.Args...), " ")
+}
+
+const dmidecodeFixture = `# dmidecode 3.3
+Getting SMBIOS data from sysfs.
+SMBIOS 3.2.0 present.
+
+Handle 0x0040, DMI type 17, 84 bytes
+Memory Device
+	Total Width: 72 bits
+	Size: 16 GB
+	Locator: DIMM_A1
+	Type: DDR4
+	Speed: 3200 MT/s
+	Manufacturer: Samsung
+	Serial Number: 40A1B2C3
+	Part Number: M393A2K43DB3-CWE
+
+Handle 0x0041, DMI type 17, 84 bytes
+Memory Device
+	Size: No Module Installed
+	Locator: DIMM_A2
+	Type: Unknown
+
+Handle 0x0042, DMI type 17, 84 bytes
+Memory Device
+	Size: 8192 MB
+	Locator: DIMM_B1
+	Type: DDR4
+	Speed: Unknown
+	Manufacturer: Not Specified
+	Serial Number: Not Specified
+	Part Number: Not Specified
+`
+
+func TestParseDmidecodeMemory(t *testing.T) {
+	dimms := parseDmidecodeMemory(dmidecodeFixture)
+
+	expected := []DIMM{
+		{Locator: "DIMM_A1", Size: 16 << 30, Type: "DDR4", Speed: "3200 MT/s", Manufacturer: "Samsung", PartNumber: "M393A2K43DB3-CWE", Serial: "40A1B2C3"},
+		{Locator: "DIMM_B1", Size: 8 << 30, Type: "DDR4"},
+	}
+	if !reflect.DeepEqual(dimms, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, dimms)
+	}
+}
+
+func TestParseLsblk(t *testing.T) {
+	// Older lsblk releases quote every value.
+	output := `{"blockdevices": [
+		{"name": "sda", "size": "480103981056", "type": "disk", "model": "INTEL SSDSC2KB48", "serial": "BTYF0123", "rota": "0", "tran": "sata"},
+		{"name": "sr0", "size": "1073741312", "type": "rom", "model": "DVD", "serial": null, "rota": "1", "tran": "sata"},
+		{"name": "zram0", "size": 0, "type": "disk", "model": null, "serial": null, "rota": false, "tran": null},
+		{"name": "vda", "size": 274877906944, "type": "disk", "model": null, "serial": null, "rota": true, "tran": null}
+	]}`
+
+	disks, err := parseLsblk(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Disk{
+		{Name: "sda", Size: 480103981056, Model: "INTEL SSDSC2KB48", Serial: "BTYF0123", Transport: "sata"},
+		{Name: "vda", Size: 274877906944, Rotational: true},
+	}
+	if !reflect.DeepEqual(disks, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, disks)
+	}
+}
+
+func TestParseCPUInfo(t *testing.T) {
+	var cpuinfo strings.Builder
+	for i := 0; i < 8; i++ {
+		// Two sockets of two cores with two threads each.
+		cpuinfo.WriteString("processor\t: " + string(rune('0'+i)) + "\n")
+		cpuinfo.WriteString("model name\t: AMD EPYC 7302\n")
+		cpuinfo.WriteString("physical id\t: " + string(rune('0'+i/4)) + "\n")
+		cpuinfo.WriteString("core id\t\t: " + string(rune('0'+i%2)) + "\n\n")
+	}
+
+	cpu := parseCPUInfo(cpuinfo.String())
+	expected := CPUHardware{Model: "AMD EPYC 7302", Sockets: 2, Cores: 4, Threads: 8}
+	if cpu != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cpu)
+	}
+}
+
+// TestHardwareInventoryContainer covers a container without DMI data, where
+// the inventory should still succeed with what it could find.
+func TestHardwareInventoryContainer(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{
+			"lscpu":                              "CPU(s):              4\nModel name:          Intel(R) Xeon(R) Gold 6248\nThread(s) per core:  2\nCore(s) per socket:  2\nSocket(s):           1\n",
+			"cat /proc/meminfo":                  "MemTotal:       8000000 kB\n",
+			configLine(inventoryConfigs[invNet]): "lo|00:00:00:00:00:00|-1|1\neth0|02:42:ac:11:00:02|10000|1\n",
+		},
+		ExitCodes: map[string]int{
+			configLine(inventoryConfigs[invDMI]):                      1,
+			"lsblk -J -b -d -o NAME,SIZE,TYPE,MODEL,SERIAL,ROTA,TRAN": 127,
+			"dmidecode -t 17":                   127,
+			"cat " + dmiDir + "/product_serial": 1,
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	inventory, err := hostManager.HardwareInventory()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expectedCPU := CPUHardware{Model: "Intel(R) Xeon(R) Gold 6248", Sockets: 1, Cores: 2, Threads: 4}
+	if inventory.CPU != expectedCPU {
+		t.Errorf("Expected CPU %+v, got %+v", expectedCPU, inventory.CPU)
+	}
+	if inventory.Memory.Total != 8000000*1024 {
+		t.Errorf("Expected total memory from /proc/meminfo, got %d", inventory.Memory.Total)
+	}
+	expectedNICs := []NIC{{Name: "eth0", MAC: "02:42:ac:11:00:02", Speed: 10000, Virtual: true}}
+	if !reflect.DeepEqual(inventory.NICs, expectedNICs) {
+		t.Errorf("Expected NICs %+v, got %+v", expectedNICs, inventory.NICs)
+	}
+	if inventory.System != (SystemHardware{}) {
+		t.Errorf("Expected no system data, got %+v", inventory.System)
+	}
+	// lsblk, DMI and dmidecode are all missing.
+	if len(inventory.Warnings) != 3 {
+		t.Errorf("Expected 3 warnings, got %q", inventory.Warnings)
+	}
+}
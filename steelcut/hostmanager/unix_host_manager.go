@@ -3,59 +3,146 @@ package hostmanager
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	multierror "github.com/hashicorp/go-multierror"
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+// cpuSampleInterval is how long to wait for a second /proc/stat sample when
+// there is no earlier one to compute CPU usage against.
+const cpuSampleInterval = time.Second
+
+var (
+	procStatConfig    = cm.CommandConfig{Command: "cat", Args: []string{"/proc/stat"}}
+	procLoadavgConfig = cm.CommandConfig{Command: "cat", Args: []string{"/proc/loadavg"}}
+	procMeminfoConfig = cm.CommandConfig{Command: "cat", Args: []string{"/proc/meminfo"}}
+	procUptimeConfig  = cm.CommandConfig{Command: "cat", Args: []string{"/proc/uptime"}}
 )
 
 type UnixHostManager struct {
 	CommandManager cm.CommandManager
+	FileManager    filemanager.FileManager // used to edit files such as /etc/hosts
+
+	// mu guards cpuSample, the last /proc/stat reading. CPU usage is the
+	// difference between two readings, so keeping the last one lets a
+	// monitor polling on an interval avoid waiting for a second sample.
+	mu        sync.Mutex
+	cpuSample []cpuTimes
 }
 
-// Info gathers comprehensive information about the host system.
+// Info gathers comprehensive information about the host system in a single round trip.
 func (uhm *UnixHostManager) Info() (HostInfo, error) {
-	hostname, err := uhm.Hostname()
+	results, err := cm.RunBatch(context.TODO(), uhm.CommandManager, []cm.CommandConfig{
+		{Command: "hostname"},
+		procUptimeConfig,
+		{Command: "nproc"},
+		{Command: "uname", Args: []string{"-r"}},
+		{Command: "uname", Args: []string{"-o"}},
+	})
 	if err != nil {
 		return HostInfo{}, err
 	}
 
-	uptime, err := uhm.Uptime()
+	cpuCount, err := strconv.Atoi(strings.TrimSpace(results[2].STDOUT))
 	if err != nil {
 		return HostInfo{}, err
 	}
 
-	cpuCount, err := uhm.CPUCount()
+	uptime, err := parseProcUptime(results[1].STDOUT)
 	if err != nil {
 		return HostInfo{}, err
 	}
 
-	kernelVersionOutput, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "uname",
-		Args:    []string{"-r"},
+	return HostInfo{
+		Hostname:      strings.TrimSpace(results[0].STDOUT),
+		OSVersion:     strings.TrimSpace(results[4].STDOUT),
+		KernelVersion: strings.TrimSpace(results[3].STDOUT),
+		Uptime:        uptime.String(),
+		NumberOfCores: cpuCount,
+	}, nil
+}
+
+// Stats gathers load, CPU, memory, disk and process usage in a single round
+// trip, which is what the monitor polls on every interval.
+func (uhm *UnixHostManager) Stats() (HostStats, error) {
+	results, err := cm.RunBatch(context.TODO(), uhm.CommandManager, []cm.CommandConfig{
+		procStatConfig,
+		procLoadavgConfig,
+		procMeminfoConfig,
+		procUptimeConfig,
+		{Command: "df", Args: []string{"-P", "-B1", "-T"}},
+		{Command: "df", Args: []string{"-P", "-i"}},
+		psConfig,
 	})
 	if err != nil {
-		return HostInfo{}, err
+		return HostStats{}, err
 	}
 
-	osVersionOutput, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "uname",
-		Args:    []string{"-o"},
-	})
+	metrics, err := uhm.parseMetrics(results[0].STDOUT, results[1].STDOUT, results[2].STDOUT, results[3].STDOUT)
 	if err != nil {
-		return HostInfo{}, err
+		return HostStats{}, err
 	}
 
-	return HostInfo{
-		Hostname:      hostname,
-		OSVersion:     strings.TrimSpace(osVersionOutput.STDOUT),
-		KernelVersion: strings.TrimSpace(kernelVersionOutput.STDOUT),
-		Uptime:        uptime.String(),
-		NumberOfCores: cpuCount,
+	mounts, err := filemanager.ParseMounts(results[4].STDOUT, results[5].STDOUT)
+	if err != nil {
+		return HostStats{}, err
+	}
+
+	processes, err := parseProcesses(results[6].STDOUT, time.Now())
+	if err != nil {
+		return HostStats{}, err
+	}
+
+	return HostStats{
+		Metrics:   metrics,
+		Mounts:    mounts,
+		Processes: processes,
 	}, nil
 }
 
+// Metrics gathers load averages, CPU usage per core, memory and uptime in a single round trip.
+func (uhm *UnixHostManager) Metrics() (Metrics, error) {
+	results, err := cm.RunBatch(context.TODO(), uhm.CommandManager, []cm.CommandConfig{
+		procStatConfig,
+		procLoadavgConfig,
+		procMeminfoConfig,
+		procUptimeConfig,
+	})
+	if err != nil {
+		return Metrics{}, err
+	}
+
+	return uhm.parseMetrics(results[0].STDOUT, results[1].STDOUT, results[2].STDOUT, results[3].STDOUT)
+}
+
+func (uhm *UnixHostManager) parseMetrics(stat, loadavg, meminfo, uptime string) (Metrics, error) {
+	var metrics Metrics
+	var err error
+
+	if metrics.CPU, metrics.Cores, err = uhm.cpuStats(stat); err != nil {
+		return Metrics{}, err
+	}
+	if metrics.Load, err = parseLoadAverage(loadavg); err != nil {
+		return Metrics{}, err
+	}
+	if metrics.Memory, err = parseMemoryStats(meminfo); err != nil {
+		return Metrics{}, err
+	}
+	if metrics.Uptime, err = parseProcUptime(uptime); err != nil {
+		return Metrics{}, err
+	}
+
+	return metrics, nil
+}
+
 func (uhm *UnixHostManager) Hostname() (string, error) {
 	output, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "hostname",
@@ -75,6 +162,230 @@ func (uhm *UnixHostManager) Reboot() error {
 	return err
 }
 
+var (
+	// rebootPollInterval is how often a rebooting host is probed.
+	rebootPollInterval = 5 * time.Second
+
+	// rebootProbeTimeout bounds a single probe, so a host that accepts
+	// connections but is still booting does not stall the wait.
+	rebootProbeTimeout = 15 * time.Second
+)
+
+// BootID returns the kernel's boot ID, or the boot session UUID on macOS.
+func (uhm *UnixHostManager) BootID() (string, error) {
+	return uhm.bootID(context.TODO())
+}
+
+func (uhm *UnixHostManager) bootID(ctx context.Context) (string, error) {
+	output, err := uhm.CommandManager.Run(ctx, cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{"/proc/sys/kernel/random/boot_id"},
+	})
+	if err != nil || strings.TrimSpace(output.STDOUT) == "" {
+		output, err = uhm.CommandManager.Run(ctx, cm.CommandConfig{
+			Command: "sysctl",
+			Args:    []string{"-n", "kern.bootsessionuuid"},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	bootID := strings.TrimSpace(output.STDOUT)
+	if bootID == "" {
+		return "", errors.New("could not determine boot id")
+	}
+	return bootID, nil
+}
+
+// RebootAndWait records the boot ID, reboots the host and polls until it
+// answers with a different boot ID. The reboot is started in the background
+// with a short delay so the command returns before the connection drops.
+// Checks are retried until they all pass or the timeout is reached.
+func (uhm *UnixHostManager) RebootAndWait(ctx context.Context, timeout time.Duration, checks ...RebootCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	before, err := uhm.bootID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read boot id before reboot: %w", err)
+	}
+
+	_, err = uhm.CommandManager.Run(ctx, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", "nohup sh -c 'sleep 2; shutdown -r now' >/dev/null 2>&1 &"},
+		Sudo:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to issue reboot: %w", err)
+	}
+	slog.Debug("Reboot issued, waiting for host to go down", "bootID", before)
+
+	wentDown := false
+	for {
+		select {
+		case <-ctx.Done():
+			if wentDown {
+				return fmt.Errorf("host went down but did not come back within %s", timeout)
+			}
+			return fmt.Errorf("host did not reboot within %s", timeout)
+		case <-time.After(rebootPollInterval):
+		}
+
+		probeCtx, probeCancel := context.WithTimeout(ctx, rebootProbeTimeout)
+		after, err := uhm.bootID(probeCtx)
+		probeCancel()
+
+		if err != nil {
+			if !wentDown {
+				slog.Debug("Host is down, waiting for it to come back", "error", err)
+			}
+			wentDown = true
+			continue
+		}
+		if after != before {
+			slog.Debug("Host is back with a new boot id", "bootID", after)
+			break
+		}
+	}
+
+	return runRebootChecks(ctx, checks)
+}
+
+// runRebootChecks retries each check until it passes, as services may still
+// be starting when SSH first answers.
+func runRebootChecks(ctx context.Context, checks []RebootCheck) error {
+	for i, check := range checks {
+		for {
+			err := check(ctx)
+			if err == nil {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("post-reboot check %d failed: %w", i+1, err)
+			case <-time.After(rebootPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// RebootRequired checks the flag files Debian and Ubuntu leave behind after
+// an upgrade and needs-restarting on RHEL and Fedora. When needs-restarting
+// is not available the running kernel is compared with the newest installed
+// one, which catches kernel updates on any distribution.
+func (uhm *UnixHostManager) RebootRequired() (bool, []string, error) {
+	results, err := cm.RunBatch(context.TODO(), uhm.CommandManager, []cm.CommandConfig{
+		{Command: "cat", Args: []string{"/var/run/reboot-required"}},
+		{Command: "cat", Args: []string{"/var/run/reboot-required.pkgs"}},
+		{Command: "needs-restarting", Args: []string{"-r"}},
+		{Command: "uname", Args: []string{"-r"}},
+		{Command: "ls", Args: []string{"-1", "/lib/modules"}},
+	})
+	if err != nil {
+		return false, nil, err
+	}
+
+	var reasons []string
+
+	if results[0].ExitCode == 0 {
+		if pkgs := strings.Fields(results[1].STDOUT); results[1].ExitCode == 0 && len(pkgs) > 0 {
+			reasons = append(reasons, "updated packages: "+strings.Join(dedupe(pkgs), ", "))
+		} else {
+			reasons = append(reasons, "/var/run/reboot-required is present")
+		}
+	}
+
+	switch results[2].ExitCode {
+	case 0:
+		// needs-restarting ran and found nothing, and it covers the kernel too.
+	case 1:
+		reasons = append(reasons, parseNeedsRestarting(results[2].STDOUT)...)
+	default:
+		running := strings.TrimSpace(results[3].STDOUT)
+		if newest := newestKernel(results[4].STDOUT); running != "" && newest != "" && compareVersions(newest, running) > 0 {
+			reasons = append(reasons, fmt.Sprintf("running kernel %s, newest installed is %s", running, newest))
+		}
+	}
+
+	return len(reasons) > 0, reasons, nil
+}
+
+// parseNeedsRestarting lists the components needs-restarting -r reports as
+// updated since boot, such as kernel or glibc.
+func parseNeedsRestarting(output string) []string {
+	var reasons []string
+	for _, line := range strings.Split(output, "\n") {
+		if component, found := strings.CutPrefix(strings.TrimSpace(line), "* "); found {
+			reasons = append(reasons, "updated since boot: "+component)
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "needs-restarting reports a reboot is required")
+	}
+	return reasons
+}
+
+// newestKernel returns the highest version among the /lib/modules directories.
+func newestKernel(listing string) string {
+	var newest string
+	for _, version := range strings.Fields(listing) {
+		if newest == "" || compareVersions(version, newest) > 0 {
+			newest = version
+		}
+	}
+	return newest
+}
+
+// compareVersions compares a and b piece by piece, treating runs of digits as
+// numbers, so that 6.1.0-18 sorts after 6.1.0-9.
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		var pa, pb string
+		pa, a = versionPiece(a)
+		pb, b = versionPiece(b)
+
+		na, errA := strconv.Atoi(pa)
+		nb, errB := strconv.Atoi(pb)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case pa != pb:
+			return strings.Compare(pa, pb)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// versionPiece splits off the leading run of digits or non-digits of s.
+func versionPiece(s string) (string, string) {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	i := 1
+	for i < len(s) && isDigit(s[i]) == isDigit(s[0]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
 func (uhm *UnixHostManager) Shutdown() error {
 	_, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "sudo",
@@ -97,31 +408,119 @@ func (uhm *UnixHostManager) CPUCount() (int, error) {
 
 // Uptime retrieves the system's uptime duration.
 func (uhm *UnixHostManager) Uptime() (time.Duration, error) {
-	output, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "uptime",
-		Args:    []string{"-p"},
-	})
+	output, err := uhm.CommandManager.Run(context.TODO(), procUptimeConfig)
 	if err != nil {
 		return 0, err
 	}
 
-	// Parse the uptime -p output. This is a naive way; you may want to enhance this parsing.
-	uptimeStr := strings.TrimSpace(output.STDOUT)
-	uptimeStr = strings.TrimPrefix(uptimeStr, "up ")
-	uptimeArr := strings.Split(uptimeStr, ", ")
-	var totalMinutes int
+	return parseProcUptime(output.STDOUT)
+}
+
+// parseProcUptime parses /proc/uptime, whose first field is the uptime in seconds.
+func parseProcUptime(content string) (time.Duration, error) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return 0, errors.New("unexpected format in /proc/uptime")
+	}
+
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected format in /proc/uptime: %w", err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)).Truncate(10 * time.Millisecond), nil
+}
+
+// LoadAverage retrieves the 1, 5 and 15 minute load averages.
+func (uhm *UnixHostManager) LoadAverage() (LoadAverage, error) {
+	output, err := uhm.CommandManager.Run(context.TODO(), procLoadavgConfig)
+	if err != nil {
+		return LoadAverage{}, err
+	}
+
+	return parseLoadAverage(output.STDOUT)
+}
 
-	for _, s := range uptimeArr {
-		if strings.Contains(s, "hour") {
-			hours, _ := strconv.Atoi(strings.Split(s, " ")[0])
-			totalMinutes += hours * 60
-		} else if strings.Contains(s, "minute") {
-			minutes, _ := strconv.Atoi(strings.Split(s, " ")[0])
-			totalMinutes += minutes
+// parseLoadAverage parses /proc/loadavg.
+func parseLoadAverage(content string) (LoadAverage, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 3 {
+		return LoadAverage{}, errors.New("unexpected format in /proc/loadavg")
+	}
+
+	var loads [3]float64
+	for i := range loads {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return LoadAverage{}, fmt.Errorf("unexpected format in /proc/loadavg: %w", err)
 		}
+		loads[i] = value
+	}
+
+	return LoadAverage{Load1: loads[0], Load5: loads[1], Load15: loads[2]}, nil
+}
+
+// Memory retrieves memory and swap usage.
+func (uhm *UnixHostManager) Memory() (MemoryStats, error) {
+	output, err := uhm.CommandManager.Run(context.TODO(), procMeminfoConfig)
+	if err != nil {
+		return MemoryStats{}, err
+	}
+
+	return parseMemoryStats(output.STDOUT)
+}
+
+// parseMemoryStats derives memory and swap usage from /proc/meminfo.
+func parseMemoryStats(content string) (MemoryStats, error) {
+	values := make(map[string]int64)
+	for _, line := range strings.Split(content, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		parts := strings.Fields(value)
+		if len(parts) == 0 {
+			continue
+		}
+		kbValue, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[key] = kbValue * 1024
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok || total == 0 {
+		return MemoryStats{}, errors.New("could not find MemTotal in /proc/meminfo")
+	}
+
+	available, ok := values["MemAvailable"]
+	if !ok {
+		// Kernels before 3.14 have no MemAvailable, so estimate it the way free(1) used to.
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+
+	stats := MemoryStats{
+		Total:     total,
+		Available: available,
+		Used:      total - available,
+		Free:      values["MemFree"],
+		Cached:    values["Cached"] + values["Buffers"] + values["SReclaimable"],
+		SwapTotal: values["SwapTotal"],
+		SwapUsed:  values["SwapTotal"] - values["SwapFree"],
 	}
+	stats.UsedPercent = percent(stats.Used, stats.Total)
+	stats.AvailablePercent = percent(stats.Available, stats.Total)
+	stats.SwapUsedPercent = percent(stats.SwapUsed, stats.SwapTotal)
 
-	return time.Duration(totalMinutes) * time.Minute, nil
+	return stats, nil
+}
+
+func percent(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
 }
 
 // FreeMemory retrieves the amount of free memory in bytes.
@@ -134,28 +533,7 @@ func (uhm *UnixHostManager) FreeMemory() (int64, error) {
 		return 0, err
 	}
 
-	// Parse the /proc/meminfo content.
-	lines := strings.Split(output.STDOUT, "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "MemAvailable:") {
-			// Extract the value and convert to bytes.
-			// Assumes that the value in /proc/meminfo is in kilobytes (KB).
-			parts := strings.Fields(line)
-			if len(parts) < 2 {
-				return 0, errors.New("unexpected format in /proc/meminfo")
-			}
-
-			kbValue, err := strconv.ParseInt(parts[1], 10, 64)
-			if err != nil {
-				return 0, err
-			}
-
-			// Convert KB to bytes
-			return kbValue * 1024, nil
-		}
-	}
-
-	return 0, errors.New("could not find MemAvailable in /proc/meminfo")
+	return parseMeminfo(output.STDOUT, "MemAvailable")
 }
 
 // TotalMemory retrieves the total amount of memory in bytes.
@@ -168,10 +546,14 @@ func (uhm *UnixHostManager) TotalMemory() (int64, error) {
 		return 0, err
 	}
 
-	// Parse the /proc/meminfo content.
-	lines := strings.Split(output.STDOUT, "\n")
+	return parseMeminfo(output.STDOUT, "MemTotal")
+}
+
+// parseMeminfo returns the value of key in /proc/meminfo content, in bytes.
+func parseMeminfo(content, key string) (int64, error) {
+	lines := strings.Split(content, "\n")
 	for _, line := range lines {
-		if strings.HasPrefix(line, "MemTotal:") {
+		if strings.HasPrefix(line, key+":") {
 			// Extract the value and convert to bytes.
 			// Assumes that the value in /proc/meminfo is in kilobytes (KB).
 			parts := strings.Fields(line)
@@ -189,54 +571,351 @@ func (uhm *UnixHostManager) TotalMemory() (int64, error) {
 		}
 	}
 
-	return 0, errors.New("could not find MemTotal in /proc/meminfo")
+	return 0, fmt.Errorf("could not find %s in /proc/meminfo", key)
 }
 
-// CPUUsage retrieves the CPU usage percentage.
+// CPUUsage retrieves the CPU usage percentage across all cores.
 func (uhm *UnixHostManager) CPUUsage() (float64, error) {
-	// Using vmstat to get CPU idle time. Parsing the 15th column for idle percentage.
-	output, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "vmstat",
-		Args:    []string{"1", "2"},
-	})
+	output, err := uhm.CommandManager.Run(context.TODO(), procStatConfig)
 	if err != nil {
 		return 0, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(output.STDOUT), "\n")
-	if len(lines) < 3 {
-		return 0, errors.New("unexpected output from vmstat")
+	total, _, err := uhm.cpuStats(output.STDOUT)
+	if err != nil {
+		return 0, err
 	}
 
-	fields := strings.Fields(lines[2])
-	if len(fields) < 15 {
-		return 0, errors.New("unexpected number of columns in vmstat output")
+	return total.Usage, nil
+}
+
+// cpuTimes are the cumulative clock ticks of one cpu line in /proc/stat.
+type cpuTimes struct {
+	name                                                  string
+	user, nice, system, idle, iowait, irq, softirq, steal uint64
+}
+
+// since returns the increase of a counter, treating a counter that went
+// backwards, as iowait can on some kernels, as unchanged.
+func since(current, previous uint64) uint64 {
+	if current < previous {
+		return 0
 	}
+	return current - previous
+}
 
-	idle, err := strconv.ParseFloat(fields[14], 64)
+func (t cpuTimes) total() uint64 {
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+// cpuStats compares the given /proc/stat content with the previous sample.
+// Without a previous sample, or when no time has passed since it, a second
+// sample is taken after cpuSampleInterval.
+func (uhm *UnixHostManager) cpuStats(stat string) (CPUStat, []CPUStat, error) {
+	current, err := parseProcStat(stat)
 	if err != nil {
-		return 0, err
+		return CPUStat{}, nil, err
+	}
+
+	uhm.mu.Lock()
+	previous := uhm.cpuSample
+	uhm.cpuSample = current
+	uhm.mu.Unlock()
+
+	if len(previous) == 0 || previous[0].total() == current[0].total() {
+		time.Sleep(cpuSampleInterval)
+
+		output, err := uhm.CommandManager.Run(context.TODO(), procStatConfig)
+		if err != nil {
+			return CPUStat{}, nil, err
+		}
+
+		previous = current
+		if current, err = parseProcStat(output.STDOUT); err != nil {
+			return CPUStat{}, nil, err
+		}
+
+		uhm.mu.Lock()
+		uhm.cpuSample = current
+		uhm.mu.Unlock()
 	}
 
-	return 100.0 - idle, nil
+	total, cores := cpuUsage(previous, current)
+	return total, cores, nil
 }
 
-// Processes retrieves a list of running processes.
-func (uhm *UnixHostManager) Processes() ([]string, error) {
-	// Using ps command to get a list of processes.
-	output, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "ps",
-		Args:    []string{"-e"},
+// parseProcStat returns the cpu lines of /proc/stat, the aggregate first.
+func parseProcStat(content string) ([]cpuTimes, error) {
+	var samples []cpuTimes
+
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+
+		var values [8]uint64
+		for i := range values {
+			value, err := strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected format in /proc/stat: %q", line)
+			}
+			values[i] = value
+		}
+
+		samples = append(samples, cpuTimes{
+			name: fields[0],
+			user: values[0], nice: values[1], system: values[2], idle: values[3],
+			iowait: values[4], irq: values[5], softirq: values[6], steal: values[7],
+		})
+	}
+
+	if len(samples) == 0 || samples[0].name != "cpu" {
+		return nil, errors.New("could not find cpu totals in /proc/stat")
+	}
+
+	return samples, nil
+}
+
+// cpuUsage turns two /proc/stat samples into percentages. Cores missing from
+// the previous sample, for example ones brought online in between, are skipped.
+func cpuUsage(previous, current []cpuTimes) (CPUStat, []CPUStat) {
+	byName := make(map[string]cpuTimes, len(previous))
+	for _, p := range previous {
+		byName[p.name] = p
+	}
+
+	var stats []CPUStat
+	for _, c := range current {
+		p, ok := byName[c.name]
+		if !ok {
+			continue
+		}
+
+		stat := CPUStat{Name: c.name}
+		if elapsed := float64(since(c.total(), p.total())); elapsed > 0 {
+			idle := since(c.idle+c.iowait, p.idle+p.iowait)
+			stat.Usage = (elapsed - float64(idle)) / elapsed * 100
+			stat.User = float64(since(c.user+c.nice, p.user+p.nice)) / elapsed * 100
+			stat.System = float64(since(c.system+c.irq+c.softirq, p.system+p.irq+p.softirq)) / elapsed * 100
+			stat.IOWait = float64(since(c.iowait, p.iowait)) / elapsed * 100
+			stat.Steal = float64(since(c.steal, p.steal)) / elapsed * 100
+		}
+		stats = append(stats, stat)
+	}
+
+	if len(stats) == 0 || stats[0].Name != "cpu" {
+		return CPUStat{Name: "cpu"}, stats
+	}
+	return stats[0], stats[1:]
+}
+
+// psConfig lists every process with fixed columns ahead of the command line,
+// which may contain spaces and so has to come last. The C locale keeps %CPU
+// formatted with a decimal point.
+var psConfig = cm.CommandConfig{
+	Command: "ps",
+	Args:    []string{"-eo", "pid=,ppid=,user=,stat=,pcpu=,rss=,etime=,args="},
+	Env:     []string{"LC_ALL=C"},
+}
+
+// signalName matches signal names as accepted by kill -s, without the SIG prefix.
+var signalName = regexp.MustCompile(`^[A-Z][A-Z0-9+-]*$`)
+
+// Processes retrieves the running processes.
+func (uhm *UnixHostManager) Processes() ([]Process, error) {
+	output, err := uhm.CommandManager.Run(context.TODO(), psConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProcesses(output.STDOUT, time.Now())
+}
+
+// FindProcesses retrieves the running processes matching filter.
+func (uhm *UnixHostManager) FindProcesses(filter ProcessFilter) ([]Process, error) {
+	var pattern *regexp.Regexp
+	if filter.Pattern != "" {
+		var err error
+		pattern, err = regexp.Compile(filter.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process pattern: %w", err)
+		}
+	}
+
+	processes, err := uhm.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Process
+	for _, p := range processes {
+		if filter.User != "" && p.User != filter.User {
+			continue
+		}
+		if filter.State != "" && !strings.HasPrefix(p.State, filter.State) {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(p.Command) {
+			continue
+		}
+		if p.CPUPercent < filter.MinCPU || p.RSS < filter.MinRSS {
+			continue
+		}
+		matches = append(matches, p)
+	}
+
+	return matches, nil
+}
+
+// Signal sends the named signal to pid.
+func (uhm *UnixHostManager) Signal(pid int, sig string) error {
+	sig = strings.TrimPrefix(strings.ToUpper(sig), "SIG")
+	if !signalName.MatchString(sig) {
+		return fmt.Errorf("invalid signal name: %q", sig)
+	}
+	if pid <= 0 {
+		return fmt.Errorf("invalid pid: %d", pid)
+	}
+
+	result, err := uhm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "kill",
+		Args:    []string{"-s", sig, strconv.Itoa(pid)},
+		Sudo:    true,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to send %s to %d: %w: %s", sig, pid, err, strings.TrimSpace(result.STDERR))
+	}
+	return nil
+}
+
+// KillByName sends TERM to every process whose command line matches pattern
+// and returns the processes that were signalled.
+func (uhm *UnixHostManager) KillByName(pattern string) ([]Process, error) {
+	if pattern == "" {
+		return nil, errors.New("a pattern is required")
+	}
+
+	matches, err := uhm.FindProcesses(ProcessFilter{Pattern: pattern})
+	if err != nil {
+		return nil, err
+	}
+
+	var killed []Process
+	var result error
+	for _, p := range matches {
+		if err := uhm.Signal(p.PID, "TERM"); err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+		killed = append(killed, p)
+	}
+
+	return killed, result
+}
+
+// ProcessTree returns pid and all of its descendants.
+func (uhm *UnixHostManager) ProcessTree(pid int) (*ProcessNode, error) {
+	processes, err := uhm.Processes()
 	if err != nil {
 		return nil, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(output.STDOUT), "\n")
-	// Removing the header line from ps output
-	if len(lines) > 0 {
-		lines = lines[1:]
+	return buildProcessTree(processes, pid)
+}
+
+// buildProcessTree links processes to their parents and returns the subtree rooted at pid.
+func buildProcessTree(processes []Process, pid int) (*ProcessNode, error) {
+	nodes := make(map[int]*ProcessNode, len(processes))
+	for _, p := range processes {
+		nodes[p.PID] = &ProcessNode{Process: p}
+	}
+
+	// Walk the slice rather than the map so children keep ps order.
+	for _, p := range processes {
+		if parent, ok := nodes[p.PPID]; ok && p.PPID != p.PID {
+			parent.Children = append(parent.Children, nodes[p.PID])
+		}
+	}
+
+	root, ok := nodes[pid]
+	if !ok {
+		return nil, fmt.Errorf("no process with pid %d", pid)
+	}
+	return root, nil
+}
+
+// parseProcesses parses the output of psConfig. Start times are derived from
+// the elapsed time, which unlike lstart does not depend on the host's time zone.
+func parseProcesses(output string, now time.Time) ([]Process, error) {
+	var processes []Process
+
+	for _, line := range strings.Split(output, "\n") {
+		fields, command := cm.CutFields(line, 7)
+		if len(fields) < 7 {
+			continue
+		}
+
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected ps output: %q", line)
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected ps output: %q", line)
+		}
+		cpu, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected ps output: %q", line)
+		}
+		rss, err := strconv.ParseInt(fields[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected ps output: %q", line)
+		}
+		elapsed, err := parseElapsed(fields[6])
+		if err != nil {
+			return nil, err
+		}
+
+		processes = append(processes, Process{
+			PID:        pid,
+			PPID:       ppid,
+			User:       fields[2],
+			State:      fields[3],
+			CPUPercent: cpu,
+			RSS:        rss * 1024,
+			StartTime:  now.Add(-elapsed).Truncate(time.Second),
+			Command:    command,
+		})
+	}
+
+	return processes, nil
+}
+
+// parseElapsed parses the [[dd-]hh:]mm:ss format of the ps etime column.
+func parseElapsed(etime string) (time.Duration, error) {
+	var days int
+	if d, rest, found := strings.Cut(etime, "-"); found {
+		var err error
+		if days, err = strconv.Atoi(d); err != nil {
+			return 0, fmt.Errorf("unexpected elapsed time: %q", etime)
+		}
+		etime = rest
+	}
+
+	parts := strings.Split(etime, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("unexpected elapsed time: %q", etime)
+	}
+
+	var seconds int
+	for _, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected elapsed time: %q", etime)
+		}
+		seconds = seconds*60 + value
 	}
 
-	return lines, nil
+	return time.Duration(days*86400+seconds) * time.Second, nil
 }
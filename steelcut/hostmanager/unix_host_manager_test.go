@@ -2,41 +2,66 @@ package hostmanager
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
 )
 
 type MockCommandManager struct {
-	Outputs map[string]string
-	Err     error
+	Outputs   map[string]string
+	ExitCodes map[string]int
+	Err       error
+	Configs   []cm.CommandConfig
 }
 
-func (m *MockCommandManager) getMockOutput(command string) cm.CommandResult {
-	if output, exists := m.Outputs[command]; exists {
+// getMockOutput looks up the full command line first, so commands such as
+// cat can be given different output per file, then the command name alone.
+func (m *MockCommandManager) getMockOutput(config cm.CommandConfig) cm.CommandResult {
+	commandLine := strings.Join(append([]string{config.Command}, config.Args...), " ")
+	if exitCode, exists := m.ExitCodes[commandLine]; exists {
+		return cm.CommandResult{STDOUT: m.Outputs[commandLine], ExitCode: exitCode}
+	}
+	if output, exists := m.Outputs[commandLine]; exists {
+		return cm.CommandResult{STDOUT: output}
+	}
+	if output, exists := m.Outputs[config.Command]; exists {
 		return cm.CommandResult{STDOUT: output}
 	}
 	return cm.CommandResult{}
 }
 
 func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
-	return m.getMockOutput(config.Command), m.Err
+	return m.getMockOutput(config), m.Err
 }
 
 func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
-	return m.getMockOutput(config.Command), m.Err
+	return m.getMockOutput(config), m.Err
 }
 
 func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
-	return m.getMockOutput(config.Command), m.Err
+	m.Configs = append(m.Configs, config)
+	return m.getMockOutput(config), m.Err
+}
+
+// commands returns the command lines run, in order.
+func (m *MockCommandManager) commands() []string {
+	lines := make([]string, len(m.Configs))
+	for i, config := range m.Configs {
+		lines[i] = strings.Join(append([]string{config.Command}, config.Args...), " ")
+	}
+	return lines
 }
 
 func TestInfo(t *testing.T) {
 	mockCmd := &MockCommandManager{
 		Outputs: map[string]string{
-			"hostname": "test-hostname\n",
-			"nproc":    "4\n",
-			"uname":    "test-version",
+			"hostname":         "test-hostname\n",
+			"nproc":            "4\n",
+			"uname":            "test-version",
+			"cat /proc/uptime": "1209600.52 4000000.00\n",
 		},
 		Err: nil,
 	}
@@ -44,10 +69,13 @@ func TestInfo(t *testing.T) {
 		CommandManager: mockCmd,
 	}
 
-	_, err := hostManager.Info()
+	info, err := hostManager.Info()
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
+	if info.Uptime != "336h0m0.52s" {
+		t.Errorf("Expected two weeks of uptime, got: %v", info.Uptime)
+	}
 }
 
 func TestHostname(t *testing.T) {
@@ -89,3 +117,265 @@ func TestCPUCount(t *testing.T) {
 		t.Errorf("Expected 4 CPU cores, got: %v", cpuCount)
 	}
 }
+
+const psOutput = `    1     0 root     Ss    0.0 12000    3-01:02:03 /sbin/init splash
+  812     1 root     Ss    0.0  8000       01:00:00 /usr/sbin/sshd -D
+ 4100   812 alice    Ss    0.1  5000          10:05 sshd: alice@pts/0
+ 4101  4100 alice    R+   92.5 900000         10:00 python3  train.py --epochs 10
+ 4200     1 www-data S     1.5 64000       02:00:00 nginx: worker process
+`
+
+func TestParseProcesses(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	processes, err := parseProcesses(psOutput, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(processes) != 5 {
+		t.Fatalf("Expected 5 processes, got: %d", len(processes))
+	}
+
+	p := processes[3]
+	if p.PID != 4101 || p.PPID != 4100 || p.User != "alice" || p.State != "R+" || p.CPUPercent != 92.5 || p.RSS != 900000*1024 {
+		t.Errorf("Unexpected process: %+v", p)
+	}
+	if p.Command != "python3  train.py --epochs 10" {
+		t.Errorf("Expected the command line to keep its spacing, got: %q", p.Command)
+	}
+	if !p.StartTime.Equal(now.Add(-10 * time.Minute)) {
+		t.Errorf("Unexpected start time: %v", p.StartTime)
+	}
+	if !processes[0].StartTime.Equal(now.Add(-(3*24*time.Hour + time.Hour + 2*time.Minute + 3*time.Second))) {
+		t.Errorf("Unexpected start time: %v", processes[0].StartTime)
+	}
+}
+
+func TestFindProcesses(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"ps": psOutput}}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	processes, err := hostManager.FindProcesses(ProcessFilter{User: "alice", Pattern: `^python`})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(processes) != 1 || processes[0].PID != 4101 {
+		t.Errorf("Unexpected processes: %+v", processes)
+	}
+
+	if _, err := hostManager.FindProcesses(ProcessFilter{Pattern: "("}); err == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
+
+func TestProcessTree(t *testing.T) {
+	processes, _ := parseProcesses(psOutput, time.Now())
+
+	tree, err := buildProcessTree(processes, 812)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].PID != 4100 || len(tree.Children[0].Children) != 1 {
+		t.Errorf("Unexpected tree: %+v", tree)
+	}
+
+	if _, err := buildProcessTree(processes, 9999); err == nil {
+		t.Errorf("Expected an error for a missing pid")
+	}
+}
+
+func TestTopByMemory(t *testing.T) {
+	processes, _ := parseProcesses(psOutput, time.Now())
+
+	top := TopByMemory(processes, 2)
+	if len(top) != 2 || top[0].PID != 4101 || top[1].PID != 4200 {
+		t.Errorf("Unexpected top consumers: %+v", top)
+	}
+}
+
+func TestSignalRejectsInvalidName(t *testing.T) {
+	hostManager := UnixHostManager{CommandManager: &MockCommandManager{}}
+
+	if err := hostManager.Signal(1234, "TERM; reboot"); err == nil {
+		t.Errorf("Expected an error for an invalid signal name")
+	}
+}
+
+const procStatBefore = `cpu  1000 0 500 8000 100 0 0 0 0 0
+cpu0 500 0 250 4000 50 0 0 0 0 0
+cpu1 500 0 250 4000 50 0 0 0 0 0
+intr 12345
+`
+
+const procStatAfter = `cpu  1300 0 600 8400 100 0 0 0 0 0
+cpu0 550 0 300 4300 50 0 0 0 0 0
+cpu1 750 0 300 4100 50 0 0 0 0 0
+intr 12400
+`
+
+func TestMetrics(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs: map[string]string{
+			"cat /proc/stat":    procStatAfter,
+			"cat /proc/loadavg": "0.52 0.58 0.59 1/467 12345\n",
+			"cat /proc/meminfo": "MemTotal:        8000000 kB\nMemFree:          1000000 kB\nMemAvailable:     6000000 kB\nBuffers:           100000 kB\nCached:           2000000 kB\nSReclaimable:      200000 kB\nSwapTotal:        2000000 kB\nSwapFree:         1500000 kB\n",
+			"cat /proc/uptime":  "90061.00 170000.00\n",
+		},
+	}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+	hostManager.cpuSample, _ = parseProcStat(procStatBefore)
+
+	metrics, err := hostManager.Metrics()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if metrics.CPU.Usage != 50 || metrics.CPU.User != 37.5 || metrics.CPU.System != 12.5 {
+		t.Errorf("Unexpected CPU usage: %+v", metrics.CPU)
+	}
+	if len(metrics.Cores) != 2 || metrics.Cores[0].Usage != 25 || metrics.Cores[1].Usage != 75 {
+		t.Errorf("Unexpected per-core usage: %+v", metrics.Cores)
+	}
+	if metrics.Load != (LoadAverage{Load1: 0.52, Load5: 0.58, Load15: 0.59}) {
+		t.Errorf("Unexpected load average: %+v", metrics.Load)
+	}
+	if metrics.Memory.UsedPercent != 25 || metrics.Memory.Cached != 2300000*1024 || metrics.Memory.SwapUsedPercent != 25 {
+		t.Errorf("Unexpected memory stats: %+v", metrics.Memory)
+	}
+	if metrics.Uptime != 25*time.Hour+time.Minute+time.Second {
+		t.Errorf("Unexpected uptime: %v", metrics.Uptime)
+	}
+}
+
+func TestParseMemoryStatsWithoutMemAvailable(t *testing.T) {
+	stats, err := parseMemoryStats("MemTotal: 1000 kB\nMemFree: 200 kB\nBuffers: 100 kB\nCached: 200 kB\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stats.Available != 500*1024 || stats.UsedPercent != 50 {
+		t.Errorf("Unexpected memory stats: %+v", stats)
+	}
+}
+
+// rebootingCommandManager answers boot ID probes from a script of responses,
+// with an empty response standing for a host that cannot be reached.
+type rebootingCommandManager struct {
+	MockCommandManager
+	bootIDs  []string
+	rebooted bool
+}
+
+func (m *rebootingCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	switch {
+	case config.Command == "sh":
+		m.rebooted = true
+		return cm.CommandResult{}, nil
+	case config.Command == "cat" && len(m.bootIDs) > 0:
+		bootID := m.bootIDs[0]
+		if len(m.bootIDs) > 1 {
+			m.bootIDs = m.bootIDs[1:]
+		}
+		if bootID == "" {
+			return cm.CommandResult{}, errors.New("connection refused")
+		}
+		return cm.CommandResult{STDOUT: bootID + "\n"}, nil
+	}
+	return cm.CommandResult{}, errors.New("unexpected command")
+}
+
+func TestRebootAndWait(t *testing.T) {
+	defer func(interval time.Duration) { rebootPollInterval = interval }(rebootPollInterval)
+	rebootPollInterval = time.Millisecond
+
+	mockCmd := &rebootingCommandManager{bootIDs: []string{"old", "old", "", "", "new"}}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	checks := 0
+	err := hostManager.RebootAndWait(context.Background(), time.Second, func(ctx context.Context) error {
+		checks++
+		if checks < 3 {
+			return errors.New("service not up yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !mockCmd.rebooted || checks != 3 {
+		t.Errorf("Expected a reboot and the check to be retried, got rebooted=%v checks=%d", mockCmd.rebooted, checks)
+	}
+}
+
+func TestRebootAndWaitBootIDUnchanged(t *testing.T) {
+	defer func(interval time.Duration) { rebootPollInterval = interval }(rebootPollInterval)
+	rebootPollInterval = time.Millisecond
+
+	mockCmd := &rebootingCommandManager{bootIDs: []string{"old"}}
+	hostManager := UnixHostManager{CommandManager: mockCmd}
+
+	err := hostManager.RebootAndWait(context.Background(), 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not reboot") {
+		t.Errorf("Expected the host not to reboot, got: %v", err)
+	}
+}
+
+func TestRebootRequired(t *testing.T) {
+	tests := []struct {
+		name      string
+		outputs   map[string]string
+		exitCodes map[string]int
+		reasons   []string
+	}{
+		{
+			name: "debian flag file",
+			outputs: map[string]string{
+				"cat /var/run/reboot-required":      "*** System restart required ***\n",
+				"cat /var/run/reboot-required.pkgs": "linux-image-6.1.0-18-amd64\nlibc6\nlibc6\n",
+				"uname -r":                          "6.1.0-18-amd64\n",
+				"ls -1 /lib/modules":                "6.1.0-18-amd64\n",
+			},
+			exitCodes: map[string]int{"needs-restarting -r": 127},
+			reasons:   []string{"updated packages: linux-image-6.1.0-18-amd64, libc6"},
+		},
+		{
+			name: "needs-restarting",
+			outputs: map[string]string{
+				"needs-restarting -r": "Core libraries or services have been updated since boot-up:\n  * kernel\n  * glibc\n\nReboot is required to fully utilize these updates.\n",
+				"uname -r":            "5.14.0-362.el9.x86_64\n",
+				"ls -1 /lib/modules":  "5.14.0-362.el9.x86_64\n5.14.0-427.el9.x86_64\n",
+			},
+			exitCodes: map[string]int{"cat /var/run/reboot-required": 1, "cat /var/run/reboot-required.pkgs": 1, "needs-restarting -r": 1},
+			reasons:   []string{"updated since boot: kernel", "updated since boot: glibc"},
+		},
+		{
+			name: "newer kernel installed",
+			outputs: map[string]string{
+				"uname -r":           "6.6.9-arch1-1\n",
+				"ls -1 /lib/modules": "6.6.10-arch1-1\n6.6.9-arch1-1\n",
+			},
+			exitCodes: map[string]int{"cat /var/run/reboot-required": 1, "cat /var/run/reboot-required.pkgs": 1, "needs-restarting -r": 127},
+			reasons:   []string{"running kernel 6.6.9-arch1-1, newest installed is 6.6.10-arch1-1"},
+		},
+		{
+			name: "up to date",
+			outputs: map[string]string{
+				"uname -r":           "6.6.10-arch1-1\n",
+				"ls -1 /lib/modules": "6.6.10-arch1-1\n6.6.9-arch1-1\n",
+			},
+			exitCodes: map[string]int{"cat /var/run/reboot-required": 1, "cat /var/run/reboot-required.pkgs": 1, "needs-restarting -r": 127},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostManager := UnixHostManager{CommandManager: &MockCommandManager{Outputs: tt.outputs, ExitCodes: tt.exitCodes}}
+
+			required, reasons, err := hostManager.RebootRequired()
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if required != (len(tt.reasons) > 0) || strings.Join(reasons, "|") != strings.Join(tt.reasons, "|") {
+				t.Errorf("Expected %v, got %v %v", tt.reasons, required, reasons)
+			}
+		})
+	}
+}
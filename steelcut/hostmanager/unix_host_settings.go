@@ -0,0 +1,484 @@
+package hostmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+const (
+	hostsPath            = "/etc/hosts"
+	hostnamePath         = "/etc/hostname"
+	zoneinfoDir          = "/usr/share/zoneinfo"
+	timesyncdDropInPath  = "/etc/systemd/timesyncd.conf.d/steelcut.conf"
+	localHostnameAddress = "127.0.1.1" // the Debian convention for the host's own name
+)
+
+var (
+	hostnameLabel = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+	timezoneName  = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+	localeName    = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+)
+
+// chronyConfigPaths are where chrony's configuration lives on Debian and on RHEL, in that order.
+var chronyConfigPaths = []string{"/etc/chrony/chrony.conf", "/etc/chrony.conf"}
+
+// SetHostname sets the static and transient hostname and makes /etc/hosts
+// resolve the new name, replacing the old one wherever it appears.
+func (uhm *UnixHostManager) SetHostname(name string) error {
+	if err := validateHostname(name); err != nil {
+		return err
+	}
+
+	current, err := uhm.Hostname()
+	if err != nil {
+		return err
+	}
+	static, _ := uhm.output(cm.CommandConfig{Command: "cat", Args: []string{hostnamePath}})
+
+	if current != name || strings.TrimSpace(static) != name {
+		systemd, err := uhm.hasSystemd()
+		if err != nil {
+			return err
+		}
+		if systemd {
+			if _, err := uhm.run(cm.CommandConfig{Command: "hostnamectl", Args: []string{"set-hostname", name}, Sudo: true}); err != nil {
+				return fmt.Errorf("failed to set hostname: %w", err)
+			}
+		} else {
+			if err := uhm.writeFile(hostnamePath, name+"\n"); err != nil {
+				return err
+			}
+			if _, err := uhm.run(cm.CommandConfig{Command: "hostname", Args: []string{name}, Sudo: true}); err != nil {
+				return fmt.Errorf("failed to set hostname: %w", err)
+			}
+		}
+	}
+
+	hosts, err := uhm.output(cm.CommandConfig{Command: "cat", Args: []string{hostsPath}})
+	if err != nil {
+		return err
+	}
+	if updated, changed := updateEtcHosts(hosts, current, name); changed {
+		return uhm.writeFile(hostsPath, updated)
+	}
+	return nil
+}
+
+func validateHostname(name string) error {
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("invalid hostname: %q", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !hostnameLabel.MatchString(label) {
+			return fmt.Errorf("invalid hostname: %q", name)
+		}
+	}
+	return nil
+}
+
+// updateEtcHosts renames old to name in the hostname columns of /etc/hosts and,
+// when no line maps name yet, adds one for localHostnameAddress. For a fully
+// qualified name the short name is added as an alias.
+func updateEtcHosts(content, old, name string) (string, bool) {
+	// A fresh host is often called localhost, and those entries must stay.
+	if old == "localhost" || strings.HasPrefix(old, "localhost.") {
+		old = ""
+	}
+
+	short, _, _ := strings.Cut(name, ".")
+	oldShort, _, _ := strings.Cut(old, ".")
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	found := false
+	for i, line := range lines {
+		entry, comment, _ := strings.Cut(line, "#")
+		fields := strings.Fields(entry)
+		if len(fields) < 2 {
+			continue
+		}
+
+		renamed := false
+		for j := 1; j < len(fields); j++ {
+			switch {
+			case old != "" && old != name && fields[j] == old:
+				fields[j] = name
+				renamed = true
+			case oldShort != "" && oldShort != short && fields[j] == oldShort:
+				fields[j] = short
+				renamed = true
+			}
+			if fields[j] == name {
+				found = true
+			}
+		}
+
+		if renamed {
+			lines[i] = strings.Join(fields, "\t")
+			if comment != "" {
+				lines[i] += " #" + comment
+			}
+		}
+	}
+
+	if !found {
+		entry := localHostnameAddress + "\t" + name
+		if short != name {
+			entry += "\t" + short
+		}
+		lines = append(lines, entry)
+	}
+
+	updated := strings.Join(lines, "\n") + "\n"
+	return updated, updated != content
+}
+
+// SetTimezone sets the system timezone, such as Europe/Berlin.
+func (uhm *UnixHostManager) SetTimezone(timezone string) error {
+	if !timezoneName.MatchString(timezone) || strings.Contains(timezone, "..") {
+		return fmt.Errorf("invalid timezone: %q", timezone)
+	}
+
+	zoneFile := zoneinfoDir + "/" + timezone
+	if known, err := uhm.probe(cm.CommandConfig{Command: "test", Args: []string{"-f", zoneFile}}); err != nil {
+		return err
+	} else if !known {
+		return fmt.Errorf("unknown timezone: %s", timezone)
+	}
+
+	if current, _ := uhm.Timezone(); current == timezone {
+		return nil
+	}
+
+	systemd, err := uhm.hasSystemd()
+	if err != nil {
+		return err
+	}
+	if systemd {
+		if _, err := uhm.run(cm.CommandConfig{Command: "timedatectl", Args: []string{"set-timezone", timezone}, Sudo: true}); err != nil {
+			return fmt.Errorf("failed to set timezone: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := uhm.run(cm.CommandConfig{Command: "ln", Args: []string{"-sf", zoneFile, "/etc/localtime"}, Sudo: true}); err != nil {
+		return fmt.Errorf("failed to set timezone: %w", err)
+	}
+	// Debian also records the zone name in /etc/timezone.
+	debian, err := uhm.probe(cm.CommandConfig{Command: "test", Args: []string{"-f", "/etc/timezone"}})
+	if err != nil || !debian {
+		return err
+	}
+	return uhm.writeFile("/etc/timezone", timezone+"\n")
+}
+
+// Timezone returns the system timezone, read from timedatectl or the /etc/localtime symlink.
+func (uhm *UnixHostManager) Timezone() (string, error) {
+	systemd, err := uhm.hasSystemd()
+	if err != nil {
+		return "", err
+	}
+	if systemd {
+		if timezone, err := uhm.output(cm.CommandConfig{Command: "timedatectl", Args: []string{"show", "-p", "Timezone", "--value"}}); err == nil && strings.TrimSpace(timezone) != "" {
+			return strings.TrimSpace(timezone), nil
+		}
+	}
+
+	target, err := uhm.output(cm.CommandConfig{Command: "readlink", Args: []string{"/etc/localtime"}})
+	if err != nil {
+		return "", err
+	}
+	_, timezone, found := strings.Cut(strings.TrimSpace(target), "zoneinfo/")
+	if !found {
+		return "", fmt.Errorf("unexpected /etc/localtime target: %s", strings.TrimSpace(target))
+	}
+	return timezone, nil
+}
+
+// SetLocale sets the system-wide LANG, such as en_US.UTF-8.
+func (uhm *UnixHostManager) SetLocale(locale string) error {
+	if !localeName.MatchString(locale) {
+		return fmt.Errorf("invalid locale: %q", locale)
+	}
+
+	systemd, err := uhm.hasSystemd()
+	if err != nil {
+		return err
+	}
+	if systemd {
+		status, err := uhm.output(cm.CommandConfig{Command: "localectl", Args: []string{"status"}})
+		if err == nil && parseLocalectlLang(status) == locale {
+			return nil
+		}
+		if _, err := uhm.run(cm.CommandConfig{Command: "localectl", Args: []string{"set-locale", "LANG=" + locale}, Sudo: true}); err != nil {
+			return fmt.Errorf("failed to set locale: %w", err)
+		}
+		return nil
+	}
+
+	// RHEL and Arch keep the locale in /etc/locale.conf, Debian in /etc/default/locale.
+	path := "/etc/default/locale"
+	if localeConf, err := uhm.probe(cm.CommandConfig{Command: "test", Args: []string{"-f", "/etc/locale.conf"}}); err != nil {
+		return err
+	} else if localeConf {
+		path = "/etc/locale.conf"
+	}
+
+	content, _ := uhm.output(cm.CommandConfig{Command: "cat", Args: []string{path}})
+	if updated, changed := setEnvValue(content, "LANG", locale); changed {
+		return uhm.writeFile(path, updated)
+	}
+	return nil
+}
+
+// parseLocalectlLang extracts LANG from the System Locale line of localectl status.
+func parseLocalectlLang(status string) string {
+	for _, line := range strings.Split(status, "\n") {
+		_, value, found := strings.Cut(strings.TrimSpace(line), "System Locale:")
+		if !found {
+			continue
+		}
+		for _, field := range strings.Fields(value) {
+			if lang, found := strings.CutPrefix(field, "LANG="); found {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// setEnvValue sets key=value in a shell style KEY=value file, replacing an
+// existing assignment or appending one.
+func setEnvValue(content, key, value string) (string, bool) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	assignment := key + "=" + value
+	found := false
+	for i, line := range lines {
+		k, _, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok && strings.TrimSpace(k) == key {
+			lines[i] = assignment
+			found = true
+		}
+	}
+	if !found {
+		lines = append(lines, assignment)
+	}
+
+	updated := strings.Join(lines, "\n") + "\n"
+	return updated, updated != content
+}
+
+// chronydProbe looks for chronyd as root. chronyd lives in an sbin directory,
+// which the PATH of an unprivileged SSH session often leaves out, so the usual
+// locations are checked as well.
+var chronydProbe = cm.CommandConfig{
+	Command: "sh",
+	Args:    []string{"-c", "command -v chronyd || test -x /usr/sbin/chronyd || test -x /sbin/chronyd"},
+	Sudo:    true,
+}
+
+// EnsureTimeSync points the host's NTP client at servers and makes sure it is
+// running. chrony is configured when it is installed, systemd-timesyncd otherwise.
+func (uhm *UnixHostManager) EnsureTimeSync(servers []string) error {
+	if len(servers) == 0 {
+		return errors.New("at least one time server is required")
+	}
+	for _, server := range servers {
+		if strings.ContainsAny(server, " \t\n#") || server == "" {
+			return fmt.Errorf("invalid time server: %q", server)
+		}
+	}
+
+	if chrony, err := uhm.probe(chronydProbe); err != nil {
+		return err
+	} else if chrony {
+		return uhm.ensureChrony(servers)
+	}
+	if systemd, err := uhm.hasSystemd(); err != nil {
+		return err
+	} else if systemd {
+		return uhm.ensureTimesyncd(servers)
+	}
+	return errors.New("no supported time sync daemon found, install chrony")
+}
+
+func (uhm *UnixHostManager) ensureChrony(servers []string) error {
+	path := chronyConfigPaths[len(chronyConfigPaths)-1]
+	var content string
+	for _, candidate := range chronyConfigPaths {
+		if c, err := uhm.output(cm.CommandConfig{Command: "cat", Args: []string{candidate}}); err == nil {
+			path, content = candidate, c
+			break
+		}
+	}
+
+	if updated, changed := updateChronyServers(content, servers); changed {
+		if err := uhm.writeFile(path, updated); err != nil {
+			return err
+		}
+		// The unit is chrony on Debian and chronyd elsewhere.
+		return uhm.restartService("chrony", "chronyd")
+	}
+	return uhm.startService("chrony", "chronyd")
+}
+
+// updateChronyServers replaces the server and pool lines of a chrony
+// configuration with servers, keeping everything else.
+func updateChronyServers(content string, servers []string) (string, bool) {
+	var kept []string
+	inserted := false
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && (fields[0] == "server" || fields[0] == "pool") {
+			if !inserted {
+				for _, server := range servers {
+					kept = append(kept, "server "+server+" iburst")
+				}
+				inserted = true
+			}
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !inserted {
+		for _, server := range servers {
+			kept = append(kept, "server "+server+" iburst")
+		}
+	}
+
+	updated := strings.TrimLeft(strings.Join(kept, "\n"), "\n") + "\n"
+	return updated, updated != content
+}
+
+func (uhm *UnixHostManager) ensureTimesyncd(servers []string) error {
+	desired := "[Time]\nNTP=" + strings.Join(servers, " ") + "\n"
+
+	current, _ := uhm.output(cm.CommandConfig{Command: "cat", Args: []string{timesyncdDropInPath}})
+	if current != desired {
+		if _, err := uhm.run(cm.CommandConfig{Command: "mkdir", Args: []string{"-p", "/etc/systemd/timesyncd.conf.d"}, Sudo: true}); err != nil {
+			return err
+		}
+		if err := uhm.writeFile(timesyncdDropInPath, desired); err != nil {
+			return err
+		}
+		if err := uhm.restartService("systemd-timesyncd"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := uhm.run(cm.CommandConfig{Command: "timedatectl", Args: []string{"set-ntp", "true"}, Sudo: true}); err != nil {
+		return fmt.Errorf("failed to enable NTP: %w", err)
+	}
+	return nil
+}
+
+// restartService restarts the first of names that exists, through systemd or
+// the service and rc-service wrappers on other init systems.
+func (uhm *UnixHostManager) restartService(names ...string) error {
+	return uhm.serviceAction("restart", names)
+}
+
+// startService starts the first of names that exists, if it is not already running.
+func (uhm *UnixHostManager) startService(names ...string) error {
+	return uhm.serviceAction("start", names)
+}
+
+func (uhm *UnixHostManager) serviceAction(action string, names []string) error {
+	var err error
+	for _, name := range names {
+		script := fmt.Sprintf("systemctl %[1]s %[2]s 2>/dev/null || service %[2]s %[1]s 2>/dev/null || rc-service %[2]s %[1]s", action, cm.ShellQuote(name))
+		if _, err = uhm.run(cm.CommandConfig{Command: "sh", Args: []string{"-c", script}, Sudo: true}); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to %s %s: %w", action, strings.Join(names, " or "), err)
+}
+
+// ClockSkew compares the host's clock with the local one. The host's reading
+// is assumed to have been taken halfway through the round trip, so Offset is
+// accurate to within half of RoundTrip.
+func (uhm *UnixHostManager) ClockSkew() (ClockSkew, error) {
+	start := time.Now()
+	output, err := uhm.output(cm.CommandConfig{Command: "date", Args: []string{"+%s.%N"}})
+	end := time.Now()
+	if err != nil {
+		return ClockSkew{}, err
+	}
+
+	remote, err := parseEpoch(output)
+	if err != nil {
+		return ClockSkew{}, err
+	}
+
+	roundTrip := end.Sub(start)
+	midpoint := start.Add(roundTrip / 2)
+	return ClockSkew{Offset: remote.Sub(midpoint), RoundTrip: roundTrip}, nil
+}
+
+// parseEpoch parses date +%s.%N. BSD date has no %N and prints it literally,
+// leaving whole seconds.
+func parseEpoch(output string) (time.Time, error) {
+	secondsStr, fraction, _ := strings.Cut(strings.TrimSpace(output), ".")
+	seconds, err := strconv.ParseInt(secondsStr, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected date output: %q", output)
+	}
+
+	var nanos int64
+	if fraction != "" && fraction != "N" {
+		fraction = (fraction + "000000000")[:9]
+		if nanos, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("unexpected date output: %q", output)
+		}
+	}
+
+	return time.Unix(seconds, nanos), nil
+}
+
+// hasSystemd reports whether systemd is the running init system, which is
+// what hostnamectl, timedatectl and localectl need to work.
+func (uhm *UnixHostManager) hasSystemd() (bool, error) {
+	return uhm.probe(cm.CommandConfig{Command: "test", Args: []string{"-d", "/run/systemd/system"}})
+}
+
+// probe runs a command that answers a question through its exit status, such
+// as test -f. A non-zero exit means no; only failing to run it is an error.
+func (uhm *UnixHostManager) probe(config cm.CommandConfig) (bool, error) {
+	result, err := uhm.run(config)
+	if result.ExitCode > 0 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// run executes config and treats a non-zero exit code as an error.
+func (uhm *UnixHostManager) run(config cm.CommandConfig) (cm.CommandResult, error) {
+	return cm.RunChecked(context.TODO(), uhm.CommandManager, config, nil)
+}
+
+func (uhm *UnixHostManager) output(config cm.CommandConfig) (string, error) {
+	result, err := uhm.run(config)
+	return result.STDOUT, err
+}
+
+func (uhm *UnixHostManager) writeFile(path, content string) error {
+	if uhm.FileManager == nil {
+		return fmt.Errorf("cannot write %s: no file manager configured", path)
+	}
+	return uhm.FileManager.WriteFileAtomic(path, []byte(content), filemanager.AtomicWriteOptions{})
+}
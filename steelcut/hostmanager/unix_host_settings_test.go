@@ -0,0 +1,222 @@
+package hostmanager
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+func TestUpdateEtcHosts(t *testing.T) {
+	content := "127.0.0.1\tlocalhost\n127.0.1.1\told.example.com old # set by installer\n10.0.0.5 db1\n"
+
+	updated, changed := updateEtcHosts(content, "old.example.com", "web1.example.com")
+	expected := "127.0.0.1\tlocalhost\n127.0.1.1\tweb1.example.com\tweb1 # set by installer\n10.0.0.5 db1\n"
+	if !changed || updated != expected {
+		t.Errorf("Unexpected hosts file:\n%s", updated)
+	}
+
+	if _, changed := updateEtcHosts(updated, "web1.example.com", "web1.example.com"); changed {
+		t.Errorf("Expected no change when the name is already mapped")
+	}
+}
+
+func TestUpdateEtcHostsFromLocalhost(t *testing.T) {
+	content := "127.0.0.1 localhost\n"
+
+	updated, changed := updateEtcHosts(content, "localhost", "web1")
+	if !changed || updated != "127.0.0.1 localhost\n127.0.1.1\tweb1\n" {
+		t.Errorf("Unexpected hosts file:\n%s", updated)
+	}
+}
+
+func TestUpdateChronyServers(t *testing.T) {
+	content := "# Use public servers\npool 2.debian.pool.ntp.org iburst\nserver 10.0.0.1\ndriftfile /var/lib/chrony/chrony.drift\n"
+
+	updated, changed := updateChronyServers(content, []string{"ntp1.example.com", "ntp2.example.com"})
+	expected := "# Use public servers\nserver ntp1.example.com iburst\nserver ntp2.example.com iburst\ndriftfile /var/lib/chrony/chrony.drift\n"
+	if !changed || updated != expected {
+		t.Errorf("Unexpected chrony configuration:\n%s", updated)
+	}
+
+	if _, changed := updateChronyServers(updated, []string{"ntp1.example.com", "ntp2.example.com"}); changed {
+		t.Errorf("Expected no change for the same servers")
+	}
+}
+
+func TestSetEnvValue(t *testing.T) {
+	updated, changed := setEnvValue("LANG=C\nLC_TIME=en_GB.UTF-8\n", "LANG", "en_US.UTF-8")
+	if !changed || updated != "LANG=en_US.UTF-8\nLC_TIME=en_GB.UTF-8\n" {
+		t.Errorf("Unexpected locale file:\n%s", updated)
+	}
+
+	if updated, _ := setEnvValue("", "LANG", "en_US.UTF-8"); updated != "LANG=en_US.UTF-8\n" {
+		t.Errorf("Unexpected locale file:\n%s", updated)
+	}
+}
+
+func TestParseEpoch(t *testing.T) {
+	tests := map[string]time.Time{
+		"1700000000.250000000\n": time.Unix(1700000000, 250000000),
+		"1700000000.N\n":         time.Unix(1700000000, 0),
+		"1700000000.5":           time.Unix(1700000000, 500000000),
+	}
+	for output, expected := range tests {
+		got, err := parseEpoch(output)
+		if err != nil || !got.Equal(expected) {
+			t.Errorf("parseEpoch(%q) = %v, %v", output, got, err)
+		}
+	}
+}
+
+func TestSetTimezoneRejectsTraversal(t *testing.T) {
+	hostManager := UnixHostManager{CommandManager: &MockCommandManager{}}
+
+	if err := hostManager.SetTimezone("../../etc/passwd"); err == nil {
+		t.Errorf("Expected an error for an invalid timezone")
+	}
+}
+
+// newNonSystemdHostManager returns a host manager for a host without systemd,
+// on which the files written are recorded as sh scripts.
+func newNonSystemdHostManager(outputs map[string]string, exitCodes map[string]int) (*UnixHostManager, *MockCommandManager) {
+	exitCodes["test -d /run/systemd/system"] = 1
+	mockCmd := &MockCommandManager{Outputs: outputs, ExitCodes: exitCodes}
+	return &UnixHostManager{
+		CommandManager: mockCmd,
+		FileManager:    &filemanager.UnixFileManager{CommandManager: mockCmd},
+	}, mockCmd
+}
+
+// written returns the paths of the files written through the file manager.
+func written(commands []string) []string {
+	var paths []string
+	for _, command := range commands {
+		if !strings.HasPrefix(command, "sh -c set -e") {
+			continue
+		}
+		_, after, _ := strings.Cut(command, `mv -f "$tmp" `)
+		paths = append(paths, strings.TrimSpace(after))
+	}
+	return paths
+}
+
+func contains(commands []string, command string) bool {
+	for _, c := range commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetHostnameWithoutSystemd(t *testing.T) {
+	hostManager, mockCmd := newNonSystemdHostManager(map[string]string{
+		"hostname":          "old\n",
+		"cat /etc/hostname": "old\n",
+		"cat /etc/hosts":    "127.0.0.1\tlocalhost\n127.0.1.1\told\n",
+	}, map[string]int{})
+
+	if err := hostManager.SetHostname("new"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	commands := mockCmd.commands()
+	for _, command := range commands {
+		if strings.HasPrefix(command, "hostnamectl") {
+			t.Errorf("Expected hostnamectl not to be used, got %q", command)
+		}
+	}
+	if !contains(commands, "hostname new") {
+		t.Errorf("Expected the transient hostname to be set, got %q", commands)
+	}
+	if paths := written(commands); !reflect.DeepEqual(paths, []string{"/etc/hostname", "/etc/hosts"}) {
+		t.Errorf("Expected /etc/hostname and /etc/hosts to be written, got %q", paths)
+	}
+}
+
+func TestSetTimezoneWithoutSystemd(t *testing.T) {
+	hostManager, mockCmd := newNonSystemdHostManager(map[string]string{
+		"readlink /etc/localtime": "/usr/share/zoneinfo/UTC\n",
+	}, map[string]int{})
+
+	if err := hostManager.SetTimezone("Europe/Berlin"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	commands := mockCmd.commands()
+	if !contains(commands, "ln -sf /usr/share/zoneinfo/Europe/Berlin /etc/localtime") {
+		t.Errorf("Expected /etc/localtime to be linked, got %q", commands)
+	}
+	if paths := written(commands); !reflect.DeepEqual(paths, []string{"/etc/timezone"}) {
+		t.Errorf("Expected /etc/timezone to be written, got %q", paths)
+	}
+
+	// Without /etc/timezone only the link changes.
+	hostManager, mockCmd = newNonSystemdHostManager(map[string]string{
+		"readlink /etc/localtime": "/usr/share/zoneinfo/UTC\n",
+	}, map[string]int{"test -f /etc/timezone": 1})
+	if err := hostManager.SetTimezone("Europe/Berlin"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if paths := written(mockCmd.commands()); len(paths) != 0 {
+		t.Errorf("Expected no files to be written, got %q", paths)
+	}
+
+	hostManager, _ = newNonSystemdHostManager(map[string]string{}, map[string]int{"test -f /usr/share/zoneinfo/Mars/Olympus": 1})
+	if err := hostManager.SetTimezone("Mars/Olympus"); err == nil || !strings.Contains(err.Error(), "unknown timezone") {
+		t.Errorf("Expected an unknown timezone error, got: %v", err)
+	}
+}
+
+func TestSetTimezoneConnectionFailure(t *testing.T) {
+	hostManager := UnixHostManager{CommandManager: &MockCommandManager{Err: errors.New("dial tcp: connection refused")}}
+
+	err := hostManager.SetTimezone("Europe/Berlin")
+	if err == nil || strings.Contains(err.Error(), "unknown timezone") {
+		t.Errorf("Expected the connection error, got: %v", err)
+	}
+}
+
+func TestSetLocaleWithoutSystemd(t *testing.T) {
+	hostManager, mockCmd := newNonSystemdHostManager(map[string]string{
+		"cat /etc/default/locale": "LANG=C\n",
+	}, map[string]int{"test -f /etc/locale.conf": 1})
+
+	if err := hostManager.SetLocale("en_US.UTF-8"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if paths := written(mockCmd.commands()); !reflect.DeepEqual(paths, []string{"/etc/default/locale"}) {
+		t.Errorf("Expected /etc/default/locale to be written, got %q", paths)
+	}
+
+	hostManager, mockCmd = newNonSystemdHostManager(map[string]string{
+		"cat /etc/locale.conf": "LANG=C\n",
+	}, map[string]int{})
+	if err := hostManager.SetLocale("en_US.UTF-8"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if paths := written(mockCmd.commands()); !reflect.DeepEqual(paths, []string{"/etc/locale.conf"}) {
+		t.Errorf("Expected /etc/locale.conf to be written, got %q", paths)
+	}
+}
+
+func TestEnsureTimeSyncWithoutSystemd(t *testing.T) {
+	probe := "sh -c " + chronydProbe.Args[1]
+	hostManager, mockCmd := newNonSystemdHostManager(map[string]string{}, map[string]int{probe: 1})
+
+	err := hostManager.EnsureTimeSync([]string{"time.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "install chrony") {
+		t.Errorf("Expected no time sync daemon to be found, got: %v", err)
+	}
+	probed := false
+	for _, config := range mockCmd.Configs {
+		if len(config.Args) == 2 && config.Args[1] == chronydProbe.Args[1] {
+			probed = config.Sudo
+		}
+	}
+	if !probed {
+		t.Errorf("Expected chronyd to be looked for as root")
+	}
+}
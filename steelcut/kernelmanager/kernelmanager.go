@@ -0,0 +1,27 @@
+package kernelmanager
+
+// KernelManager tunes kernel parameters and manages kernel modules. The Set,
+// Load and Unload operations are idempotent and report whether anything changed.
+type KernelManager interface {
+	// GetSysctl returns the current runtime value of a kernel parameter.
+	GetSysctl(key string) (string, error)
+
+	// SetSysctl sets a kernel parameter at runtime and, when persist is set,
+	// records it in /etc/sysctl.d so it survives a reboot.
+	SetSysctl(key, value string, persist bool) (bool, error)
+
+	// UnpersistSysctl removes a kernel parameter from the steelcut managed
+	// sysctl.d file. The runtime value is left as it is.
+	UnpersistSysctl(key string) (bool, error)
+
+	// LoadedModules lists the loaded kernel modules.
+	LoadedModules() ([]string, error)
+
+	// LoadModule loads a module with modprobe and, when persist is set,
+	// records it in /etc/modules-load.d so it is loaded on boot.
+	LoadModule(name string, persist bool) (bool, error)
+
+	// UnloadModule unloads a module and, when persist is set, removes it from
+	// the steelcut managed modules-load.d file.
+	UnloadModule(name string, persist bool) (bool, error)
+}
@@ -0,0 +1,298 @@
+package kernelmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+const (
+	// SysctlConfPath is the file persisted kernel parameters are written to.
+	// The 99 prefix makes it override the distribution's defaults.
+	SysctlConfPath = "/etc/sysctl.d/99-steelcut.conf"
+
+	// ModulesConfPath is the file persisted modules are written to.
+	ModulesConfPath = "/etc/modules-load.d/steelcut.conf"
+)
+
+var (
+	sysctlKey  = regexp.MustCompile(`^[A-Za-z0-9_-]+([./][A-Za-z0-9_*-]+)*$`)
+	moduleName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+type LinuxKernelManager struct {
+	CommandManager cm.CommandManager
+	FileManager    filemanager.FileManager
+}
+
+func (lkm *LinuxKernelManager) GetSysctl(key string) (string, error) {
+	if !sysctlKey.MatchString(key) {
+		return "", fmt.Errorf("invalid sysctl key: %q", key)
+	}
+
+	result, err := lkm.run(cm.CommandConfig{
+		Command: "sysctl",
+		Args:    []string{"-n", key},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return normalizeSysctlValue(result.STDOUT), nil
+}
+
+// SetSysctl compares values with their whitespace normalized, as sysctl prints
+// multi-value parameters such as net.ipv4.ip_local_port_range tab separated.
+func (lkm *LinuxKernelManager) SetSysctl(key, value string, persist bool) (bool, error) {
+	if !sysctlKey.MatchString(key) {
+		return false, fmt.Errorf("invalid sysctl key: %q", key)
+	}
+	if strings.ContainsAny(value, "\n#") {
+		return false, fmt.Errorf("invalid sysctl value for %s: %q", key, value)
+	}
+	value = normalizeSysctlValue(value)
+
+	current, err := lkm.GetSysctl(key)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	if current != value {
+		if _, err := lkm.run(cm.CommandConfig{
+			Command: "sysctl",
+			Args:    []string{"-w", key + "=" + value},
+			Sudo:    true,
+		}); err != nil {
+			return false, fmt.Errorf("failed to set %s: %w", key, err)
+		}
+		changed = true
+	}
+
+	if persist {
+		persisted, err := lkm.updateFile(SysctlConfPath, func(content string) (string, bool) {
+			return updateSysctlConf(content, key, value)
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || persisted
+	}
+
+	return changed, nil
+}
+
+func (lkm *LinuxKernelManager) UnpersistSysctl(key string) (bool, error) {
+	if !sysctlKey.MatchString(key) {
+		return false, fmt.Errorf("invalid sysctl key: %q", key)
+	}
+
+	return lkm.updateFile(SysctlConfPath, func(content string) (string, bool) {
+		return updateSysctlConf(content, key, "")
+	})
+}
+
+func (lkm *LinuxKernelManager) LoadedModules() ([]string, error) {
+	result, err := lkm.run(cm.CommandConfig{
+		Command: "cat",
+		Args:    []string{"/proc/modules"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []string
+	for _, line := range strings.Split(result.STDOUT, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			modules = append(modules, fields[0])
+		}
+	}
+	return modules, nil
+}
+
+func (lkm *LinuxKernelManager) LoadModule(name string, persist bool) (bool, error) {
+	if !moduleName.MatchString(name) {
+		return false, fmt.Errorf("invalid module name: %q", name)
+	}
+
+	loaded, err := lkm.isLoaded(name)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	if !loaded {
+		if _, err := lkm.run(cm.CommandConfig{
+			Command: "modprobe",
+			Args:    []string{name},
+			Sudo:    true,
+		}); err != nil {
+			return false, fmt.Errorf("failed to load module %s: %w", name, err)
+		}
+		changed = true
+	}
+
+	if persist {
+		persisted, err := lkm.updateFile(ModulesConfPath, func(content string) (string, bool) {
+			return updateModulesConf(content, name, true)
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || persisted
+	}
+
+	return changed, nil
+}
+
+func (lkm *LinuxKernelManager) UnloadModule(name string, persist bool) (bool, error) {
+	if !moduleName.MatchString(name) {
+		return false, fmt.Errorf("invalid module name: %q", name)
+	}
+
+	changed := false
+	if persist {
+		persisted, err := lkm.updateFile(ModulesConfPath, func(content string) (string, bool) {
+			return updateModulesConf(content, name, false)
+		})
+		if err != nil {
+			return false, err
+		}
+		changed = persisted
+	}
+
+	loaded, err := lkm.isLoaded(name)
+	if err != nil {
+		return changed, err
+	}
+	if loaded {
+		if _, err := lkm.run(cm.CommandConfig{
+			Command: "modprobe",
+			Args:    []string{"-r", name},
+			Sudo:    true,
+		}); err != nil {
+			return changed, fmt.Errorf("failed to unload module %s: %w", name, err)
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// isLoaded checks /proc/modules, where names always use underscores even
+// when the module was loaded by a name with dashes.
+func (lkm *LinuxKernelManager) isLoaded(name string) (bool, error) {
+	modules, err := lkm.LoadedModules()
+	if err != nil {
+		return false, err
+	}
+
+	name = strings.ReplaceAll(name, "-", "_")
+	for _, module := range modules {
+		if module == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// updateFile applies update to the content of path, treating a missing file
+// as empty, and writes the result back only when it changed.
+func (lkm *LinuxKernelManager) updateFile(path string, update func(content string) (string, bool)) (bool, error) {
+	content, err := filemanager.ReadFileIfExists(lkm.CommandManager, path, false)
+	if err != nil {
+		return false, err
+	}
+
+	updated, changed := update(content)
+	if !changed {
+		return false, nil
+	}
+
+	if _, err := lkm.run(cm.CommandConfig{
+		Command: "mkdir",
+		Args:    []string{"-p", path[:strings.LastIndex(path, "/")]},
+		Sudo:    true,
+	}); err != nil {
+		return false, err
+	}
+	if err := lkm.FileManager.WriteFileAtomic(path, []byte(updated), filemanager.AtomicWriteOptions{Mode: 0644}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (lkm *LinuxKernelManager) run(config cm.CommandConfig) (cm.CommandResult, error) {
+	return cm.RunChecked(context.TODO(), lkm.CommandManager, config, nil)
+}
+
+func normalizeSysctlValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// updateSysctlConf sets key = value in a sysctl.d file, or removes key when
+// value is empty. Keys may be written with dots or slashes, so both are
+// matched. Other lines are kept as they are.
+func updateSysctlConf(content, key, value string) (string, bool) {
+	normalize := func(k string) string { return strings.ReplaceAll(strings.TrimSpace(k), "/", ".") }
+
+	var lines []string
+	found := false
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if k, _, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(strings.TrimSpace(line), "#") && normalize(k) == normalize(key) {
+			if value != "" && !found {
+				lines = append(lines, key+" = "+value)
+			}
+			found = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if !found && value != "" {
+		lines = append(lines, key+" = "+value)
+	}
+
+	return joinLines(lines, content)
+}
+
+// updateModulesConf adds or removes a module in a modules-load.d file.
+func updateModulesConf(content, name string, present bool) (string, bool) {
+	normalize := func(n string) string { return strings.ReplaceAll(strings.TrimSpace(n), "-", "_") }
+
+	var lines []string
+	found := false
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if normalize(line) == normalize(name) {
+			if present && !found {
+				lines = append(lines, line)
+			}
+			found = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if !found && present {
+		lines = append(lines, name)
+	}
+
+	return joinLines(lines, content)
+}
+
+// joinLines rebuilds a file from lines and reports whether it differs from
+// the original content. An empty first line left over from splitting an
+// empty file is dropped.
+func joinLines(lines []string, original string) (string, bool) {
+	if len(lines) > 0 && lines[0] == "" && original == "" {
+		lines = lines[1:]
+	}
+
+	updated := ""
+	if len(lines) > 0 {
+		updated = strings.Join(lines, "\n") + "\n"
+	}
+	return updated, updated != original
+}
@@ -0,0 +1,207 @@
+package kernelmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+// MockCommandManager answers by full command line and records what was run.
+// Commands in ExitCodes fail with that status, commands in Errors fail to run.
+type MockCommandManager struct {
+	Outputs   map[string]string
+	ExitCodes map[string]int
+	Errors    map[string]error
+	Configs   []cm.CommandConfig
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Configs = append(m.Configs, config)
+	line := commandLine(config)
+	if err := m.Errors[line]; err != nil {
+		return cm.CommandResult{}, err
+	}
+	if status := m.ExitCodes[line]; status != 0 {
+		return cm.CommandResult{STDOUT: m.Outputs[line], STDERR: "failed\n", ExitCode: status}, fmt.Errorf("Process exited with status %d", status)
+	}
+	return cm.CommandResult{STDOUT: m.Outputs[line]}, nil
+}
+
+func (m *MockCommandManager) ran(line string) bool {
+	for _, config := range m.Configs {
+		if commandLine(config) == line {
+			return true
+		}
+	}
+	return false
+}
+
+func commandLine(config cm.CommandConfig) string {
+	return strings.Join(append([]string{config.Command}, config.Args...), " ")
+}
+
+// readLine is the command line that reads path when it exists.
+func readLine(path string) string {
+	return "sh -c if test -e " + path + "; then cat " + path + "; fi"
+}
+
+// MockFileManager records atomic writes, embedding the interface so only
+// WriteFileAtomic needs implementing. Every write fails with Err when it is set.
+type MockFileManager struct {
+	filemanager.FileManager
+	Writes map[string]string
+	Err    error
+}
+
+func (m *MockFileManager) WriteFileAtomic(path string, content []byte, opts filemanager.AtomicWriteOptions) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.Writes == nil {
+		m.Writes = make(map[string]string)
+	}
+	m.Writes[path] = string(content)
+	return nil
+}
+
+func TestSetSysctl(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"sysctl -n net.ipv4.ip_local_port_range": "32768\t60999\n",
+		readLine(SysctlConfPath):                 "# managed by steelcut\nvm.swappiness = 60\n",
+	}}
+	mockFile := &MockFileManager{}
+	manager := LinuxKernelManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	changed, err := manager.SetSysctl("net.ipv4.ip_local_port_range", "32768 60999", false)
+	if err != nil || changed {
+		t.Errorf("Expected no change for an equal multi-value parameter, got %v, %v", changed, err)
+	}
+
+	mockCmd.Outputs["sysctl -n vm.swappiness"] = "60\n"
+	changed, err = manager.SetSysctl("vm.swappiness", "10", true)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	if !mockCmd.ran("sysctl -w vm.swappiness=10") {
+		t.Errorf("Expected the runtime value to be set, ran: %v", mockCmd.Configs)
+	}
+	if mockFile.Writes[SysctlConfPath] != "# managed by steelcut\nvm.swappiness = 10\n" {
+		t.Errorf("Unexpected sysctl.d file: %q", mockFile.Writes[SysctlConfPath])
+	}
+}
+
+func TestSetSysctlInvalidKey(t *testing.T) {
+	manager := LinuxKernelManager{CommandManager: &MockCommandManager{}}
+
+	if _, err := manager.SetSysctl("vm.swappiness; reboot", "10", false); err == nil {
+		t.Errorf("Expected an error for an invalid key")
+	}
+}
+
+func TestSetSysctlFailures(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Outputs:   map[string]string{"sysctl -n vm.swappiness": "60\n"},
+		ExitCodes: map[string]int{"sysctl -w vm.swappiness=10": 255},
+	}
+	mockFile := &MockFileManager{}
+	manager := LinuxKernelManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	if _, err := manager.SetSysctl("vm.swappiness", "10", true); err == nil {
+		t.Errorf("Expected a failed sysctl -w to be an error")
+	}
+
+	// An unreadable sysctl.d file must not be replaced by the managed line alone.
+	delete(mockCmd.ExitCodes, "sysctl -w vm.swappiness=10")
+	mockCmd.ExitCodes[readLine(SysctlConfPath)] = 1
+	if _, err := manager.SetSysctl("vm.swappiness", "10", true); err == nil {
+		t.Errorf("Expected a failed read to be an error")
+	}
+	if mockFile.Writes != nil {
+		t.Errorf("Expected nothing to be written, got %v", mockFile.Writes)
+	}
+
+	delete(mockCmd.ExitCodes, readLine(SysctlConfPath))
+	mockCmd.Errors = map[string]error{readLine(SysctlConfPath): errors.New("connection lost")}
+	if _, err := manager.UnpersistSysctl("vm.swappiness"); err == nil {
+		t.Errorf("Expected a read that could not run to be an error")
+	}
+
+	mockCmd.Errors = nil
+	mockFile.Err = errors.New("validation failed")
+	if _, err := manager.SetSysctl("vm.swappiness", "10", true); err == nil {
+		t.Errorf("Expected a failed write to be an error")
+	}
+}
+
+func TestLoadModuleFailure(t *testing.T) {
+	mockCmd := &MockCommandManager{ExitCodes: map[string]int{"modprobe nosuchmodule": 1}}
+	manager := LinuxKernelManager{CommandManager: mockCmd, FileManager: &MockFileManager{}}
+
+	if _, err := manager.LoadModule("nosuchmodule", false); err == nil {
+		t.Errorf("Expected a failed modprobe to be an error")
+	}
+}
+
+func TestLoadModule(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"cat /proc/modules": "br_netfilter 32768 0 - Live 0x0000000000000000\noverlay 151552 0 - Live 0x0000000000000000\n",
+	}}
+	mockFile := &MockFileManager{}
+	manager := LinuxKernelManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	changed, err := manager.LoadModule("br-netfilter", false)
+	if err != nil || changed {
+		t.Errorf("Expected an already loaded module to be left alone, got %v, %v", changed, err)
+	}
+
+	changed, err = manager.LoadModule("nf_conntrack", true)
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+	if !mockCmd.ran("modprobe nf_conntrack") || mockFile.Writes[ModulesConfPath] != "nf_conntrack\n" {
+		t.Errorf("Expected the module to be loaded and persisted, got: %v %v", mockCmd.Configs, mockFile.Writes)
+	}
+}
+
+func TestUpdateSysctlConf(t *testing.T) {
+	content := "# tuning\nnet/core/somaxconn = 1024\nvm.swappiness=10\n"
+
+	updated, changed := updateSysctlConf(content, "net.core.somaxconn", "4096")
+	if !changed || updated != "# tuning\nnet.core.somaxconn = 4096\nvm.swappiness=10\n" {
+		t.Errorf("Unexpected sysctl.d file: %q", updated)
+	}
+
+	updated, changed = updateSysctlConf(updated, "vm.swappiness", "")
+	if !changed || updated != "# tuning\nnet.core.somaxconn = 4096\n" {
+		t.Errorf("Unexpected sysctl.d file: %q", updated)
+	}
+
+	if _, changed := updateSysctlConf(updated, "net.core.somaxconn", "4096"); changed {
+		t.Errorf("Expected no change for the same value")
+	}
+}
+
+func TestUpdateModulesConf(t *testing.T) {
+	updated, changed := updateModulesConf("", "overlay", true)
+	if !changed || updated != "overlay\n" {
+		t.Errorf("Unexpected modules-load.d file: %q", updated)
+	}
+
+	updated, changed = updateModulesConf("overlay\nbr_netfilter\n", "br-netfilter", false)
+	if !changed || updated != "overlay\n" {
+		t.Errorf("Unexpected modules-load.d file: %q", updated)
+	}
+}
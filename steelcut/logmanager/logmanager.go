@@ -0,0 +1,49 @@
+package logmanager
+
+import "time"
+
+// Syslog priorities, as used by journald. Lower is more severe.
+const (
+	PriorityEmergency = 0
+	PriorityAlert     = 1
+	PriorityCritical  = 2
+	PriorityError     = 3
+	PriorityWarning   = 4
+	PriorityNotice    = 5
+	PriorityInfo      = 6
+	PriorityDebug     = 7
+
+	// PriorityUnknown marks entries read from plain log files, which do not
+	// record a priority.
+	PriorityUnknown = -1
+)
+
+// Entry is a single log record.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Hostname   string    `json:"hostname,omitempty"` // as recorded in the log
+	Unit       string    `json:"unit,omitempty"`     // systemd unit, journald only
+	Identifier string    `json:"identifier"`         // syslog tag, usually the program name
+	PID        int       `json:"pid,omitempty"`
+	Priority   int       `json:"priority"`
+	Message    string    `json:"message"`
+}
+
+// Query selects log entries. Zero-valued fields match everything.
+type Query struct {
+	Unit     string // systemd unit; matched against the syslog tag in log files
+	Priority string // journalctl -p value such as err, 4 or 0..3; ignored for log files
+	Since    time.Time
+	Until    time.Time
+	Grep     string // POSIX extended regular expression, as for grep -E, matched case-sensitively against the message
+	Lines    int    // return at most the newest Lines entries; zero means DefaultLines
+}
+
+// DefaultLines is how many entries a Query returns when Lines is not set.
+const DefaultLines = 1000
+
+// LogManager reads the system log of a host.
+type LogManager interface {
+	// Query returns the matching entries, oldest first.
+	Query(query Query) ([]Entry, error)
+}
@@ -0,0 +1,299 @@
+package logmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// SyslogFiles are the plain log files read, first one found wins, on hosts
+// without journald.
+var SyslogFiles = []string{"/var/log/syslog", "/var/log/messages", "/var/log/system.log"}
+
+// noJournalExitCode is what the journal script exits with when journald is not running.
+const noJournalExitCode = 100
+
+// journalScript runs journalctl with the arguments passed after the script,
+// but only on hosts that actually booted with systemd.
+var journalScript = fmt.Sprintf(`if command -v journalctl >/dev/null 2>&1 && [ -d /run/systemd/system ]; then exec journalctl "$@"; fi; exit %d`, noJournalExitCode)
+
+// syslogLine matches the traditional "Mon  2 15:04:05 host tag[pid]: message"
+// format as well as the RFC 3339 timestamps written by newer rsyslog setups.
+var syslogLine = regexp.MustCompile(`^(\w{3} [ \d]\d \d\d:\d\d:\d\d|\d{4}-\d\d-\d\dT[\d:.]+(?:Z|[+-]\d\d:?\d\d)) (\S+) ([^:\[\s]+)(?:\[(\d+)\])?: ?(.*)$`)
+
+type UnixLogManager struct {
+	CommandManager cm.CommandManager
+}
+
+// Query reads from journald when the host runs systemd and from the first of
+// SyslogFiles otherwise. The grep pattern is applied on the host to cut down
+// what is transferred, and again locally so both sources match the same way.
+// It must be a POSIX extended regular expression, the one syntax that grep -E,
+// journalctl's PCRE2 and Go all read alike.
+func (ulm *UnixLogManager) Query(query Query) ([]Entry, error) {
+	var pattern *regexp.Regexp
+	if query.Grep != "" {
+		var err error
+		pattern, err = regexp.CompilePOSIX(query.Grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grep pattern: %w", err)
+		}
+	}
+	if query.Lines <= 0 {
+		query.Lines = DefaultLines
+	}
+
+	entries, err := ulm.queryJournal(query)
+	if errors.Is(err, errNoJournal) {
+		entries, err = ulm.queryFiles(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return filterEntries(entries, query, pattern), nil
+}
+
+var errNoJournal = errors.New("journald is not running")
+
+func (ulm *UnixLogManager) queryJournal(query Query) ([]Entry, error) {
+	result, err := ulm.runJournal(journalArgs(query, true))
+	if err == nil && query.Grep != "" && result.ExitCode != 0 && result.ExitCode != noJournalExitCode &&
+		(strings.Contains(result.STDERR, "pattern matching") || strings.Contains(result.STDERR, "case-sensitive")) {
+		// journalctl built without PCRE2 support, or too old to turn off
+		// its smart case matching, cannot grep, so the pattern is only
+		// applied locally.
+		result, err = ulm.runJournal(journalArgs(query, false))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode == noJournalExitCode {
+		return nil, errNoJournal
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("journalctl failed: %s", strings.TrimSpace(result.STDERR))
+	}
+
+	return parseJournal(result.STDOUT)
+}
+
+func (ulm *UnixLogManager) runJournal(args []string) (cm.CommandResult, error) {
+	// A non-zero exit is reported through the result, not as an error.
+	return cm.RunChecked(context.TODO(), ulm.CommandManager, cm.CommandConfig{
+		Command: "sh",
+		Args:    append([]string{"-c", journalScript, "sh"}, args...),
+		Sudo:    true,
+	}, func(int) bool { return true })
+}
+
+func journalArgs(query Query, grep bool) []string {
+	args := []string{"-o", "json", "--no-pager", "-q", "-n", strconv.Itoa(query.Lines)}
+	if query.Unit != "" {
+		args = append(args, "-u", query.Unit)
+	}
+	if query.Priority != "" {
+		args = append(args, "-p", query.Priority)
+	}
+	if !query.Since.IsZero() {
+		args = append(args, "--since", "@"+strconv.FormatInt(query.Since.Unix(), 10))
+	}
+	if !query.Until.IsZero() {
+		args = append(args, "--until", "@"+strconv.FormatInt(query.Until.Unix(), 10))
+	}
+	if grep && query.Grep != "" {
+		// journalctl ignores case for all-lowercase patterns unless told
+		// not to, which grep -E never does.
+		args = append(args, "--case-sensitive=true", "-g", query.Grep)
+	}
+	return args
+}
+
+// parseJournal parses journalctl -o json output, one object per line.
+func parseJournal(output string) ([]Entry, error) {
+	var entries []Entry
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse journal entry: %w", err)
+		}
+
+		micros, err := strconv.ParseInt(journalField(record, "__REALTIME_TIMESTAMP"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("journal entry without a valid timestamp: %s", line)
+		}
+
+		priority := PriorityUnknown
+		if p, err := strconv.Atoi(journalField(record, "PRIORITY")); err == nil {
+			priority = p
+		}
+		pid, _ := strconv.Atoi(journalField(record, "_PID"))
+
+		entries = append(entries, Entry{
+			Time:       time.UnixMicro(micros),
+			Hostname:   journalField(record, "_HOSTNAME"),
+			Unit:       journalField(record, "_SYSTEMD_UNIT"),
+			Identifier: journalField(record, "SYSLOG_IDENTIFIER"),
+			PID:        pid,
+			Priority:   priority,
+			Message:    journalField(record, "MESSAGE"),
+		})
+	}
+	return entries, nil
+}
+
+// journalField returns a journal field as text. Fields holding non-UTF-8 data
+// are exported as arrays of bytes rather than strings.
+func journalField(record map[string]interface{}, name string) string {
+	switch v := record[name].(type) {
+	case string:
+		return v
+	case []interface{}:
+		data := make([]byte, 0, len(v))
+		for _, b := range v {
+			if n, ok := b.(float64); ok {
+				data = append(data, byte(n))
+			}
+		}
+		return string(data)
+	}
+	return ""
+}
+
+// queryFiles reads the newest matching lines of the first syslog file found.
+// The host's UTC offset is printed first, as traditional syslog timestamps
+// carry neither a zone nor a year.
+func (ulm *UnixLogManager) queryFiles(query Query) ([]Entry, error) {
+	filter := "cat \"$f\""
+	if query.Unit != "" {
+		tag := strings.TrimSuffix(query.Unit, ".service")
+		filter += " | grep -E -- " + cm.ShellQuote(" "+regexp.QuoteMeta(tag)+`(\[[0-9]+\])?:`)
+	}
+	if query.Grep != "" {
+		filter += " | grep -E -- " + cm.ShellQuote(query.Grep)
+	}
+	filter += " | tail -n " + strconv.Itoa(query.Lines)
+
+	quoted := make([]string, len(SyslogFiles))
+	for i, path := range SyslogFiles {
+		quoted[i] = cm.ShellQuote(path)
+	}
+	script := "date +%z; for f in " + strings.Join(quoted, " ") + "; do if [ -r \"$f\" ]; then " + filter + "; exit 0; fi; done; echo 'no log file found' >&2; exit 1"
+
+	result, err := ulm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", script},
+		Sudo:    true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log files: %w: %s", err, strings.TrimSpace(result.STDERR))
+	}
+
+	offset, lines, _ := strings.Cut(result.STDOUT, "\n")
+	location, err := parseZoneOffset(strings.TrimSpace(offset))
+	if err != nil {
+		return nil, err
+	}
+	return parseSyslog(lines, location, time.Now()), nil
+}
+
+// parseZoneOffset turns date +%z output such as +0200 into a location.
+func parseZoneOffset(offset string) (*time.Location, error) {
+	t, err := time.Parse("-0700", offset)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected UTC offset %q: %w", offset, err)
+	}
+	_, seconds := t.Zone()
+	return time.FixedZone(offset, seconds), nil
+}
+
+// parseSyslog parses syslog lines. Timestamps without a year are placed in the
+// year before now when they would otherwise lie in the future. Lines that do
+// not parse, such as continuation lines, are appended to the previous entry.
+func parseSyslog(content string, location *time.Location, now time.Time) []Entry {
+	var entries []Entry
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		match := syslogLine.FindStringSubmatch(line)
+		if match == nil {
+			if len(entries) > 0 {
+				entries[len(entries)-1].Message += "\n" + line
+			}
+			continue
+		}
+
+		timestamp, err := parseSyslogTime(match[1], location, now)
+		if err != nil {
+			continue
+		}
+		pid, _ := strconv.Atoi(match[4])
+
+		entries = append(entries, Entry{
+			Time:       timestamp,
+			Hostname:   match[2],
+			Identifier: match[3],
+			PID:        pid,
+			Priority:   PriorityUnknown,
+			Message:    match[5],
+		})
+	}
+	return entries
+}
+
+func parseSyslogTime(value string, location *time.Location, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("Jan _2 15:04:05", value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	year := now.In(location).Year()
+	t = t.AddDate(year, 0, 0)
+	// Allow for some clock skew before deciding the entry is from last year.
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, nil
+}
+
+// filterEntries applies the parts of query that the sources may not have
+// applied themselves and returns the newest query.Lines entries, oldest first.
+func filterEntries(entries []Entry, query Query, pattern *regexp.Regexp) []Entry {
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if !query.Since.IsZero() && entry.Time.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && entry.Time.After(query.Until) {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(entry.Message) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].Time.Before(filtered[j].Time) })
+	if len(filtered) > query.Lines {
+		filtered = filtered[len(filtered)-query.Lines:]
+	}
+	return filtered
+}
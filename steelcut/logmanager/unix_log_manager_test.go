@@ -0,0 +1,171 @@
+package logmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// MockCommandManager answers the journal and log file scripts and records
+// the arguments journalctl was given. A non-zero JournalExitCode comes with
+// an error, as it does from a real command manager.
+type MockCommandManager struct {
+	Journal         string
+	JournalExitCode int
+	JournalStderr   string
+	Files           string
+	JournalArgs     [][]string
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	if config.Args[1] == journalScript {
+		args := config.Args[3:]
+		m.JournalArgs = append(m.JournalArgs, args)
+		if m.JournalStderr != "" && strings.Contains(strings.Join(args, " "), "--case-sensitive") {
+			return cm.CommandResult{STDERR: m.JournalStderr, ExitCode: 1}, errors.New("Process exited with status 1")
+		}
+		if m.JournalExitCode != 0 {
+			return cm.CommandResult{STDOUT: m.Journal, ExitCode: m.JournalExitCode}, fmt.Errorf("Process exited with status %d", m.JournalExitCode)
+		}
+		return cm.CommandResult{STDOUT: m.Journal}, nil
+	}
+	return cm.CommandResult{STDOUT: m.Files}, nil
+}
+
+func TestParseJournal(t *testing.T) {
+	output := `{"__REALTIME_TIMESTAMP":"1700000000123456","_HOSTNAME":"web1","_SYSTEMD_UNIT":"nginx.service","SYSLOG_IDENTIFIER":"nginx","_PID":"812","PRIORITY":"3","MESSAGE":"bind() to 0.0.0.0:80 failed"}
+{"__REALTIME_TIMESTAMP":"1700000001000000","SYSLOG_IDENTIFIER":"kernel","PRIORITY":"6","MESSAGE":[104,105,255]}
+`
+	entries, err := parseJournal(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Entry{
+		{Time: time.UnixMicro(1700000000123456), Hostname: "web1", Unit: "nginx.service", Identifier: "nginx", PID: 812, Priority: PriorityError, Message: "bind() to 0.0.0.0:80 failed"},
+		{Time: time.UnixMicro(1700000001000000), Identifier: "kernel", Priority: PriorityInfo, Message: "hi\xff"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, entries)
+	}
+}
+
+func TestParseSyslog(t *testing.T) {
+	location := time.FixedZone("+0200", 2*60*60)
+	now := time.Date(2024, time.January, 2, 12, 0, 0, 0, time.UTC)
+	content := `Dec 31 23:59:58 db1 postgres[4242]: checkpoint starting
+	continued detail
+Jan  2 10:00:00 db1 CRON[99]: (root) CMD (run-parts /etc/cron.hourly)
+2024-01-02T11:30:00.5+01:00 db1 sshd[7]: Accepted publickey for deploy
+`
+	entries := parseSyslog(content, location, now)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if want := time.Date(2023, time.December, 31, 23, 59, 58, 0, location); !entries[0].Time.Equal(want) {
+		t.Errorf("Expected the December entry to be from last year, got %v", entries[0].Time)
+	}
+	if entries[0].Message != "checkpoint starting\n\tcontinued detail" || entries[0].PID != 4242 {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if want := time.Date(2024, time.January, 2, 8, 0, 0, 0, time.UTC); !entries[1].Time.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, entries[1].Time)
+	}
+	if entries[2].Identifier != "sshd" || entries[2].Priority != PriorityUnknown {
+		t.Errorf("Unexpected last entry: %+v", entries[2])
+	}
+}
+
+func TestQueryJournalArgs(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Journal: `{"__REALTIME_TIMESTAMP":"1700000000000000","MESSAGE":"Timeout waiting for backend"}
+{"__REALTIME_TIMESTAMP":"1700000001000000","MESSAGE":"timeout waiting for backend"}
+`,
+	}
+	manager := &UnixLogManager{CommandManager: mockCmd}
+
+	since := time.Unix(1699999000, 0)
+	entries, err := manager.Query(Query{Unit: "app.service", Priority: "err", Since: since, Grep: "Timeout", Lines: 50})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expectedArgs := []string{"-o", "json", "--no-pager", "-q", "-n", "50", "-u", "app.service", "-p", "err", "--since", "@1699999000", "--case-sensitive=true", "-g", "Timeout"}
+	if !reflect.DeepEqual(mockCmd.JournalArgs[0], expectedArgs) {
+		t.Errorf("Expected journalctl %v, got %v", expectedArgs, mockCmd.JournalArgs[0])
+	}
+	// The local filter keeps the match case-sensitive like the file
+	// fallback, whatever journalctl did.
+	if len(entries) != 1 || entries[0].Message != "Timeout waiting for backend" {
+		t.Errorf("Expected only the case-sensitive match, got %+v", entries)
+	}
+}
+
+func TestQueryFallsBackToFiles(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		JournalExitCode: noJournalExitCode,
+		Files:           "+0000\nMar  1 10:00:00 old1 app[1]: first\nMar  1 10:00:01 old1 app[1]: second\nMar  1 10:00:02 old1 app[1]: third\n",
+	}
+	manager := &UnixLogManager{CommandManager: mockCmd}
+
+	entries, err := manager.Query(Query{Lines: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var messages []string
+	for _, entry := range entries {
+		messages = append(messages, entry.Message)
+	}
+	if strings.Join(messages, ",") != "second,third" {
+		t.Errorf("Expected the newest two entries oldest first, got %v", messages)
+	}
+}
+
+func TestQueryJournalWithoutCaseSensitive(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Journal:       `{"__REALTIME_TIMESTAMP":"1700000000000000","MESSAGE":"timeout waiting for backend"}` + "\n",
+		JournalStderr: "journalctl: unrecognized option '--case-sensitive=true'",
+	}
+	manager := &UnixLogManager{CommandManager: mockCmd}
+
+	entries, err := manager.Query(Query{Grep: "timeout"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(mockCmd.JournalArgs) != 2 || strings.Contains(strings.Join(mockCmd.JournalArgs[1], " "), "-g") {
+		t.Errorf("Expected a retry without grepping on the host, got %v", mockCmd.JournalArgs)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected the entry to match locally, got %+v", entries)
+	}
+}
+
+func TestQueryRejectsNonPOSIXPattern(t *testing.T) {
+	manager := &UnixLogManager{CommandManager: &MockCommandManager{}}
+
+	// Perl classes and flags mean something else, or nothing, to grep -E.
+	for _, pattern := range []string{`error \d+`, `(?i)error`} {
+		if _, err := manager.Query(Query{Grep: pattern}); err == nil {
+			t.Errorf("Expected %q to be rejected", pattern)
+		}
+	}
+	if _, err := manager.Query(Query{Grep: `error [0-9]+|fail(ed|ure)`}); err != nil {
+		t.Errorf("Expected an extended regular expression to be accepted, got: %v", err)
+	}
+}
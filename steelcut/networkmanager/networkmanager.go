@@ -1,12 +1,156 @@
 package networkmanager
 
+import (
+	"strconv"
+	"time"
+)
+
 // PingResult represents the result of a ping operation.
 type PingResult struct {
-	Address string
-	RTT     float64 // Round Trip Time in milliseconds
-	Success bool
+	Address     string
+	Transmitted int
+	Received    int
+	PacketLoss  float64 // percent
+	Min         time.Duration
+	Avg         time.Duration
+	Max         time.Duration
+	StdDev      time.Duration // zero where ping does not report it, as with busybox
+	Success     bool          // at least one reply was received
+}
+
+// PortCheckResult is the outcome of connecting to a port from a host.
+type PortCheckResult struct {
+	Host     string
+	Port     int
+	Protocol string // tcp or udp
+	Open     bool
+	Latency  time.Duration // time to connect, zero when the probe cannot time it
+	Method   string        // probe used: python, nc or bash
+	Error    string        // why the connection failed, e.g. connection refused
+}
+
+// Interface is a network interface and the addresses assigned to it.
+type Interface struct {
+	Name      string    `json:"name"`
+	MAC       string    `json:"mac,omitempty"`
+	MTU       int       `json:"mtu"`
+	State     string    `json:"state"` // up, down or unknown
+	Addresses []Address `json:"addresses,omitempty"`
+}
+
+// Address is an IP address with its prefix length.
+type Address struct {
+	Family    string `json:"family"` // inet or inet6
+	IP        string `json:"ip"`
+	PrefixLen int    `json:"prefixLen"`
+	Scope     string `json:"scope,omitempty"` // global, link or host
+}
+
+// CIDR returns the address in CIDR notation, e.g. 10.0.0.5/24.
+func (a Address) CIDR() string {
+	return a.IP + "/" + strconv.Itoa(a.PrefixLen)
+}
+
+// Route is an entry of the main routing table.
+type Route struct {
+	Family      string `json:"family"`      // inet or inet6
+	Destination string `json:"destination"` // "default" or a network in CIDR notation
+	Gateway     string `json:"gateway,omitempty"`
+	Interface   string `json:"interface,omitempty"`
+	Metric      int    `json:"metric,omitempty"`
+}
+
+// IsDefault reports whether r is a default route.
+func (r Route) IsDefault() bool {
+	return r.Destination == "default"
+}
+
+// DNSConfig is the host's resolver configuration.
+type DNSConfig struct {
+	Servers []string `json:"servers"`
+	Search  []string `json:"search,omitempty"`
+	Options []string `json:"options,omitempty"`
+	Source  string   `json:"source"` // resolv.conf or systemd-resolved
+}
+
+// Socket is a socket waiting for connections, or for datagrams in the case
+// of UDP.
+type Socket struct {
+	Protocol     string `json:"protocol"`     // tcp or udp
+	LocalAddress string `json:"localAddress"` // "*" when bound to all addresses
+	LocalPort    int    `json:"localPort"`
+	PID          int    `json:"pid,omitempty"` // zero when the owner is unknown
+	Process      string `json:"process,omitempty"`
+}
+
+// HTTPProbe describes a request made from the managed host to an endpoint.
+type HTTPProbe struct {
+	URL       string
+	BodyMatch string        // regular expression the body must match; empty matches any body
+	Timeout   time.Duration // overall limit for the request, 10 seconds when zero
+	Insecure  bool          // do not verify the TLS certificate
+}
+
+// HTTPProbeResult is the outcome of an HTTP probe. A failed request is
+// reported with a zero StatusCode and the reason in Error.
+type HTTPProbeResult struct {
+	URL         string
+	StatusCode  int
+	Latency     time.Duration // time until the whole response was received
+	TLSExpiry   time.Time     // zero for plain HTTP or where openssl is missing
+	BodyMatched bool
+	Method      string // client used: curl or wget
+	Error       string
 }
 
 type NetworkManager interface {
-	Ping(address string) (PingResult, error)
+	// Ping sends count echo requests to address, an IPv4 or IPv6 address or
+	// a hostname, waiting at most timeout overall. An unreachable address is
+	// not an error; it is reported with Success false and 100% packet loss.
+	Ping(address string, count int, timeout time.Duration) (PingResult, error)
+
+	// CheckPort connects from the managed host to host:port over proto, tcp
+	// or udp. A closed or filtered port is not an error; it is reported
+	// with Open false. UDP has no handshake, so a UDP port counts as open
+	// unless the target actively refuses it.
+	CheckPort(host string, port int, proto string, timeout time.Duration) (PortCheckResult, error)
+
+	// Interfaces lists the network interfaces, including ones that are down.
+	Interfaces() ([]Interface, error)
+
+	// Routes lists the IPv4 and IPv6 routes of the main routing table.
+	Routes() ([]Route, error)
+
+	// DNSConfig returns the resolvers in use. Where resolv.conf only points at
+	// the systemd-resolved stub, the upstream servers are asked of resolvectl.
+	DNSConfig() (DNSConfig, error)
+
+	// ListeningSockets lists the TCP sockets in the listening state and the
+	// unconnected UDP sockets, sorted by protocol and port. Owners are only
+	// known where ss or lsof is available.
+	ListeningSockets() ([]Socket, error)
+
+	// ProbeHTTP requests probe.URL from the managed host with curl, or wget
+	// where curl is missing. Redirects are not followed. Error responses and
+	// failed connections are not errors; they are reported in the result.
+	ProbeHTTP(probe HTTPProbe) (HTTPProbeResult, error)
+
+	// EnsureHostsEntry makes /etc/hosts map names to ip, the first name
+	// being the canonical one. The names are taken off other addresses of
+	// the same family, so they resolve to ip alone.
+	EnsureHostsEntry(ip string, names ...string) (bool, error)
+
+	// RemoveHostsEntry removes names from the /etc/hosts entries for ip, or
+	// the entries altogether when no names are given.
+	RemoveHostsEntry(ip string, names ...string) (bool, error)
+
+	// EnsureResolvers configures the DNS servers and search domains through
+	// whatever manages them on the host: NetworkManager, systemd-resolved or
+	// a plain /etc/resolv.conf.
+	EnsureResolvers(servers, searchDomains []string) (bool, error)
+
+	// ResolveName resolves name on the host the way its programs would,
+	// /etc/hosts included. A name that does not resolve is not an error;
+	// no addresses are returned for it.
+	ResolveName(name string) ([]string, error)
 }
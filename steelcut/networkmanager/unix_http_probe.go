@@ -0,0 +1,160 @@
+package networkmanager
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+const (
+	defaultHTTPProbeTimeout = 10 * time.Second
+
+	// maxProbeBody is how much of the body is sent back to match BodyMatch.
+	maxProbeBody = 64 * 1024
+)
+
+// httpProbeScript fetches a URL into a temporary file with curl or wget and
+// prints key=value lines, then a "body" line followed by the start of the
+// body. wget is timed with date +%s%N like the port check fallbacks. Neither
+// client follows redirects, so the status is that of the URL itself, whose
+// host is also where the certificate expiry is read with openssl s_client,
+// as neither client reports it.
+const httpProbeScript = `url=$1 timeout=$2 insecure=$3 connect=$4 servername=$5 limit=$6
+body=$(mktemp) && err=$(mktemp) || exit 2
+trap 'rm -f "$body" "$err"' EXIT
+if command -v curl >/dev/null 2>&1; then
+	echo method=curl
+	k=; [ "$insecure" = 1 ] && k=-k
+	curl -sS $k --max-time "$timeout" -o "$body" -w 'status=%{http_code}\nlatency=%{time_total}\n' "$url" 2>"$err"
+	rc=$?
+elif command -v wget >/dev/null 2>&1; then
+	echo method=wget
+	k=; [ "$insecure" = 1 ] && k=--no-check-certificate
+	start=$(date +%s%N)
+	wget -S -T "$timeout" -t 1 --max-redirect=0 $k -O "$body" "$url" 2>"$err"
+	rc=$?
+	end=$(date +%s%N)
+	echo "start=$start"
+	echo "end=$end"
+	echo "status=$(grep -Eo 'HTTP/[0-9.]+ [0-9]{3}' "$err" | tail -n 1 | cut -d ' ' -f 2)"
+	# wget exits 8 for error responses and unfollowed redirects, which
+	# still count as answers.
+	[ "$rc" -eq 8 ] && rc=0
+else
+	echo "no HTTP client available: need curl or wget" >&2
+	exit 2
+fi
+if [ "$rc" -ne 0 ]; then echo "error=$(grep -v '^ ' "$err" | tail -n 1)"; fi
+if [ -n "$connect" ] && command -v openssl >/dev/null 2>&1; then
+	sni=; [ -n "$servername" ] && sni="-servername $servername"
+	echo "tls_expiry=$(openssl s_client -connect "$connect" $sni </dev/null 2>/dev/null | openssl x509 -noout -enddate 2>/dev/null | cut -d = -f 2)"
+fi
+echo body
+head -c "$limit" "$body"`
+
+func (unm *UnixNetworkManager) ProbeHTTP(probe HTTPProbe) (HTTPProbeResult, error) {
+	target, err := url.Parse(probe.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return HTTPProbeResult{}, fmt.Errorf("invalid URL: %q", probe.URL)
+	}
+	var bodyMatch *regexp.Regexp
+	if probe.BodyMatch != "" {
+		if bodyMatch, err = regexp.Compile(probe.BodyMatch); err != nil {
+			return HTTPProbeResult{}, fmt.Errorf("invalid body match: %w", err)
+		}
+	}
+
+	timeout := probe.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPProbeTimeout
+	}
+	seconds := int(math.Ceil(timeout.Seconds()))
+
+	// The certificate is fetched from the same host and port as the URL,
+	// with SNI unless the host is an address.
+	var connect, servername string
+	if target.Scheme == "https" {
+		port := target.Port()
+		if port == "" {
+			port = "443"
+		}
+		connect = net.JoinHostPort(target.Hostname(), port)
+		if net.ParseIP(target.Hostname()) == nil {
+			servername = target.Hostname()
+		}
+	}
+	insecure := "0"
+	if probe.Insecure {
+		insecure = "1"
+	}
+
+	// Allow for the TLS check on top of the request itself.
+	ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Duration(seconds)*time.Second+10*time.Second)
+	defer cancel()
+
+	output, err := unm.CommandManager.Run(ctx, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", httpProbeScript, "sh", probe.URL, strconv.Itoa(seconds), insecure, connect, servername, strconv.Itoa(maxProbeBody)},
+	})
+	if err != nil {
+		return HTTPProbeResult{}, fmt.Errorf("HTTP probe of %s failed: %w: %s", probe.URL, err, strings.TrimSpace(output.STDERR))
+	}
+
+	result, body := parseHTTPProbe(output.STDOUT)
+	result.URL = probe.URL
+	result.BodyMatched = result.StatusCode != 0 && (bodyMatch == nil || bodyMatch.MatchString(body))
+	return result, nil
+}
+
+// parseHTTPProbe parses the key=value lines printed by httpProbeScript and
+// returns the body that follows them.
+func parseHTTPProbe(output string) (HTTPProbeResult, string) {
+	var result HTTPProbeResult
+	var start, end int64
+
+	header, body, _ := strings.Cut(output, "\nbody\n")
+	for _, line := range strings.Split(header, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "method":
+			result.Method = value
+		case "status":
+			// curl reports 000 when no response was received.
+			result.StatusCode, _ = strconv.Atoi(value)
+		case "latency":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+				result.Latency = time.Duration(seconds * float64(time.Second))
+			}
+		case "start":
+			start, _ = strconv.ParseInt(value, 10, 64)
+		case "end":
+			end, _ = strconv.ParseInt(value, 10, 64)
+		case "error":
+			result.Error = strings.TrimSpace(value)
+		case "tls_expiry":
+			// openssl prints dates such as "Jan  2 15:04:05 2026 GMT".
+			if expiry, err := time.Parse("Jan 2 15:04:05 2006 MST", strings.Join(strings.Fields(value), " ")); err == nil {
+				result.TLSExpiry = expiry.UTC()
+			}
+		}
+	}
+
+	if result.Latency == 0 && start > 0 && end >= start {
+		result.Latency = time.Duration(end - start)
+	}
+	if result.StatusCode == 0 && result.Error == "" {
+		result.Error = "no response"
+	}
+	return result, body
+}
@@ -0,0 +1,60 @@
+package networkmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHTTPProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected HTTPProbeResult
+		body     string
+	}{
+		{
+			name:     "curl with certificate",
+			output:   "method=curl\nstatus=200\nlatency=0.052\ntls_expiry=Mar  5 12:00:00 2027 GMT\nbody\n{\"status\":\"ok\"}\n",
+			expected: HTTPProbeResult{StatusCode: 200, Latency: 52 * time.Millisecond, TLSExpiry: time.Date(2027, 3, 5, 12, 0, 0, 0, time.UTC), Method: "curl"},
+			body:     "{\"status\":\"ok\"}\n",
+		},
+		{
+			name:     "curl connection refused",
+			output:   "method=curl\nstatus=000\nlatency=0.000181\nerror=curl: (7) Failed to connect to 127.0.0.1 port 1 after 0 ms: Couldn't connect to server\nbody\n",
+			expected: HTTPProbeResult{Latency: 181 * time.Microsecond, Method: "curl", Error: "curl: (7) Failed to connect to 127.0.0.1 port 1 after 0 ms: Couldn't connect to server"},
+		},
+		{
+			name:     "wget",
+			output:   "method=wget\nstart=1000000000\nend=1030000000\nstatus=503\ntls_expiry=\nbody\n",
+			expected: HTTPProbeResult{StatusCode: 503, Latency: 30 * time.Millisecond, Method: "wget"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, body := parseHTTPProbe(tt.output)
+			if result != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+			if body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+}
+
+func TestProbeHTTPBodyMatch(t *testing.T) {
+	unm := &UnixNetworkManager{CommandManager: &MockCommandManager{Output: "method=curl\nstatus=200\nlatency=0.01\nbody\nstatus: degraded\n"}}
+
+	result, err := unm.ProbeHTTP(HTTPProbe{URL: "http://localhost:8080/healthz", BodyMatch: `status: (ok|up)`})
+	if err != nil {
+		t.Fatalf("Error probing: %v", err)
+	}
+	if result.StatusCode != 200 || result.BodyMatched {
+		t.Errorf("Expected a 200 response with an unmatched body, got %+v", result)
+	}
+
+	if _, err := unm.ProbeHTTP(HTTPProbe{URL: "ftp://localhost/"}); err == nil {
+		t.Error("Expected an error for a non-HTTP URL")
+	}
+}
@@ -0,0 +1,420 @@
+package networkmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// InterfacesCommand lists interfaces as JSON with iproute2, falling back to
+// ifconfig where ip is missing or too old for -j, as on macOS. Its output is
+// parsed by ParseInterfaces.
+const InterfacesCommand = "ip -j addr show 2>/dev/null || ifconfig -a"
+
+// resolvedStubs are the addresses systemd-resolved listens on. A resolv.conf
+// pointing at them says nothing about the servers actually used.
+var resolvedStubs = map[string]bool{"127.0.0.53": true, "127.0.0.54": true}
+
+func (unm *UnixNetworkManager) Interfaces() ([]Interface, error) {
+	output, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", InterfacesCommand},
+		Env:     []string{"LC_ALL=C"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w: %s", err, strings.TrimSpace(output.STDERR))
+	}
+	return ParseInterfaces(output.STDOUT)
+}
+
+func (unm *UnixNetworkManager) Routes() ([]Route, error) {
+	results, err := cm.RunBatch(context.TODO(), unm.CommandManager, []cm.CommandConfig{
+		{Command: "ip", Args: []string{"-j", "-4", "route", "show"}},
+		{Command: "ip", Args: []string{"-j", "-6", "route", "show"}},
+		{Command: "netstat", Args: []string{"-rn"}, Env: []string{"LC_ALL=C"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if results[0].ExitCode == 0 && strings.HasPrefix(strings.TrimSpace(results[0].STDOUT), "[") {
+		routes, err := parseIPRoutes(results[0].STDOUT, "inet")
+		if err != nil {
+			return nil, err
+		}
+		// IPv6 may be disabled, in which case there are no IPv6 routes.
+		if results[1].ExitCode == 0 {
+			routes6, err := parseIPRoutes(results[1].STDOUT, "inet6")
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, routes6...)
+		}
+		return routes, nil
+	}
+
+	if results[2].ExitCode == 0 {
+		return parseNetstatRoutes(results[2].STDOUT), nil
+	}
+	return nil, fmt.Errorf("failed to list routes: %s", strings.TrimSpace(results[0].STDERR+" "+results[2].STDERR))
+}
+
+func (unm *UnixNetworkManager) DNSConfig() (DNSConfig, error) {
+	results, err := cm.RunBatch(context.TODO(), unm.CommandManager, []cm.CommandConfig{
+		{Command: "cat", Args: []string{"/etc/resolv.conf"}},
+		{Command: "resolvectl", Args: []string{"dns"}},
+		{Command: "resolvectl", Args: []string{"domain"}},
+	})
+	if err != nil {
+		return DNSConfig{}, err
+	}
+	if results[0].ExitCode != 0 {
+		return DNSConfig{}, fmt.Errorf("failed to read /etc/resolv.conf: %s", strings.TrimSpace(results[0].STDERR))
+	}
+
+	config := ParseResolvConf(results[0].STDOUT)
+	if !usesResolvedStub(config.Servers) || results[1].ExitCode != 0 {
+		return config, nil
+	}
+
+	config.Servers = parseResolvectl(results[1].STDOUT)
+	if results[2].ExitCode == 0 {
+		config.Search = nil
+		for _, domain := range parseResolvectl(results[2].STDOUT) {
+			// Domains starting with ~ only route queries and are not searched.
+			if !strings.HasPrefix(domain, "~") {
+				config.Search = append(config.Search, domain)
+			}
+		}
+	}
+	config.Source = "systemd-resolved"
+	return config, nil
+}
+
+func usesResolvedStub(servers []string) bool {
+	if len(servers) == 0 {
+		return false
+	}
+	for _, server := range servers {
+		if !resolvedStubs[server] {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseInterfaces parses the output of InterfacesCommand, which is either
+// ip -j addr JSON or ifconfig -a text.
+func ParseInterfaces(output string) ([]Interface, error) {
+	if strings.HasPrefix(strings.TrimSpace(output), "[") {
+		return parseIPAddr(output)
+	}
+	return parseIfconfig(output), nil
+}
+
+func parseIPAddr(output string) ([]Interface, error) {
+	var links []struct {
+		Name      string   `json:"ifname"`
+		Flags     []string `json:"flags"`
+		MTU       int      `json:"mtu"`
+		OperState string   `json:"operstate"`
+		Address   string   `json:"address"`
+		LinkType  string   `json:"link_type"`
+		AddrInfo  []struct {
+			Family    string `json:"family"`
+			Local     string `json:"local"`
+			PrefixLen int    `json:"prefixlen"`
+			Scope     string `json:"scope"`
+		} `json:"addr_info"`
+	}
+	if err := json.Unmarshal([]byte(output), &links); err != nil {
+		return nil, fmt.Errorf("failed to parse ip addr output: %w", err)
+	}
+
+	interfaces := make([]Interface, 0, len(links))
+	for _, link := range links {
+		iface := Interface{
+			Name:  link.Name,
+			MTU:   link.MTU,
+			State: strings.ToLower(link.OperState),
+		}
+		if link.LinkType != "loopback" {
+			iface.MAC = link.Address
+		}
+		// Loopback and tunnel devices have no carrier and report UNKNOWN
+		// even when they are in use.
+		if iface.State == "unknown" && hasFlag(link.Flags, "UP") && hasFlag(link.Flags, "LOWER_UP") {
+			iface.State = "up"
+		}
+		for _, addr := range link.AddrInfo {
+			iface.Addresses = append(iface.Addresses, Address{
+				Family:    addr.Family,
+				IP:        addr.Local,
+				PrefixLen: addr.PrefixLen,
+				Scope:     addr.Scope,
+			})
+		}
+		interfaces = append(interfaces, iface)
+	}
+	return interfaces, nil
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfconfig parses the ifconfig -a output of macOS and of current
+// net-tools on Linux, which share the "name: flags=...<...> mtu N" layout.
+func parseIfconfig(output string) []Interface {
+	var interfaces []Interface
+	var current *Interface
+	var flags []string
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		if current.State == "" {
+			current.State = "down"
+			if hasFlag(flags, "UP") && hasFlag(flags, "RUNNING") {
+				current.State = "up"
+			}
+		}
+		interfaces = append(interfaces, *current)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			name, rest, found := strings.Cut(line, ": flags=")
+			if !found {
+				continue
+			}
+			finish()
+			current = &Interface{Name: name}
+			flags = nil
+			if _, list, ok := strings.Cut(rest, "<"); ok {
+				list, _, _ = strings.Cut(list, ">")
+				flags = strings.Split(list, ",")
+			}
+			fields := strings.Fields(rest)
+			for i, field := range fields {
+				if field == "mtu" && i+1 < len(fields) {
+					current.MTU, _ = strconv.Atoi(fields[i+1])
+				}
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "ether":
+			current.MAC = fields[1]
+		case "status:":
+			// macOS reports the link state separately from the flags.
+			if fields[1] == "active" {
+				current.State = "up"
+			} else {
+				current.State = "down"
+			}
+		case "inet":
+			ip := fields[1]
+			prefix := 32
+			if mask := fieldAfter(fields, "netmask"); mask != "" {
+				prefix = maskPrefix(mask)
+			}
+			current.Addresses = append(current.Addresses, Address{Family: "inet", IP: ip, PrefixLen: prefix, Scope: addressScope(ip)})
+		case "inet6":
+			ip, _, _ := strings.Cut(fields[1], "%")
+			prefix, _ := strconv.Atoi(fieldAfter(fields, "prefixlen"))
+			current.Addresses = append(current.Addresses, Address{Family: "inet6", IP: ip, PrefixLen: prefix, Scope: addressScope(ip)})
+		}
+	}
+	finish()
+
+	return interfaces
+}
+
+func fieldAfter(fields []string, name string) string {
+	for i, field := range fields {
+		if field == name && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+// maskPrefix converts a netmask, dotted (255.255.255.0) or hex (0xffffff00)
+// as macOS prints it, to a prefix length.
+func maskPrefix(mask string) int {
+	if hex, found := strings.CutPrefix(mask, "0x"); found {
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return 0
+		}
+		ones, _ := net.IPv4Mask(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).Size()
+		return ones
+	}
+	ip := net.ParseIP(mask).To4()
+	if ip == nil {
+		return 0
+	}
+	ones, _ := net.IPMask(ip).Size()
+	return ones
+}
+
+// addressScope derives the scope that ip addr reports from the address itself.
+func addressScope(address string) string {
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.IsLoopback():
+		return "host"
+	case ip.IsLinkLocalUnicast():
+		return "link"
+	}
+	return "global"
+}
+
+func parseIPRoutes(output, family string) ([]Route, error) {
+	var entries []struct {
+		Dst     string `json:"dst"`
+		Gateway string `json:"gateway"`
+		Dev     string `json:"dev"`
+		Metric  int    `json:"metric"`
+	}
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse ip route output: %w", err)
+	}
+
+	routes := make([]Route, 0, len(entries))
+	for _, entry := range entries {
+		routes = append(routes, Route{
+			Family:      family,
+			Destination: entry.Dst,
+			Gateway:     entry.Gateway,
+			Interface:   entry.Dev,
+			Metric:      entry.Metric,
+		})
+	}
+	return routes, nil
+}
+
+// parseNetstatRoutes parses netstat -rn from macOS, which lists "Internet:"
+// and "Internet6:" sections, and from Linux net-tools, which has a Genmask column.
+func parseNetstatRoutes(output string) []Route {
+	var routes []Route
+	family := "inet"
+	linux, table := false, false
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case fields[0] == "Internet:":
+			family = "inet"
+			continue
+		case fields[0] == "Internet6:":
+			family = "inet6"
+			continue
+		case fields[0] == "Destination":
+			linux, table = hasFlag(fields, "Genmask"), true
+			continue
+		case !table:
+			// Titles such as "Kernel IP routing table" come before the header.
+			continue
+		}
+
+		if linux {
+			if len(fields) < 8 {
+				continue
+			}
+			route := Route{Family: "inet", Destination: fields[0], Interface: fields[7]}
+			if fields[1] != "0.0.0.0" {
+				route.Gateway = fields[1]
+			}
+			if prefix := maskPrefix(fields[2]); fields[0] == "0.0.0.0" && prefix == 0 {
+				route.Destination = "default"
+			} else {
+				route.Destination = fields[0] + "/" + strconv.Itoa(prefix)
+			}
+			routes = append(routes, route)
+			continue
+		}
+
+		if len(fields) < 4 {
+			continue
+		}
+		route := Route{Family: family, Destination: fields[0], Interface: fields[3]}
+		// Directly connected networks have link#N as their gateway.
+		if !strings.HasPrefix(fields[1], "link#") {
+			route.Gateway, _, _ = strings.Cut(fields[1], "%")
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// ParseResolvConf parses the nameserver, search, domain and options lines of
+// /etc/resolv.conf.
+func ParseResolvConf(content string) DNSConfig {
+	config := DNSConfig{Source: "resolv.conf"}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			config.Servers = append(config.Servers, fields[1])
+		case "search", "domain":
+			config.Search = append(config.Search, fields[1:]...)
+		case "options":
+			config.Options = append(config.Options, fields[1:]...)
+		}
+	}
+	return config
+}
+
+// parseResolvectl parses resolvectl dns or domain output, which lists values
+// per scope as "Global: a b" and "Link 2 (eth0): c", skipping duplicates.
+func parseResolvectl(output string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		var rest string
+		if _, after, found := strings.Cut(line, "): "); found && strings.HasPrefix(line, "Link ") {
+			rest = after
+		} else if after, found := strings.CutPrefix(line, "Global:"); found {
+			rest = after
+		}
+		for _, value := range strings.Fields(rest) {
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,152 @@
+package networkmanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+const darwinIfconfig = `lo0: flags=8049<UP,LOOPBACK,RUNNING,MULTICAST> mtu 16384
+	options=1203<RXCSUM,TXCSUM,TXSTATUS,SW_TIMESTAMP>
+	inet 127.0.0.1 netmask 0xff000000
+	inet6 ::1 prefixlen 128
+	inet6 fe80::1%lo0 prefixlen 64 scopeid 0x1
+en0: flags=8863<UP,BROADCAST,SMART,RUNNING,SIMPLEX,MULTICAST> mtu 1500
+	ether a4:83:e7:12:34:56
+	inet6 fe80::1c2b:3a4d:5e6f:7081%en0 prefixlen 64 secured scopeid 0x6
+	inet 192.168.1.20 netmask 0xffffff00 broadcast 192.168.1.255
+	nd6 options=201<PERFORMNUD,DAD>
+	media: autoselect
+	status: active
+en1: flags=8863<UP,BROADCAST,SMART,RUNNING,SIMPLEX,MULTICAST> mtu 1500
+	ether 82:12:34:56:78:9a
+	status: inactive
+`
+
+func TestParseInterfacesIfconfig(t *testing.T) {
+	interfaces, err := ParseInterfaces(darwinIfconfig)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Interface{
+		{Name: "lo0", MTU: 16384, State: "up", Addresses: []Address{
+			{Family: "inet", IP: "127.0.0.1", PrefixLen: 8, Scope: "host"},
+			{Family: "inet6", IP: "::1", PrefixLen: 128, Scope: "host"},
+			{Family: "inet6", IP: "fe80::1", PrefixLen: 64, Scope: "link"},
+		}},
+		{Name: "en0", MAC: "a4:83:e7:12:34:56", MTU: 1500, State: "up", Addresses: []Address{
+			{Family: "inet6", IP: "fe80::1c2b:3a4d:5e6f:7081", PrefixLen: 64, Scope: "link"},
+			{Family: "inet", IP: "192.168.1.20", PrefixLen: 24, Scope: "global"},
+		}},
+		{Name: "en1", MAC: "82:12:34:56:78:9a", MTU: 1500, State: "down"},
+	}
+	if !reflect.DeepEqual(interfaces, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, interfaces)
+	}
+}
+
+func TestParseInterfacesIPJSON(t *testing.T) {
+	output := `[{"ifindex":1,"ifname":"lo","flags":["LOOPBACK","UP","LOWER_UP"],"mtu":65536,"operstate":"UNKNOWN","link_type":"loopback","address":"00:00:00:00:00:00","addr_info":[{"family":"inet","local":"127.0.0.1","prefixlen":8,"scope":"host"}]},
+{"ifindex":2,"ifname":"eth0","flags":["BROADCAST","MULTICAST","UP","LOWER_UP"],"mtu":9000,"operstate":"UP","link_type":"ether","address":"52:54:00:12:34:56","addr_info":[{"family":"inet","local":"10.0.0.5","prefixlen":24,"scope":"global"},{"family":"inet6","local":"fd00::5","prefixlen":64,"scope":"global"}]},
+{"ifindex":3,"ifname":"eth1","flags":["BROADCAST","MULTICAST"],"mtu":1500,"operstate":"DOWN","link_type":"ether","address":"52:54:00:12:34:57","addr_info":[]}]`
+
+	interfaces, err := ParseInterfaces(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Interface{
+		{Name: "lo", MTU: 65536, State: "up", Addresses: []Address{{Family: "inet", IP: "127.0.0.1", PrefixLen: 8, Scope: "host"}}},
+		{Name: "eth0", MAC: "52:54:00:12:34:56", MTU: 9000, State: "up", Addresses: []Address{
+			{Family: "inet", IP: "10.0.0.5", PrefixLen: 24, Scope: "global"},
+			{Family: "inet6", IP: "fd00::5", PrefixLen: 64, Scope: "global"},
+		}},
+		{Name: "eth1", MAC: "52:54:00:12:34:57", MTU: 1500, State: "down"},
+	}
+	if !reflect.DeepEqual(interfaces, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, interfaces)
+	}
+}
+
+func TestRoutesIP(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"ip -j -4 route show": `[{"dst":"default","gateway":"10.0.0.1","dev":"eth0","protocol":"dhcp","metric":100,"flags":[]},{"dst":"10.0.0.0/24","dev":"eth0","protocol":"kernel","scope":"link","prefsrc":"10.0.0.5","flags":[]}]`,
+		"ip -j -6 route show": `[{"dst":"fd00::/64","dev":"eth0","protocol":"kernel","metric":256,"flags":[],"pref":"medium"}]`,
+	}}
+	manager := &UnixNetworkManager{CommandManager: mockCmd}
+
+	routes, err := manager.Routes()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Route{
+		{Family: "inet", Destination: "default", Gateway: "10.0.0.1", Interface: "eth0", Metric: 100},
+		{Family: "inet", Destination: "10.0.0.0/24", Interface: "eth0"},
+		{Family: "inet6", Destination: "fd00::/64", Interface: "eth0", Metric: 256},
+	}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, routes)
+	}
+}
+
+func TestParseNetstatRoutes(t *testing.T) {
+	darwin := `Routing tables
+
+Internet:
+Destination        Gateway            Flags               Netif Expire
+default            192.168.1.1        UGScg                 en0
+127                127.0.0.1          UCS                   lo0
+192.168.1          link#6             UCS                   en0      !
+
+Internet6:
+Destination                             Gateway                                 Flags               Netif Expire
+default                                 fe80::1%en0                             UGcg                  en0
+`
+	expected := []Route{
+		{Family: "inet", Destination: "default", Gateway: "192.168.1.1", Interface: "en0"},
+		{Family: "inet", Destination: "127", Gateway: "127.0.0.1", Interface: "lo0"},
+		{Family: "inet", Destination: "192.168.1", Interface: "en0"},
+		{Family: "inet6", Destination: "default", Gateway: "fe80::1", Interface: "en0"},
+	}
+	if routes := parseNetstatRoutes(darwin); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, routes)
+	}
+
+	linux := `Kernel IP routing table
+Destination     Gateway         Genmask         Flags   MSS Window  irtt Iface
+0.0.0.0         192.0.2.1       0.0.0.0         UG        0 0          0 eth0
+192.0.2.0       0.0.0.0         255.255.255.0   U         0 0          0 eth0
+`
+	expected = []Route{
+		{Family: "inet", Destination: "default", Gateway: "192.0.2.1", Interface: "eth0"},
+		{Family: "inet", Destination: "192.0.2.0/24", Interface: "eth0"},
+	}
+	if routes := parseNetstatRoutes(linux); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, routes)
+	}
+}
+
+func TestDNSConfigResolved(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"cat /etc/resolv.conf": "# This is /run/systemd/resolve/stub-resolv.conf\nnameserver 127.0.0.53\noptions edns0 trust-ad\nsearch .\n",
+		"resolvectl dns":       "Global: 1.1.1.1\nLink 2 (eth0): 10.0.0.2 fe80::1%eth0 1.1.1.1\n",
+		"resolvectl domain":    "Global:\nLink 2 (eth0): corp.example.com ~.\n",
+	}}
+	manager := &UnixNetworkManager{CommandManager: mockCmd}
+
+	config, err := manager.DNSConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := DNSConfig{
+		Servers: []string{"1.1.1.1", "10.0.0.2", "fe80::1%eth0"},
+		Search:  []string{"corp.example.com"},
+		Options: []string{"edns0", "trust-ad"},
+		Source:  "systemd-resolved",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config)
+	}
+}
@@ -0,0 +1,175 @@
+package networkmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// listeningScript lists sockets with the first of ss, lsof and netstat found,
+// announcing which one on the first line. lsof exits 1 when nothing matches.
+const listeningScript = `if command -v ss >/dev/null 2>&1; then echo '#ss'; exec ss -tulpnH; fi
+if command -v lsof >/dev/null 2>&1; then echo '#lsof'; lsof -nP -iTCP -sTCP:LISTEN -iUDP -FpcPn; exit 0; fi
+echo '#netstat'; exec netstat -an`
+
+// ssUser matches the first owner in ss's users:(("sshd",pid=900,fd=3),...).
+var ssUser = regexp.MustCompile(`\("([^"]*)",pid=(\d+)`)
+
+// ListeningSockets runs as root, as only root may see which process owns
+// another user's socket.
+func (unm *UnixNetworkManager) ListeningSockets() ([]Socket, error) {
+	result, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", listeningScript},
+		Sudo:    true,
+		Env:     []string{"LC_ALL=C"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list listening sockets: %w: %s", err, strings.TrimSpace(result.STDERR))
+	}
+
+	tool, output, _ := strings.Cut(result.STDOUT, "\n")
+	var sockets []Socket
+	switch strings.TrimSpace(tool) {
+	case "#ss":
+		sockets = parseSS(output)
+	case "#lsof":
+		sockets = parseLsof(output)
+	case "#netstat":
+		sockets = parseNetstatSockets(output)
+	default:
+		return nil, fmt.Errorf("unexpected output listing sockets: %q", tool)
+	}
+
+	sort.SliceStable(sockets, func(i, j int) bool {
+		a, b := sockets[i], sockets[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.LocalPort != b.LocalPort {
+			return a.LocalPort < b.LocalPort
+		}
+		return a.LocalAddress < b.LocalAddress
+	})
+	return sockets, nil
+}
+
+// splitHostPort splits addresses such as 0.0.0.0:22, [::]:22, *:22 and
+// 127.0.0.53%lo:53 at the last separator, dropping brackets and zones.
+func splitHostPort(address string, separator string) (string, int, bool) {
+	i := strings.LastIndex(address, separator)
+	if i < 0 {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(address[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	host := strings.Trim(address[:i], "[]")
+	host, _, _ = strings.Cut(host, "%")
+	return host, port, true
+}
+
+// parseSS parses ss -tulpnH output:
+// tcp LISTEN 0 4096 0.0.0.0:22 0.0.0.0:* users:(("sshd",pid=900,fd=3))
+func parseSS(output string) []Socket {
+	var sockets []Socket
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || (fields[0] != "tcp" && fields[0] != "udp") {
+			continue
+		}
+		host, port, ok := splitHostPort(fields[4], ":")
+		if !ok {
+			continue
+		}
+		socket := Socket{Protocol: fields[0], LocalAddress: host, LocalPort: port}
+		if match := ssUser.FindStringSubmatch(line); match != nil {
+			socket.Process = match[1]
+			socket.PID, _ = strconv.Atoi(match[2])
+		}
+		sockets = append(sockets, socket)
+	}
+	return sockets
+}
+
+// parseLsof parses lsof -F pcPn output, where each line is a field tag and a
+// value. A process (p, c) is followed by its files (P, n). Sockets shared
+// by several processes, as after a fork, are kept once.
+func parseLsof(output string) []Socket {
+	var sockets []Socket
+	seen := make(map[string]bool)
+	var pid int
+	var process, protocol string
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		value := line[1:]
+		switch line[0] {
+		case 'p':
+			pid, _ = strconv.Atoi(value)
+			process, protocol = "", ""
+		case 'c':
+			process = value
+		case 'P':
+			protocol = strings.ToLower(value)
+		case 'n':
+			// Connected UDP sockets are shown with their peer.
+			if strings.Contains(value, "->") || (protocol != "tcp" && protocol != "udp") {
+				continue
+			}
+			host, port, ok := splitHostPort(value, ":")
+			if !ok {
+				continue
+			}
+			key := protocol + " " + host + " " + strconv.Itoa(port)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			sockets = append(sockets, Socket{Protocol: protocol, LocalAddress: host, LocalPort: port, PID: pid, Process: process})
+		}
+	}
+	return sockets
+}
+
+// parseNetstatSockets parses netstat -an output, which does not name the
+// owning process. macOS separates the port with a dot, as in *.22 or
+// ::1.631, where Linux uses a colon.
+func parseNetstatSockets(output string) []Socket {
+	var sockets []Socket
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		protocol := strings.TrimRight(fields[0], "46")
+		if protocol != "tcp" && protocol != "udp" {
+			continue
+		}
+		if protocol == "tcp" && fields[len(fields)-1] != "LISTEN" {
+			continue
+		}
+		// Unconnected UDP sockets have no peer.
+		if peer := fields[4]; protocol == "udp" && peer != "*.*" && !strings.HasSuffix(peer, ":*") {
+			continue
+		}
+
+		address, separator := fields[3], ":"
+		if strings.LastIndex(address, ".") > strings.LastIndex(address, ":") {
+			separator = "."
+		}
+		host, port, ok := splitHostPort(address, separator)
+		if !ok {
+			continue
+		}
+		sockets = append(sockets, Socket{Protocol: protocol, LocalAddress: host, LocalPort: port})
+	}
+	return sockets
+}
@@ -0,0 +1,103 @@
+package networkmanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListeningSocketsSS(t *testing.T) {
+	mockCmd := &MockCommandManager{Output: `#ss
+udp   UNCONN 0      0      127.0.0.53%lo:53        0.0.0.0:*    users:(("systemd-resolve",pid=612,fd=13))
+tcp   LISTEN 0      4096         0.0.0.0:22        0.0.0.0:*    users:(("sshd",pid=900,fd=3),("systemd",pid=1,fd=47))
+tcp   LISTEN 0      128             [::]:22           [::]:*    users:(("sshd",pid=900,fd=4))
+tcp   LISTEN 0      511                *:80              *:*
+`}
+	manager := &UnixNetworkManager{CommandManager: mockCmd}
+
+	sockets, err := manager.ListeningSockets()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []Socket{
+		{Protocol: "tcp", LocalAddress: "0.0.0.0", LocalPort: 22, PID: 900, Process: "sshd"},
+		{Protocol: "tcp", LocalAddress: "::", LocalPort: 22, PID: 900, Process: "sshd"},
+		{Protocol: "tcp", LocalAddress: "*", LocalPort: 80},
+		{Protocol: "udp", LocalAddress: "127.0.0.53", LocalPort: 53, PID: 612, Process: "systemd-resolve"},
+	}
+	if !reflect.DeepEqual(sockets, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sockets)
+	}
+	if !mockCmd.Configs[0].Sudo {
+		t.Error("Expected sockets to be listed as root")
+	}
+}
+
+func TestParseLsof(t *testing.T) {
+	output := `p301
+claunchd
+f12
+PTCP
+n*:22
+p1150
+cmDNSResponder
+f5
+PUDP
+n*:5353
+f9
+PUDP
+n192.168.1.20:60123->192.168.1.1:53
+p1802
+cpostgres
+f7
+PTCP
+n[::1]:5432
+p1803
+cpostgres
+f7
+PTCP
+n[::1]:5432
+`
+	expected := []Socket{
+		{Protocol: "tcp", LocalAddress: "*", LocalPort: 22, PID: 301, Process: "launchd"},
+		{Protocol: "udp", LocalAddress: "*", LocalPort: 5353, PID: 1150, Process: "mDNSResponder"},
+		{Protocol: "tcp", LocalAddress: "::1", LocalPort: 5432, PID: 1802, Process: "postgres"},
+	}
+	if sockets := parseLsof(output); !reflect.DeepEqual(sockets, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sockets)
+	}
+}
+
+func TestParseNetstatSockets(t *testing.T) {
+	darwin := `Active Internet connections (including servers)
+Proto Recv-Q Send-Q  Local Address          Foreign Address        (state)
+tcp4       0      0  192.168.1.20.52114     17.57.146.52.5223      ESTABLISHED
+tcp46      0      0  *.22                   *.*                    LISTEN
+tcp6       0      0  ::1.631                *.*                    LISTEN
+udp4       0      0  *.5353                 *.*
+udp4       0      0  192.168.1.20.60123     192.168.1.1.53
+`
+	expected := []Socket{
+		{Protocol: "tcp", LocalAddress: "*", LocalPort: 22},
+		{Protocol: "tcp", LocalAddress: "::1", LocalPort: 631},
+		{Protocol: "udp", LocalAddress: "*", LocalPort: 5353},
+	}
+	if sockets := parseNetstatSockets(darwin); !reflect.DeepEqual(sockets, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sockets)
+	}
+
+	linux := `Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State
+tcp        0      0 0.0.0.0:22              0.0.0.0:*               LISTEN
+tcp6       0      0 :::80                   :::*                    LISTEN
+udp        0      0 0.0.0.0:68              0.0.0.0:*
+`
+	expected = []Socket{
+		{Protocol: "tcp", LocalAddress: "0.0.0.0", LocalPort: 22},
+		{Protocol: "tcp", LocalAddress: "::", LocalPort: 80},
+		{Protocol: "udp", LocalAddress: "0.0.0.0", LocalPort: 68},
+	}
+	if sockets := parseNetstatSockets(linux); !reflect.DeepEqual(sockets, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, sockets)
+	}
+}
@@ -0,0 +1,336 @@
+package networkmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+const (
+	HostsPath      = "/etc/hosts"
+	ResolvConfPath = "/etc/resolv.conf"
+
+	// Drop-in files written by EnsureResolvers. NetworkManager's global DNS
+	// settings take precedence over those of individual connections.
+	NetworkManagerDNSPath  = "/etc/NetworkManager/conf.d/90-steelcut-dns.conf"
+	ResolvedDropInPath     = "/etc/systemd/resolved.conf.d/90-steelcut.conf"
+	resolverManagedComment = "# Managed by steelcut\n"
+
+	// maxResolvConfServers is how many nameserver lines the C library reads.
+	maxResolvConfServers = 3
+)
+
+// hostName matches a host name or alias as accepted in /etc/hosts.
+var hostName = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_])?$`)
+
+// resolverScript reports what manages /etc/resolv.conf. NetworkManager only
+// counts when it writes resolv.conf itself or feeds systemd-resolved.
+const resolverScript = `stub=
+readlink /etc/resolv.conf 2>/dev/null | grep -q '/run/systemd/resolve/' && stub=1
+if [ "$(uname -s)" = Darwin ]; then echo darwin
+elif command -v nmcli >/dev/null 2>&1 && [ "$(nmcli -t -f RUNNING general 2>/dev/null)" = running ] && { [ -n "$stub" ] || grep -q NetworkManager /etc/resolv.conf 2>/dev/null; }; then echo networkmanager
+elif [ -n "$stub" ]; then echo systemd-resolved
+elif [ -L /etc/resolv.conf ]; then echo "symlink $(readlink /etc/resolv.conf)"
+else echo resolv.conf
+fi`
+
+// resolveScript uses getent, which goes through NSS like other programs do,
+// and falls back to dscacheutil on macOS.
+const resolveScript = `if command -v getent >/dev/null 2>&1; then getent ahosts "$1"; exit 0; fi
+dscacheutil -q host -a name "$1"`
+
+func (unm *UnixNetworkManager) EnsureHostsEntry(ip string, names ...string) (bool, error) {
+	if net.ParseIP(ip) == nil {
+		return false, fmt.Errorf("invalid IP address: %q", ip)
+	}
+	if len(names) == 0 {
+		return false, fmt.Errorf("no names given for %s", ip)
+	}
+	if err := validateHostNames(names); err != nil {
+		return false, err
+	}
+	return unm.updateFile(HostsPath, func(content string) string {
+		return updateHosts(content, ip, names, false)
+	})
+}
+
+func (unm *UnixNetworkManager) RemoveHostsEntry(ip string, names ...string) (bool, error) {
+	if net.ParseIP(ip) == nil {
+		return false, fmt.Errorf("invalid IP address: %q", ip)
+	}
+	if err := validateHostNames(names); err != nil {
+		return false, err
+	}
+	return unm.updateFile(HostsPath, func(content string) string {
+		return updateHosts(content, ip, names, true)
+	})
+}
+
+func validateHostNames(names []string) error {
+	for _, name := range names {
+		if !hostName.MatchString(name) {
+			return fmt.Errorf("invalid host name: %q", name)
+		}
+	}
+	return nil
+}
+
+func (unm *UnixNetworkManager) EnsureResolvers(servers, searchDomains []string) (bool, error) {
+	if len(servers) == 0 {
+		return false, fmt.Errorf("no DNS servers given")
+	}
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return false, fmt.Errorf("invalid DNS server: %q", server)
+		}
+	}
+	if err := validateHostNames(searchDomains); err != nil {
+		return false, err
+	}
+
+	result, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", resolverScript},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to detect the resolver configuration: %w: %s", err, strings.TrimSpace(result.STDERR))
+	}
+
+	switch manager := strings.TrimSpace(result.STDOUT); {
+	case manager == "networkmanager":
+		content := resolverManagedComment + "[global-dns]\nsearches=" + strings.Join(searchDomains, ",") +
+			"\n\n[global-dns-domain-*]\nservers=" + strings.Join(servers, ",") + "\n"
+		return unm.writeResolverDropIn(NetworkManagerDNSPath, content, "reload", "NetworkManager")
+
+	case manager == "systemd-resolved":
+		content := resolverManagedComment + "[Resolve]\nDNS=" + strings.Join(servers, " ") +
+			"\nDomains=" + strings.Join(searchDomains, " ") + "\n"
+		return unm.writeResolverDropIn(ResolvedDropInPath, content, "restart", "systemd-resolved")
+
+	case manager == "resolv.conf":
+		if len(servers) > maxResolvConfServers {
+			return false, fmt.Errorf("resolv.conf only supports %d servers, got %d", maxResolvConfServers, len(servers))
+		}
+		return unm.updateFile(ResolvConfPath, func(content string) string {
+			return updateResolvConf(content, servers, searchDomains)
+		})
+
+	case strings.HasPrefix(manager, "symlink "):
+		return false, fmt.Errorf("%s links to %s, which steelcut does not know how to manage", ResolvConfPath, strings.TrimPrefix(manager, "symlink "))
+
+	case manager == "darwin":
+		return false, fmt.Errorf("managing resolvers is not supported on macOS")
+
+	default:
+		return false, fmt.Errorf("unexpected resolver detection output: %q", manager)
+	}
+}
+
+// writeResolverDropIn writes a configuration drop-in and has service pick it
+// up, doing neither when the drop-in is already up to date.
+func (unm *UnixNetworkManager) writeResolverDropIn(path, content, action, service string) (bool, error) {
+	changed, err := unm.updateFile(path, func(string) string { return content })
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	result, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "systemctl",
+		Args:    []string{action, service},
+		Sudo:    true,
+	})
+	if err != nil {
+		return true, fmt.Errorf("failed to %s %s: %w: %s", action, service, err, strings.TrimSpace(result.STDERR))
+	}
+	return true, nil
+}
+
+// updateFile applies update to the content of path, treating a missing file
+// as empty, and writes the result back only when it changed.
+func (unm *UnixNetworkManager) updateFile(path string, update func(content string) string) (bool, error) {
+	if unm.FileManager == nil {
+		return false, fmt.Errorf("cannot write %s: no file manager configured", path)
+	}
+
+	content, err := filemanager.ReadFileIfExists(unm.CommandManager, path, false)
+	if err != nil {
+		return false, err
+	}
+
+	updated := update(content)
+	if updated == content {
+		return false, nil
+	}
+
+	dir := path[:strings.LastIndex(path, "/")]
+	if result, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "mkdir",
+		Args:    []string{"-p", dir},
+		Sudo:    true,
+	}); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w: %s", dir, err, strings.TrimSpace(result.STDERR))
+	}
+	if err := unm.FileManager.WriteFileAtomic(path, []byte(updated), filemanager.AtomicWriteOptions{Mode: 0644}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (unm *UnixNetworkManager) ResolveName(name string) ([]string, error) {
+	if !hostName.MatchString(name) {
+		return nil, fmt.Errorf("invalid host name: %q", name)
+	}
+
+	result, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", resolveScript, "sh", name},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w: %s", name, err, strings.TrimSpace(result.STDERR))
+	}
+	return parseResolvedAddresses(result.STDOUT), nil
+}
+
+// parseResolvedAddresses collects the distinct addresses, in order, from
+// getent ahosts lines such as "10.0.0.5 STREAM db1" or dscacheutil lines
+// such as "ip_address: 10.0.0.5".
+func parseResolvedAddresses(output string) []string {
+	addresses := []string{}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		address := fields[0]
+		if fields[0] == "ip_address:" || fields[0] == "ipv6_address:" {
+			address = fields[1]
+		}
+		if net.ParseIP(address) == nil || seen[address] {
+			continue
+		}
+		seen[address] = true
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// updateHosts returns content with the entries for ip updated. When ensuring,
+// the first entry for ip is set to names and any further ones are dropped,
+// and names are taken off other addresses of the same family, so a name can
+// keep both an IPv4 and an IPv6 address. When removing, names are taken off
+// the entries for ip, or the entries are dropped when names is empty. Either
+// way, entries left without names are dropped, and lines that need no change
+// keep their formatting and comments.
+func updateHosts(content, ip string, names []string, remove bool) string {
+	target := net.ParseIP(ip)
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = true
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+	var updated []string
+	placed := false
+	for _, line := range lines {
+		body, comment, hasComment := strings.Cut(line, "#")
+		fields := strings.Fields(body)
+		address := net.ParseIP(firstField(fields))
+		if len(fields) < 2 || address == nil {
+			updated = append(updated, line)
+			continue
+		}
+
+		var keep []string
+		switch {
+		case address.Equal(target) && !remove && placed:
+			continue
+		case address.Equal(target) && !remove:
+			placed = true
+			keep = names
+		case address.Equal(target) && len(names) == 0:
+			continue
+		case address.Equal(target) || (!remove && (address.To4() == nil) == (target.To4() == nil)):
+			// Names that are being removed from ip, or moved to it from
+			// another address of the same family.
+			for _, name := range fields[1:] {
+				if !wanted[strings.ToLower(name)] {
+					keep = append(keep, name)
+				}
+			}
+		default:
+			keep = fields[1:]
+		}
+
+		switch {
+		case len(keep) == 0:
+			continue
+		case strings.Join(keep, " ") == strings.Join(fields[1:], " "):
+			updated = append(updated, line)
+		default:
+			entry := fields[0] + "\t" + strings.Join(keep, " ")
+			if hasComment {
+				entry += " #" + comment
+			}
+			updated = append(updated, entry)
+		}
+	}
+	if !remove && !placed {
+		updated = append(updated, ip+"\t"+strings.Join(names, " "))
+	}
+
+	if len(updated) == 0 {
+		return ""
+	}
+	return strings.Join(updated, "\n") + "\n"
+}
+
+func firstField(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// updateResolvConf replaces the nameserver, search and domain lines of a
+// resolv.conf, placing the new ones after any leading comments and keeping
+// everything else, such as options.
+func updateResolvConf(content string, servers, searchDomains []string) string {
+	var settings []string
+	if len(searchDomains) > 0 {
+		settings = append(settings, "search "+strings.Join(searchDomains, " "))
+	}
+	for _, server := range servers {
+		settings = append(settings, "nameserver "+server)
+	}
+
+	var header, rest []string
+	inHeader := true
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if content == "" {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		if inHeader && (strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";")) {
+			header = append(header, line)
+			continue
+		}
+		inHeader = false
+		switch firstField(strings.Fields(trimmed)) {
+		case "nameserver", "search", "domain":
+			continue
+		}
+		rest = append(rest, line)
+	}
+
+	lines := append(append(header, settings...), rest...)
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,206 @@
+package networkmanager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+// MockFileManager records atomic writes, embedding the interface so only
+// WriteFileAtomic needs implementing.
+type MockFileManager struct {
+	filemanager.FileManager
+	Writes map[string]string
+}
+
+func (m *MockFileManager) WriteFileAtomic(path string, content []byte, opts filemanager.AtomicWriteOptions) error {
+	if m.Writes == nil {
+		m.Writes = make(map[string]string)
+	}
+	m.Writes[path] = string(content)
+	return nil
+}
+
+const sampleHosts = `127.0.0.1	localhost
+127.0.1.1	web1.example.com web1
+
+# migration
+10.0.0.5   db-old db # primary database
+10.0.0.9	cache
+10.0.0.9	cache2
+::1	localhost ip6-localhost
+`
+
+func TestUpdateHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		names    []string
+		remove   bool
+		expected string
+	}{
+		{
+			name:  "move a name to a new address",
+			ip:    "10.0.0.6",
+			names: []string{"db"},
+			expected: `127.0.0.1	localhost
+127.0.1.1	web1.example.com web1
+
+# migration
+10.0.0.5	db-old # primary database
+10.0.0.9	cache
+10.0.0.9	cache2
+::1	localhost ip6-localhost
+10.0.0.6	db
+`,
+		},
+		{
+			name:  "replace the names of an address with several entries",
+			ip:    "10.0.0.9",
+			names: []string{"cache", "cache.example.com"},
+			expected: `127.0.0.1	localhost
+127.0.1.1	web1.example.com web1
+
+# migration
+10.0.0.5   db-old db # primary database
+10.0.0.9	cache cache.example.com
+::1	localhost ip6-localhost
+`,
+		},
+		{
+			name:   "remove a name",
+			ip:     "10.0.0.5",
+			names:  []string{"db-old"},
+			remove: true,
+			expected: `127.0.0.1	localhost
+127.0.1.1	web1.example.com web1
+
+# migration
+10.0.0.5	db # primary database
+10.0.0.9	cache
+10.0.0.9	cache2
+::1	localhost ip6-localhost
+`,
+		},
+		{
+			name:   "remove an address",
+			ip:     "10.0.0.9",
+			remove: true,
+			expected: `127.0.0.1	localhost
+127.0.1.1	web1.example.com web1
+
+# migration
+10.0.0.5   db-old db # primary database
+::1	localhost ip6-localhost
+`,
+		},
+		{
+			name:     "already in place",
+			ip:       "0:0:0:0:0:0:0:1",
+			names:    []string{"localhost", "ip6-localhost"},
+			expected: sampleHosts,
+		},
+	}
+
+	for _, tt := range tests {
+		if updated := updateHosts(sampleHosts, tt.ip, tt.names, tt.remove); updated != tt.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tt.name, tt.expected, updated)
+		}
+	}
+}
+
+func TestEnsureHostsEntryUnchanged(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{"sh -c if test -e /etc/hosts; then cat /etc/hosts; fi": sampleHosts}}
+	mockFile := &MockFileManager{}
+	manager := &UnixNetworkManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	changed, err := manager.EnsureHostsEntry("10.0.0.9", "cache", "cache2")
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+
+	mockCmd.Outputs["sh -c if test -e /etc/hosts; then cat /etc/hosts; fi"] = mockFile.Writes[HostsPath]
+	mockFile.Writes = nil
+	changed, err = manager.EnsureHostsEntry("10.0.0.9", "cache", "cache2")
+	if err != nil || changed || mockFile.Writes != nil {
+		t.Errorf("Expected no change the second time, got %v, %v", changed, err)
+	}
+}
+
+func TestEnsureHostsEntryUnreadable(t *testing.T) {
+	// The read is not in Outputs, so the mock fails it with a non-zero exit.
+	mockCmd := &MockCommandManager{Outputs: map[string]string{}}
+	mockFile := &MockFileManager{}
+	manager := &UnixNetworkManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	changed, err := manager.EnsureHostsEntry("10.0.0.9", "cache")
+	if err == nil || changed {
+		t.Errorf("Expected an unreadable hosts file to fail, got %v, %v", changed, err)
+	}
+	if mockFile.Writes != nil {
+		t.Errorf("Expected nothing to be written, got %v", mockFile.Writes)
+	}
+}
+
+func TestUpdateResolvConf(t *testing.T) {
+	content := `# Generated by the installer
+domain example.org
+nameserver 192.0.2.1
+options rotate timeout:2
+nameserver 192.0.2.2
+`
+	expected := `# Generated by the installer
+search corp.example.com example.com
+nameserver 10.0.0.2
+nameserver 10.0.0.3
+options rotate timeout:2
+`
+	if updated := updateResolvConf(content, []string{"10.0.0.2", "10.0.0.3"}, []string{"corp.example.com", "example.com"}); updated != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, updated)
+	}
+}
+
+func TestEnsureResolversResolved(t *testing.T) {
+	mockCmd := &MockCommandManager{Outputs: map[string]string{
+		"sh -c " + resolverScript: "systemd-resolved\n",
+		"sh -c if test -e /etc/systemd/resolved.conf.d/90-steelcut.conf; then cat /etc/systemd/resolved.conf.d/90-steelcut.conf; fi": "",
+		"mkdir -p /etc/systemd/resolved.conf.d": "",
+		"systemctl restart systemd-resolved":    "",
+	}}
+	mockFile := &MockFileManager{}
+	manager := &UnixNetworkManager{CommandManager: mockCmd, FileManager: mockFile}
+
+	changed, err := manager.EnsureResolvers([]string{"10.0.0.2", "2001:db8::53"}, []string{"corp.example.com"})
+	if err != nil || !changed {
+		t.Fatalf("Expected a change, got %v, %v", changed, err)
+	}
+
+	expected := "# Managed by steelcut\n[Resolve]\nDNS=10.0.0.2 2001:db8::53\nDomains=corp.example.com\n"
+	if mockFile.Writes[ResolvedDropInPath] != expected {
+		t.Errorf("Expected drop-in:\n%s\ngot:\n%s", expected, mockFile.Writes[ResolvedDropInPath])
+	}
+	if last := mockCmd.Configs[len(mockCmd.Configs)-1]; last.Command != "systemctl" || !last.Sudo {
+		t.Errorf("Expected systemd-resolved to be restarted, got %+v", last)
+	}
+}
+
+func TestParseResolvedAddresses(t *testing.T) {
+	getent := `10.0.0.5        STREAM db.example.com
+10.0.0.5        DGRAM
+10.0.0.5        RAW
+2001:db8::5     STREAM
+`
+	if addresses := parseResolvedAddresses(getent); !reflect.DeepEqual(addresses, []string{"10.0.0.5", "2001:db8::5"}) {
+		t.Errorf("Unexpected getent addresses: %v", addresses)
+	}
+
+	dscacheutil := "name: db.example.com\nipv6_address: 2001:db8::5\n\nname: db.example.com\nip_address: 10.0.0.5\n"
+	if addresses := parseResolvedAddresses(dscacheutil); !reflect.DeepEqual(addresses, []string{"2001:db8::5", "10.0.0.5"}) {
+		t.Errorf("Unexpected dscacheutil addresses: %v", addresses)
+	}
+
+	if addresses := parseResolvedAddresses(""); addresses == nil || len(addresses) != 0 {
+		t.Errorf("Expected an empty list for an unknown name, got %#v", addresses)
+	}
+}
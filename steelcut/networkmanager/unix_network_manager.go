@@ -4,42 +4,111 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"math"
+	"net"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+	"github.com/steelcutops/steelcut/steelcut/filemanager"
+)
+
+// pingScript runs ping with a count, an overall deadline in seconds, an
+// address family and an address. Older iputils and macOS need ping6 for IPv6,
+// and the deadline flag differs between Linux and macOS.
+const pingScript = `count=$1 deadline=$2 family=$3 address=$4
+ping=ping
+if [ "$family" = 6 ]; then
+	if command -v ping6 >/dev/null 2>&1; then ping=ping6; else ping="ping -6"; fi
+fi
+if [ "$(uname -s)" = Darwin ]; then
+	if [ "$ping" = ping6 ]; then exec ping6 -c "$count" "$address"; fi
+	exec ping -c "$count" -t "$deadline" "$address"
+fi
+exec $ping -c "$count" -w "$deadline" "$address"`
+
+var (
+	// Linux and macOS say "3 received"/"3 packets received"; iputils adds
+	// "+3 errors" before the loss when hosts are unreachable.
+	pingSummary = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received,.*?([\d.]+)% packet loss`)
+	// iputils prints "rtt min/avg/max/mdev", macOS "round-trip min/avg/max/stddev"
+	// and busybox "round-trip min/avg/max" without a deviation.
+	pingRTT = regexp.MustCompile(`(?:rtt|round-trip) min/avg/max(?:/(?:mdev|stddev))? = ([\d.]+)/([\d.]+)/([\d.]+)(?:/([\d.]+))? ms`)
 )
 
 type UnixNetworkManager struct {
 	CommandManager cm.CommandManager
+	FileManager    filemanager.FileManager // needed to edit /etc/hosts and resolver configuration
 }
 
-func (unm *UnixNetworkManager) Ping(address string) (PingResult, error) {
-	// For simplicity, we'll use the 'ping' command and send a single packet
-	output, err := unm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "ping",
-		Args:    []string{"-c", "1", address},
+func (unm *UnixNetworkManager) Ping(address string, count int, timeout time.Duration) (PingResult, error) {
+	if count < 1 {
+		return PingResult{}, fmt.Errorf("ping count must be at least 1, got %d", count)
+	}
+	if address == "" || strings.HasPrefix(address, "-") {
+		return PingResult{}, fmt.Errorf("invalid address: %q", address)
+	}
+
+	family := "4"
+	if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+		family = "6"
+	}
+	deadline := int(math.Ceil(timeout.Seconds()))
+	if deadline < 1 {
+		deadline = 1
+	}
+
+	// macOS ping6 has no deadline flag, so the context backs up the timeout.
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Duration(deadline)*time.Second+10*time.Second)
+	defer cancel()
+
+	output, err := unm.CommandManager.Run(ctx, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", pingScript, "sh", strconv.Itoa(count), strconv.Itoa(deadline), family, address},
+		Env:     []string{"LC_ALL=C"},
 	})
-	if err != nil {
-		return PingResult{}, err
+	// ping exits non-zero when replies are missing, which is still a result.
+	result, parseErr := parsePing(output.STDOUT)
+	if parseErr != nil {
+		if err != nil {
+			return PingResult{}, fmt.Errorf("ping %s failed: %w: %s", address, err, strings.TrimSpace(output.STDERR))
+		}
+		return PingResult{}, fmt.Errorf("%w: %s", parseErr, strings.TrimSpace(output.STDERR))
 	}
 
-	// Parsing the 'ping' command output to extract RTT
-	// Typically, the line we're interested in looks like: rtt min/avg/max/mdev = 0.029/0.029/0.029/0.000 ms
-	regex := regexp.MustCompile(`rtt min/avg/max/mdev = (.+?)/(.+?)/(.+?)/(.+?) ms`)
-	matches := regex.FindStringSubmatch(output.STDOUT)
-	if matches == nil {
+	result.Address = address
+	return result, nil
+}
+
+// parsePing parses the summary ping prints when it finishes.
+func parsePing(output string) (PingResult, error) {
+	summary := pingSummary.FindStringSubmatch(output)
+	if summary == nil {
 		return PingResult{}, errors.New("unable to parse ping output")
 	}
 
-	// Convert average RTT to float64
-	rtt, err := strconv.ParseFloat(matches[2], 64)
-	if err != nil {
-		return PingResult{}, fmt.Errorf("unable to convert RTT to float: %v", err)
+	var result PingResult
+	result.Transmitted, _ = strconv.Atoi(summary[1])
+	result.Received, _ = strconv.Atoi(summary[2])
+	result.PacketLoss, _ = strconv.ParseFloat(summary[3], 64)
+	result.Success = result.Received > 0
+
+	if rtt := pingRTT.FindStringSubmatch(output); rtt != nil {
+		result.Min = parseMilliseconds(rtt[1])
+		result.Avg = parseMilliseconds(rtt[2])
+		result.Max = parseMilliseconds(rtt[3])
+		result.StdDev = parseMilliseconds(rtt[4])
 	}
 
-	return PingResult{
-		Address: address,
-		RTT:     rtt,
-		Success: true,
-	}, nil
+	return result, nil
+}
+
+func parseMilliseconds(value string) time.Duration {
+	ms, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
 }
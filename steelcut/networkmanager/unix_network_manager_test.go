@@ -0,0 +1,166 @@
+package networkmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// MockCommandManager answers with Outputs by full command line where one is
+// given and with Output otherwise. Commands without any output fail when
+// Outputs is set, like tools missing from the host.
+type MockCommandManager struct {
+	Output   string
+	ExitCode int
+	Outputs  map[string]string
+	Configs  []cm.CommandConfig
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	m.Configs = append(m.Configs, config)
+	if m.Outputs != nil {
+		output, found := m.Outputs[strings.Join(append([]string{config.Command}, config.Args...), " ")]
+		if !found {
+			return cm.CommandResult{ExitCode: 127}, nil
+		}
+		return cm.CommandResult{STDOUT: output}, nil
+	}
+	return cm.CommandResult{STDOUT: m.Output, ExitCode: m.ExitCode}, nil
+}
+
+func TestParsePing(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected PingResult
+	}{
+		{
+			name: "linux",
+			output: `PING 10.0.0.1 (10.0.0.1) 56(84) bytes of data.
+64 bytes from 10.0.0.1: icmp_seq=1 ttl=64 time=0.321 ms
+64 bytes from 10.0.0.1: icmp_seq=3 ttl=64 time=0.412 ms
+
+--- 10.0.0.1 ping statistics ---
+3 packets transmitted, 2 received, 33.3333% packet loss, time 2031ms
+rtt min/avg/max/mdev = 0.321/0.366/0.412/0.045 ms
+`,
+			expected: PingResult{Transmitted: 3, Received: 2, PacketLoss: 33.3333, Min: 321 * time.Microsecond, Avg: 366 * time.Microsecond, Max: 412 * time.Microsecond, StdDev: 45 * time.Microsecond, Success: true},
+		},
+		{
+			name: "linux unreachable",
+			output: `PING 10.0.0.9 (10.0.0.9) 56(84) bytes of data.
+From 10.0.0.2 icmp_seq=1 Destination Host Unreachable
+
+--- 10.0.0.9 ping statistics ---
+2 packets transmitted, 0 received, +2 errors, 100% packet loss, time 1012ms
+`,
+			expected: PingResult{Transmitted: 2, Received: 0, PacketLoss: 100},
+		},
+		{
+			name: "darwin",
+			output: `PING 10.0.0.1 (10.0.0.1): 56 data bytes
+64 bytes from 10.0.0.1: icmp_seq=0 ttl=64 time=1.204 ms
+
+--- 10.0.0.1 ping statistics ---
+1 packets transmitted, 1 packets received, 0.0% packet loss
+round-trip min/avg/max/stddev = 1.204/1.204/1.204/0.000 ms
+`,
+			expected: PingResult{Transmitted: 1, Received: 1, Min: 1204 * time.Microsecond, Avg: 1204 * time.Microsecond, Max: 1204 * time.Microsecond, Success: true},
+		},
+		{
+			name: "busybox ipv6",
+			output: `PING ::1 (::1): 56 data bytes
+64 bytes from ::1: seq=0 ttl=64 time=0.052 ms
+64 bytes from ::1: seq=1 ttl=64 time=0.090 ms
+
+--- ::1 ping statistics ---
+2 packets transmitted, 2 packets received, 0% packet loss
+round-trip min/avg/max = 0.052/0.071/0.090 ms
+`,
+			expected: PingResult{Transmitted: 2, Received: 2, Min: 52 * time.Microsecond, Avg: 71 * time.Microsecond, Max: 90 * time.Microsecond, Success: true},
+		},
+	}
+
+	for _, test := range tests {
+		result, err := parsePing(test.output)
+		if err != nil {
+			t.Errorf("%s: expected no error, got: %v", test.name, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, result)
+		}
+	}
+
+	if _, err := parsePing("ping: unknown host nowhere.invalid\n"); err == nil {
+		t.Error("Expected an error for output without statistics")
+	}
+}
+
+func TestPingIPv6(t *testing.T) {
+	mockCmd := &MockCommandManager{
+		Output:   "--- fe80::1 ping statistics ---\n3 packets transmitted, 0 received, 100% packet loss, time 2046ms\n",
+		ExitCode: 1,
+	}
+	manager := &UnixNetworkManager{CommandManager: mockCmd}
+
+	result, err := manager.Ping("fe80::1", 3, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error for an unreachable address, got: %v", err)
+	}
+	if result.Success || result.PacketLoss != 100 || result.Address != "fe80::1" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	args := mockCmd.Configs[0].Args
+	if got := args[3:]; got[0] != "3" || got[1] != "2" || got[2] != "6" || got[3] != "fe80::1" {
+		t.Errorf("Expected count 3, deadline 2s and family 6, got %v", got)
+	}
+}
+
+func TestCheckPortWithoutProbe(t *testing.T) {
+	manager := UnixNetworkManager{CommandManager: &MockCommandManager{ExitCode: 2}}
+	if _, err := manager.CheckPort("db1", 5432, "tcp", time.Second); err == nil {
+		t.Error("Expected an error when no port probe is available")
+	}
+
+	// Output without a method line means no probe ran, even on a zero exit.
+	manager = UnixNetworkManager{CommandManager: &MockCommandManager{Output: "\n"}}
+	if _, err := manager.CheckPort("db1", 5432, "tcp", time.Second); err == nil {
+		t.Error("Expected an error for output without a probe method")
+	}
+
+	manager = UnixNetworkManager{CommandManager: &MockCommandManager{Output: "method=nc\nopen=1\n"}}
+	if result, err := manager.CheckPort("db1", 5432, "tcp", time.Second); err != nil || !result.Open {
+		t.Errorf("Expected an open port, got %+v, %v", result, err)
+	}
+}
+
+func TestParsePortCheck(t *testing.T) {
+	python := parsePortCheck("method=python\nlatency=0.001500\nopen=1\n")
+	if !python.Open || python.Method != "python" || python.Latency != 1500*time.Microsecond {
+		t.Errorf("Unexpected python result: %+v", python)
+	}
+
+	nc := parsePortCheck("method=nc\nstart=1000000000\nend=1002500000\nopen=0\nerror=nc: connect to db1 port 5432 (tcp) failed: Connection refused\n")
+	if nc.Open || nc.Latency != 2500*time.Microsecond || nc.Error != "nc: connect to db1 port 5432 (tcp) failed: Connection refused" {
+		t.Errorf("Unexpected nc result: %+v", nc)
+	}
+
+	// BSD date prints a literal N for %N, so the latency is unknown.
+	bsd := parsePortCheck("method=nc\nstart=1700000000N\nend=1700000001N\nopen=1\n")
+	if !bsd.Open || bsd.Latency != 0 {
+		t.Errorf("Unexpected result without nanosecond timestamps: %+v", bsd)
+	}
+}
@@ -0,0 +1,143 @@
+package networkmanager
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// portProbe is the preferred probe. It resolves the name first so the
+// latency it reports is only the connect.
+const portProbe = `import socket, sys, time
+host, port, proto, timeout = sys.argv[1], int(sys.argv[2]), sys.argv[3], float(sys.argv[4])
+kind = socket.SOCK_DGRAM if proto == "udp" else socket.SOCK_STREAM
+print("method=python")
+try:
+    family, _, _, _, address = socket.getaddrinfo(host, port, 0, kind)[0]
+    s = socket.socket(family, kind)
+    s.settimeout(timeout)
+    start = time.monotonic()
+    s.connect(address)
+    if kind == socket.SOCK_DGRAM:
+        s.send(b"")
+    print("latency=%f" % (time.monotonic() - start))
+    if kind == socket.SOCK_DGRAM:
+        try:
+            s.recv(1)
+        except socket.timeout:
+            pass
+    print("open=1")
+except Exception as e:
+    print("open=0")
+    print("error=%s" % e)
+`
+
+// portCheckScript tries the python probe, then nc, then bash's /dev/tcp.
+// The shell fallbacks are timed with date +%s%N, which only GNU date supports,
+// and their latency includes starting the tool.
+// macOS ships a python3 stub that fails until developer tools are installed,
+// hence the trial run.
+const portCheckScript = `host=$1 port=$2 proto=$3 timeout=$4 probe=$5
+if command -v python3 >/dev/null 2>&1 && python3 -c '' >/dev/null 2>&1; then
+	exec python3 -c "$probe" "$host" "$port" "$proto" "$timeout"
+fi
+seconds=${timeout%.*}
+[ "$seconds" -ge 1 ] 2>/dev/null || seconds=1
+start=$(date +%s%N)
+if command -v nc >/dev/null 2>&1; then
+	echo method=nc
+	if [ "$proto" = udp ]; then
+		err=$(nc -z -u -w "$seconds" "$host" "$port" 2>&1)
+	else
+		err=$(nc -z -w "$seconds" "$host" "$port" 2>&1)
+	fi
+	rc=$?
+elif [ "$proto" = tcp ] && command -v bash >/dev/null 2>&1; then
+	echo method=bash
+	err=$(timeout "$seconds" bash -c 'exec 3<>"/dev/tcp/$0/$1"' "$host" "$port" 2>&1)
+	rc=$?
+else
+	echo "no port probe available: need python3, nc or bash" >&2
+	exit 2
+fi
+end=$(date +%s%N)
+echo "start=$start"
+echo "end=$end"
+if [ "$rc" -eq 0 ]; then echo open=1; else echo open=0; echo "error=$(echo "$err" | head -n 1)"; fi`
+
+func (unm *UnixNetworkManager) CheckPort(host string, port int, proto string, timeout time.Duration) (PortCheckResult, error) {
+	if proto != "tcp" && proto != "udp" {
+		return PortCheckResult{}, fmt.Errorf("unsupported protocol: %q", proto)
+	}
+	if port < 1 || port > 65535 {
+		return PortCheckResult{}, fmt.Errorf("invalid port: %d", port)
+	}
+	if host == "" || strings.HasPrefix(host, "-") {
+		return PortCheckResult{}, fmt.Errorf("invalid host: %q", host)
+	}
+
+	seconds := math.Max(timeout.Seconds(), 0.1)
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Duration(math.Ceil(seconds))*time.Second+10*time.Second)
+	defer cancel()
+
+	output, err := cm.RunChecked(ctx, unm.CommandManager, cm.CommandConfig{
+		Command: "sh",
+		Args:    []string{"-c", portCheckScript, "sh", host, strconv.Itoa(port), proto, strconv.FormatFloat(seconds, 'f', -1, 64), portProbe},
+	}, nil)
+	if err != nil {
+		return PortCheckResult{}, fmt.Errorf("port check of %s:%d failed: %w", host, port, err)
+	}
+
+	result := parsePortCheck(output.STDOUT)
+	if result.Method == "" {
+		// Every probe announces itself first, so nothing ran.
+		return PortCheckResult{}, fmt.Errorf("port check of %s:%d failed: no probe output: %s", host, port, strings.TrimSpace(output.STDERR))
+	}
+	result.Host = host
+	result.Port = port
+	result.Protocol = proto
+	return result, nil
+}
+
+// parsePortCheck parses the key=value lines printed by the probes.
+func parsePortCheck(output string) PortCheckResult {
+	var result PortCheckResult
+	var start, end int64
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "method":
+			result.Method = value
+		case "open":
+			result.Open = value == "1"
+		case "error":
+			result.Error = strings.TrimSpace(value)
+		case "latency":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+				result.Latency = time.Duration(seconds * float64(time.Second))
+			}
+		case "start":
+			// Not a number where date does not support %N.
+			start, _ = strconv.ParseInt(value, 10, 64)
+		case "end":
+			end, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+
+	if result.Latency == 0 && start > 0 && end >= start {
+		result.Latency = time.Duration(end - start)
+	}
+	if !result.Open && result.Error == "" {
+		result.Error = "connection failed"
+	}
+	return result
+}
@@ -11,16 +11,39 @@ type ApkPackageManager struct {
 	CommandManager cm.CommandManager
 }
 
-func (apkm *ApkPackageManager) ListPackages() ([]string, error) {
+func (apkm *ApkPackageManager) ListPackages() ([]Package, error) {
 	output, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "apk",
-		Args:    []string{"info"},
+		Args:    []string{"info", "-v"},
 	})
 	if err != nil {
 		return nil, err
 	}
+	return parseApkInfo(output.STDOUT), nil
+}
 
-	return strings.Split(strings.TrimSpace(output.STDOUT), "\n"), nil
+// parseApkInfo parses apk info -v, which prints each package as name,
+// version and release joined by hyphens, e.g. py3-pip-23.1.2-r0. The version
+// starts after the last hyphen that is followed by a digit, as names may
+// contain hyphens and digits but not a hyphen followed by one.
+func parseApkInfo(output string) []Package {
+	var packages []Package
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "WARNING:") {
+			continue
+		}
+		nameVersion, release := splitRelease(line)
+		if !strings.HasPrefix(release, "r") {
+			continue
+		}
+		i := strings.LastIndex(nameVersion, "-")
+		if i <= 0 || i+1 == len(nameVersion) || nameVersion[i+1] < '0' || nameVersion[i+1] > '9' {
+			continue
+		}
+		packages = append(packages, Package{Name: nameVersion[:i], Version: nameVersion[i+1:], Release: release, State: Installed})
+	}
+	return packages
 }
 
 func (apkm *ApkPackageManager) AddPackage(pkg string) error {
@@ -82,20 +105,19 @@ func (apkm *ApkPackageManager) UpgradeAll() ([]string, error) {
 	return apkm.CheckOSUpdates()
 }
 
-func (apkm *ApkPackageManager) EnsurePackagePresent(pkg string) error {
-	packages, err := apkm.ListPackages()
-	if err != nil {
-		return err
-	}
-
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is already installed; return without taking action
-			return nil
+func (apkm *ApkPackageManager) EnsurePackagePresent(pkg string, constraint string) error {
+	return ensurePresent(apkm, pkg, constraint, func(version string, installed *Package) error {
+		// Without a release, ~ matches any release of the version.
+		spec := pkg + "=" + version
+		if !strings.Contains(version, "-r") {
+			spec = pkg + "~" + version
 		}
-	}
-	// Package is not installed; proceed with installation
-	return apkm.AddPackage(pkg)
+		_, err := apkm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "apk",
+			Args:    []string{"add", spec},
+		})
+		return err
+	})
 }
 
 func (apkm *ApkPackageManager) EnsurePackageAbsent(pkg string) error {
@@ -104,11 +126,9 @@ func (apkm *ApkPackageManager) EnsurePackageAbsent(pkg string) error {
 		return err
 	}
 
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is installed; proceed with removal
-			return apkm.RemovePackage(pkg)
-		}
+	if isPresent(packages, pkg) {
+		// Package is installed; proceed with removal
+		return apkm.RemovePackage(pkg)
 	}
 	// Package is not installed; return without taking action
 	return nil
@@ -0,0 +1,38 @@
+package packagemanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseApkInfo(t *testing.T) {
+	output := "musl-1.2.4-r2\n" +
+		"py3-pip-23.1.2-r0\n" +
+		"libcrypto3-3.1.4-r5\n" +
+		"ca-certificates-bundle-20230506-r0\n"
+
+	expected := []Package{
+		{Name: "musl", Version: "1.2.4", Release: "r2", State: Installed},
+		{Name: "py3-pip", Version: "23.1.2", Release: "r0", State: Installed},
+		{Name: "libcrypto3", Version: "3.1.4", Release: "r5", State: Installed},
+		{Name: "ca-certificates-bundle", Version: "20230506", Release: "r0", State: Installed},
+	}
+	if packages := parseApkInfo(output); !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, packages)
+	}
+}
+
+func TestApkEnsurePackagePresentAnyRelease(t *testing.T) {
+	mock := &MockCommandManager{Outputs: map[string]string{
+		"apk info -v":        "musl-1.2.4-r2\n",
+		"apk add curl~8.5.0": "",
+	}}
+	apkm := &ApkPackageManager{CommandManager: mock}
+
+	if err := apkm.EnsurePackagePresent("curl", "8.5.0"); err == nil {
+		t.Error("Expected an error for a version that was not installed")
+	}
+	if !contains(mock.Commands, "apk add curl~8.5.0") {
+		t.Errorf("Expected curl to be added with a fuzzy version, got %q", mock.Commands)
+	}
+}
@@ -11,24 +11,44 @@ type AptPackageManager struct {
 	CommandManager cm.CommandManager
 }
 
-func (apm *AptPackageManager) ListPackages() ([]string, error) {
+// dpkgFormat prints the fields of each package for parseDpkgQuery. ${Status}
+// ends in the state, e.g. "install ok installed" or "deinstall ok config-files".
+const dpkgFormat = "${Package}\t${Version}\t${Architecture}\t${Status}\n"
+
+func (apm *AptPackageManager) ListPackages() ([]Package, error) {
 	output, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "dpkg",
-		Args:    []string{"--get-selections"},
+		Command: "dpkg-query",
+		Args:    []string{"-W", "-f", dpkgFormat},
 	})
 	if err != nil {
 		return nil, err
 	}
+	return parseDpkgQuery(output.STDOUT), nil
+}
 
-	lines := strings.Split(output.STDOUT, "\n")
-	var packages []string
-	for _, line := range lines {
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			packages = append(packages, parts[0])
+// parseDpkgQuery parses dpkg-query output in dpkgFormat. Packages dpkg only
+// knows about from the available list are left out.
+func parseDpkgQuery(output string) []Package {
+	var packages []Package
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
 		}
+		status := strings.Fields(fields[3])
+		if len(status) == 0 || status[len(status)-1] == "not-installed" {
+			continue
+		}
+		version, release := splitRelease(fields[1])
+		packages = append(packages, Package{
+			Name:    fields[0],
+			Version: version,
+			Release: release,
+			Arch:    fields[2],
+			State:   PackageState(status[len(status)-1]),
+		})
 	}
-	return packages, nil
+	return packages
 }
 
 func (apm *AptPackageManager) AddPackage(pkg string) error {
@@ -104,21 +124,20 @@ func (apm *AptPackageManager) UpgradeAll() ([]string, error) {
 	return apm.CheckOSUpdates()
 }
 
-func (apm *AptPackageManager) EnsurePackagePresent(pkg string) error {
-	packages, err := apm.ListPackages()
-	if err != nil {
-		return err
-	}
-
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is already installed; return without taking action
-			return nil
+func (apm *AptPackageManager) EnsurePackagePresent(pkg string, constraint string) error {
+	return ensurePresent(apm, pkg, constraint, func(version string, installed *Package) error {
+		// A version without a Debian revision matches any revision.
+		if !strings.Contains(version, "-") {
+			version += "-*"
 		}
-	}
-
-	// Package is not installed; proceed with installation
-	return apm.AddPackage(pkg)
+		_, err := apm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "apt-get",
+			Sudo:    true,
+			Env:     []string{"DEBIAN_FRONTEND=noninteractive"},
+			Args:    []string{"install", "-y", "--allow-downgrades", "-o", "Dpkg::Options::=--force-confdef", "-o", "Dpkg::Options::=--force-confold", pkg + "=" + version},
+		})
+		return err
+	})
 }
 
 func (apm *AptPackageManager) EnsurePackageAbsent(pkg string) error {
@@ -127,11 +146,9 @@ func (apm *AptPackageManager) EnsurePackageAbsent(pkg string) error {
 		return err
 	}
 
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is installed; proceed with removal
-			return apm.RemovePackage(pkg)
-		}
+	if isPresent(packages, pkg) {
+		// Package is installed; proceed with removal
+		return apm.RemovePackage(pkg)
 	}
 
 	// Package is not installed; return without taking action
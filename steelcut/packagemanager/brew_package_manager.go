@@ -11,16 +11,41 @@ type BrewPackageManager struct {
 	CommandManager cm.CommandManager
 }
 
-func (bpm *BrewPackageManager) ListPackages() ([]string, error) {
+func (bpm *BrewPackageManager) ListPackages() ([]Package, error) {
 	output, err := bpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "brew",
-		Args:    []string{"list"},
+		Args:    []string{"list", "--versions"},
 	})
 	if err != nil {
 		return nil, err
 	}
+	return parseBrewList(output.STDOUT), nil
+}
 
-	return strings.Split(strings.TrimSpace(output.STDOUT), "\n"), nil
+// parseBrewList parses brew list --versions, which prints a formula or cask
+// followed by its installed versions, e.g. "openssl@3 3.2.1 3.3.0". Only the
+// newest version is kept. A formula revision, the 1 of 1.2.3_1, becomes the
+// release.
+func parseBrewList(output string) []Package {
+	var packages []Package
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		newest := fields[1]
+		for _, version := range fields[2:] {
+			if CompareVersions(version, newest) > 0 {
+				newest = version
+			}
+		}
+		p := Package{Name: fields[0], Version: newest, State: Installed}
+		if i := strings.LastIndex(newest, "_"); i > 0 && isDigits(newest[i+1:]) {
+			p.Version, p.Release = newest[:i], newest[i+1:]
+		}
+		packages = append(packages, p)
+	}
+	return packages
 }
 
 func (bpm *BrewPackageManager) AddPackage(pkg string) error {
@@ -70,21 +95,17 @@ func (bpm *BrewPackageManager) UpgradeAll() ([]string, error) {
 	return bpm.CheckOSUpdates()
 }
 
-func (bpm *BrewPackageManager) EnsurePackagePresent(pkg string) error {
-	packages, err := bpm.ListPackages()
-	if err != nil {
-		return err
-	}
-
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is already installed; return without taking action
-			return nil
+// EnsurePackagePresent can only install or upgrade to the current version
+// of a formula, so an exact constraint is met only when that is the version
+// asked for. Older major versions are usually available as versioned
+// formulae, such as postgresql@15.
+func (bpm *BrewPackageManager) EnsurePackagePresent(pkg string, constraint string) error {
+	return ensurePresent(bpm, pkg, constraint, func(version string, installed *Package) error {
+		if installed == nil {
+			return bpm.AddPackage(pkg)
 		}
-	}
-
-	// Package is not installed; proceed with installation
-	return bpm.AddPackage(pkg)
+		return bpm.UpgradePackage(pkg)
+	})
 }
 
 func (bpm *BrewPackageManager) EnsurePackageAbsent(pkg string) error {
@@ -93,11 +114,9 @@ func (bpm *BrewPackageManager) EnsurePackageAbsent(pkg string) error {
 		return err
 	}
 
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is installed; proceed with removal
-			return bpm.RemovePackage(pkg)
-		}
+	if isPresent(packages, pkg) {
+		// Package is installed; proceed with removal
+		return bpm.RemovePackage(pkg)
 	}
 
 	// Package is not installed; return without taking action
@@ -0,0 +1,21 @@
+package packagemanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBrewList(t *testing.T) {
+	output := "openssl@3 3.2.1 3.3.0\n" +
+		"git 2.45.1\n" +
+		"python@3.12 3.12.3_1\n"
+
+	expected := []Package{
+		{Name: "openssl@3", Version: "3.3.0", State: Installed},
+		{Name: "git", Version: "2.45.1", State: Installed},
+		{Name: "python@3.12", Version: "3.12.3", Release: "1", State: Installed},
+	}
+	if packages := parseBrewList(output); !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, packages)
+	}
+}
@@ -11,24 +11,40 @@ type DnfPackageManager struct {
 	CommandManager cm.CommandManager
 }
 
-func (dpm *DnfPackageManager) ListPackages() ([]string, error) {
+// ListPackages reads the package database with rpm and, where dnf can tell,
+// adds the repository each package was installed from.
+func (dpm *DnfPackageManager) ListPackages() ([]Package, error) {
+	packages, err := listRPMPackages(dpm.CommandManager)
+	if err != nil {
+		return nil, err
+	}
+
 	output, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
 		Command: "dnf",
-		Args:    []string{"list", "installed"},
+		Args:    []string{"repoquery", "--installed", "-q", "--queryformat", "%{name}.%{arch}\t%{from_repo}\n"},
 	})
 	if err != nil {
-		return nil, err
+		// Not all dnf versions know from_repo; the packages are still good.
+		return packages, nil
+	}
+	repositories := parseDnfRepoquery(output.STDOUT)
+	for i, p := range packages {
+		packages[i].Repository = repositories[p.Name+"."+p.Arch]
 	}
+	return packages, nil
+}
 
-	lines := strings.Split(output.STDOUT, "\n")
-	var packages []string
-	for _, line := range lines[1:] { // Skipping the header line
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			packages = append(packages, parts[0])
+// parseDnfRepoquery maps name.arch to the repository each package was
+// installed from, as printed by dnf repoquery with a name.arch<TAB>from_repo
+// query format.
+func parseDnfRepoquery(output string) map[string]string {
+	repositories := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, repository, found := strings.Cut(strings.TrimSpace(line), "\t"); found {
+			repositories[key] = repository
 		}
 	}
-	return packages, nil
+	return repositories
 }
 
 func (dpm *DnfPackageManager) AddPackage(pkg string) error {
@@ -59,10 +75,11 @@ func (dpm *DnfPackageManager) UpgradePackage(pkg string) error {
 }
 
 func (dpm *DnfPackageManager) CheckOSUpdates() ([]string, error) {
-	output, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	// dnf list exits with 1 when there is nothing to upgrade.
+	output, err := cm.RunChecked(context.TODO(), dpm.CommandManager, cm.CommandConfig{
 		Command: "dnf",
 		Args:    []string{"list", "upgrades"},
-	})
+	}, func(status int) bool { return status == 1 })
 	if err != nil {
 		return nil, err
 	}
@@ -90,21 +107,16 @@ func (dpm *DnfPackageManager) UpgradeAll() ([]string, error) {
 	return dpm.CheckOSUpdates()
 }
 
-func (dpm *DnfPackageManager) EnsurePackagePresent(pkg string) error {
-	packages, err := dpm.ListPackages()
-	if err != nil {
+func (dpm *DnfPackageManager) EnsurePackagePresent(pkg string, constraint string) error {
+	// dnf install up- or downgrades to a version given with the name.
+	return ensurePresent(dpm, pkg, constraint, func(version string, installed *Package) error {
+		_, err := dpm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "dnf",
+			Sudo:    true,
+			Args:    []string{"install", "-y", pkg + "-" + version},
+		})
 		return err
-	}
-
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is already installed; return without taking action
-			return nil
-		}
-	}
-
-	// Package is not installed; proceed with installation
-	return dpm.AddPackage(pkg)
+	})
 }
 
 func (dpm *DnfPackageManager) EnsurePackageAbsent(pkg string) error {
@@ -113,11 +125,9 @@ func (dpm *DnfPackageManager) EnsurePackageAbsent(pkg string) error {
 		return err
 	}
 
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is installed; proceed with removal
-			return dpm.RemovePackage(pkg)
-		}
+	if isPresent(packages, pkg) {
+		// Package is installed; proceed with removal
+		return dpm.RemovePackage(pkg)
 	}
 
 	// Package is not installed; return without taking action
@@ -0,0 +1,53 @@
+package packagemanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDnfRepoquery(t *testing.T) {
+	output := "bash.x86_64\tbaseos\n" +
+		"openssl.x86_64\t@System\n" +
+		"kernel-core.x86_64\tanaconda\n" +
+		"\n"
+
+	expected := map[string]string{
+		"bash.x86_64":        "baseos",
+		"openssl.x86_64":     "@System",
+		"kernel-core.x86_64": "anaconda",
+	}
+	if repositories := parseDnfRepoquery(output); !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("Expected %v, got %v", expected, repositories)
+	}
+}
+
+func TestDnfListPackages(t *testing.T) {
+	mock := &MockCommandManager{Outputs: map[string]string{
+		"rpm -qa --queryformat " + rpmFormat:                                         rpmFixture,
+		"dnf repoquery --installed -q --queryformat %{name}.%{arch}\t%{from_repo}\n": "bash.x86_64\tbaseos\nopenssl.x86_64\tbaseos\n",
+	}}
+	dpm := &DnfPackageManager{CommandManager: mock}
+
+	packages, err := dpm.ListPackages()
+	if err != nil {
+		t.Fatalf("Error listing packages: %v", err)
+	}
+
+	repositories := map[string]string{}
+	for _, p := range packages {
+		repositories[p.Name] = p.Repository
+	}
+	if repositories["bash"] != "baseos" || repositories["openssl"] != "baseos" || repositories["gpg-pubkey"] != "" {
+		t.Errorf("Unexpected repositories: %v", repositories)
+	}
+}
+
+func TestDnfListPackagesWithoutRepoquery(t *testing.T) {
+	mock := &MockCommandManager{Outputs: map[string]string{"rpm -qa --queryformat " + rpmFormat: rpmFixture}}
+	dpm := &DnfPackageManager{CommandManager: mock}
+
+	packages, err := dpm.ListPackages()
+	if err != nil || len(packages) != 3 {
+		t.Errorf("Expected the rpm packages alone, got %+v, %v", packages, err)
+	}
+}
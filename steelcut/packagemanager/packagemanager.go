@@ -1,7 +1,56 @@
 package packagemanager
 
+import (
+	"fmt"
+	"strings"
+)
+
+// PackageState is how far a package is installed.
+type PackageState string
+
+const (
+	Installed PackageState = "installed"
+
+	// ConfigFiles is a package that was removed with its configuration left
+	// behind. Other partial states reported by dpkg, such as half-installed
+	// or unpacked, are passed through as they are.
+	ConfigFiles PackageState = "config-files"
+)
+
+// Package is a package known to the host's package database.
+type Package struct {
+	Name       string       `json:"name"`
+	Version    string       `json:"version"`           // upstream version, with the epoch if it has one
+	Release    string       `json:"release,omitempty"` // distribution revision, such as 1ubuntu2, 3.el9 or r4
+	Arch       string       `json:"arch,omitempty"`
+	Repository string       `json:"repository,omitempty"` // empty where the package database does not record it
+	State      PackageState `json:"state"`
+}
+
+// FullVersion returns the version and release as the package manager
+// writes them, e.g. 1.24.0-2ubuntu7.
+func (p Package) FullVersion() string {
+	if p.Release == "" {
+		return p.Version
+	}
+	return p.Version + "-" + p.Release
+}
+
+func (p Package) String() string {
+	s := p.Name + " " + p.FullVersion()
+	if p.Arch != "" {
+		s += " (" + p.Arch + ")"
+	}
+	if p.State != Installed {
+		s += " [" + string(p.State) + "]"
+	}
+	return s
+}
+
 type PackageManager interface {
-	ListPackages() ([]string, error)
+	// ListPackages lists the packages in the package database. Packages that
+	// are not fully installed are included with their State set accordingly.
+	ListPackages() ([]Package, error)
 	AddPackage(pkg string) error
 	RemovePackage(pkg string) error
 	UpgradePackage(pkg string) error
@@ -9,6 +58,82 @@ type PackageManager interface {
 	UpgradeAll() ([]string, error)
 
 	// Idempotent package management
-	EnsurePackagePresent(pkg string) error
+
+	// EnsurePackagePresent installs pkg unless an installed version already
+	// satisfies constraint, such as ">= 1.2" or "= 1.2.3-1"; see
+	// ParseVersionConstraint. An empty constraint accepts any version.
+	EnsurePackagePresent(pkg string, constraint string) error
 	EnsurePackageAbsent(pkg string) error
 }
+
+// ensurePresent implements EnsurePackagePresent on top of the other methods.
+// An exact constraint is met by installing that version with installVersion,
+// which is given the installed package, if any; any other constraint by
+// installing or upgrading the package. The result is checked against the
+// constraint, as the available versions may not meet it.
+func ensurePresent(pm PackageManager, pkg, constraint string, installVersion func(version string, installed *Package) error) error {
+	c, err := ParseVersionConstraint(constraint)
+	if err != nil {
+		return err
+	}
+
+	packages, err := pm.ListPackages()
+	if err != nil {
+		return err
+	}
+	installed := findInstalled(packages, pkg)
+	if installed != nil && c.Allows(*installed) {
+		return nil
+	}
+
+	switch {
+	case c.Op == "=":
+		err = installVersion(c.Version, installed)
+	case installed == nil:
+		err = pm.AddPackage(pkg)
+	default:
+		err = pm.UpgradePackage(pkg)
+	}
+	if err != nil || c.Op == "" {
+		return err
+	}
+
+	if packages, err = pm.ListPackages(); err != nil {
+		return err
+	}
+	if installed = findInstalled(packages, pkg); installed == nil {
+		return fmt.Errorf("%s is not installed after installing it", pkg)
+	}
+	if !c.Allows(*installed) {
+		return fmt.Errorf("%s %s does not satisfy %s", pkg, installed.FullVersion(), c)
+	}
+	return nil
+}
+
+// isPresent reports whether any part of pkg beyond its configuration files
+// is on the host, meaning there is something left to remove.
+func isPresent(packages []Package, pkg string) bool {
+	for _, p := range packages {
+		if p.Name == pkg && p.State != ConfigFiles {
+			return true
+		}
+	}
+	return false
+}
+
+func findInstalled(packages []Package, pkg string) *Package {
+	for i, p := range packages {
+		if p.Name == pkg && p.State == Installed {
+			return &packages[i]
+		}
+	}
+	return nil
+}
+
+// splitRelease splits a version such as 1.2.3-4 at its last hyphen.
+func splitRelease(version string) (string, string) {
+	if i := strings.LastIndex(version, "-"); i > 0 {
+		return version[:i], version[i+1:]
+	}
+	return version, ""
+}
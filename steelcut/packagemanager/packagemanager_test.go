@@ -0,0 +1,109 @@
+package packagemanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// MockCommandManager answers each command line from Outputs, failing ones
+// it has no output for, and records the command lines it was given. Command
+// lines in ExitCodes exit with that status, which is reported as an error as
+// a real command manager does.
+type MockCommandManager struct {
+	Outputs   map[string]string
+	ExitCodes map[string]int
+	Commands  []string
+}
+
+func (m *MockCommandManager) RunLocal(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) RunRemote(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	return m.Run(ctx, config)
+}
+
+func (m *MockCommandManager) Run(ctx context.Context, config cm.CommandConfig) (cm.CommandResult, error) {
+	command := strings.Join(append([]string{config.Command}, config.Args...), " ")
+	m.Commands = append(m.Commands, command)
+	output, found := m.Outputs[command]
+	if status := m.ExitCodes[command]; status != 0 {
+		return cm.CommandResult{STDOUT: output, ExitCode: status}, fmt.Errorf("exit status %d", status)
+	}
+	if !found {
+		return cm.CommandResult{ExitCode: 1}, errors.New("command failed")
+	}
+	return cm.CommandResult{STDOUT: output}, nil
+}
+
+func TestParseDpkgQuery(t *testing.T) {
+	output := "nginx\t1.24.0-2ubuntu7\tamd64\tinstall ok installed\n" +
+		"apache2\t2.4.58-1ubuntu8\tamd64\tdeinstall ok config-files\n" +
+		"libc6\t2.39-0ubuntu8\ti386\tinstall ok installed\n" +
+		"vim\t\t\tunknown ok not-installed\n"
+
+	expected := []Package{
+		{Name: "nginx", Version: "1.24.0", Release: "2ubuntu7", Arch: "amd64", State: Installed},
+		{Name: "apache2", Version: "2.4.58", Release: "1ubuntu8", Arch: "amd64", State: ConfigFiles},
+		{Name: "libc6", Version: "2.39", Release: "0ubuntu8", Arch: "i386", State: Installed},
+	}
+	if packages := parseDpkgQuery(output); !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, packages)
+	}
+}
+
+func TestEnsurePackagePresent(t *testing.T) {
+	list := "dpkg-query -W -f " + dpkgFormat
+	tests := []struct {
+		name       string
+		installed  string
+		constraint string
+		expected   string // the install command run, if any
+	}{
+		{"satisfied", "nginx\t1.24.0-2ubuntu7\tamd64\tinstall ok installed\n", ">= 1.22", ""},
+		{"only configuration left", "nginx\t1.24.0-2ubuntu7\tamd64\tdeinstall ok config-files\n", "", "apt-get install -y -o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold nginx"},
+		{"exact version", "nginx\t1.26.0-1\tamd64\tinstall ok installed\n", "= 1.24.0", "apt-get install -y --allow-downgrades -o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold nginx=1.24.0-*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockCommandManager{Outputs: map[string]string{list: tt.installed}}
+			if tt.expected != "" {
+				mock.Outputs[tt.expected] = ""
+			}
+			apm := &AptPackageManager{CommandManager: mock}
+
+			// The listing does not change, so the exact version is reported
+			// as not taking effect.
+			err := apm.EnsurePackagePresent("nginx", tt.constraint)
+			if (err != nil) != strings.HasPrefix(tt.constraint, "=") {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			var installs []string
+			for _, command := range mock.Commands {
+				if command != list {
+					installs = append(installs, command)
+				}
+			}
+			if tt.expected == "" && len(installs) > 0 || tt.expected != "" && !reflect.DeepEqual(installs, []string{tt.expected}) {
+				t.Errorf("Expected %q to be run, got %q", tt.expected, installs)
+			}
+		})
+	}
+}
+
+func contains(commands []string, command string) bool {
+	for _, c := range commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
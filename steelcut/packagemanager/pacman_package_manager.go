@@ -0,0 +1,189 @@
+package packagemanager
+
+import (
+	"context"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// PacmanPackageManager manages packages on Arch Linux and its derivatives.
+type PacmanPackageManager struct {
+	CommandManager cm.CommandManager
+}
+
+// ListPackages reads the local database with pacman -Qi and adds the sync
+// repository each package is available from, where there is one.
+func (ppm *PacmanPackageManager) ListPackages() ([]Package, error) {
+	output, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Args:    []string{"-Qi"},
+		Env:     []string{"LC_ALL=C"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	packages := parsePacmanInfo(output.STDOUT)
+
+	output, err = ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Args:    []string{"-Sl"},
+		Env:     []string{"LC_ALL=C"},
+	})
+	if err != nil {
+		// Without synced databases the packages are still good.
+		return packages, nil
+	}
+	repositories := parsePacmanSyncList(output.STDOUT)
+	for i, p := range packages {
+		packages[i].Repository = repositories[p.Name]
+	}
+	return packages, nil
+}
+
+// parsePacmanInfo parses pacman -Qi, which prints a block of "Key : Value"
+// lines per package, separated by blank lines.
+func parsePacmanInfo(output string) []Package {
+	var packages []Package
+	var p Package
+	for _, line := range strings.Split(output+"\n", "\n") {
+		if strings.TrimSpace(line) == "" {
+			if p.Name != "" {
+				p.State = Installed
+				packages = append(packages, p)
+			}
+			p = Package{}
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(line, " ") {
+			// A continuation of a long value, such as Depends On.
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Name":
+			p.Name = value
+		case "Version":
+			p.Version, p.Release = splitRelease(value)
+		case "Architecture":
+			p.Arch = value
+		}
+	}
+	return packages
+}
+
+// parsePacmanSyncList maps installed packages to their repository from
+// pacman -Sl, which prints lines such as "core glibc 2.39-1 [installed]".
+func parsePacmanSyncList(output string) map[string]string {
+	repositories := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && strings.HasPrefix(fields[3], "[installed") {
+			repositories[fields[1]] = fields[0]
+		}
+	}
+	return repositories
+}
+
+func (ppm *PacmanPackageManager) AddPackage(pkg string) error {
+	_, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-S", "--noconfirm", "--needed", pkg},
+	})
+	return err
+}
+
+func (ppm *PacmanPackageManager) RemovePackage(pkg string) error {
+	_, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-R", "--noconfirm", pkg},
+	})
+	return err
+}
+
+// UpgradePackage installs the version in the local copy of the sync
+// databases. They are not refreshed, as upgrading one package against newer
+// databases than the rest of the system is unsupported on Arch.
+func (ppm *PacmanPackageManager) UpgradePackage(pkg string) error {
+	_, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-S", "--noconfirm", pkg},
+	})
+	return err
+}
+
+// CheckOSUpdates uses checkupdates, from pacman-contrib, which syncs into a
+// temporary copy of the databases. Syncing the real ones with pacman -Sy
+// without upgrading would leave the next pacman -S with a partial upgrade.
+// Without checkupdates the databases are compared as they were last synced.
+func (ppm *PacmanPackageManager) CheckOSUpdates() ([]string, error) {
+	// checkupdates exits with 2 when nothing is out of date.
+	output, err := cm.RunChecked(context.TODO(), ppm.CommandManager, cm.CommandConfig{
+		Command: "checkupdates",
+	}, func(status int) bool { return status == 2 || status == 127 })
+	if err != nil {
+		return nil, err
+	}
+	if output.ExitCode == 127 {
+		// pacman -Qu exits with 1 when nothing is out of date.
+		output, err = cm.RunChecked(context.TODO(), ppm.CommandManager, cm.CommandConfig{
+			Command: "pacman",
+			Args:    []string{"-Qu"},
+		}, func(status int) bool { return status == 1 })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var updates []string
+	for _, line := range strings.Split(output.STDOUT, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) > 0 {
+			updates = append(updates, parts[0])
+		}
+	}
+	return updates, nil
+}
+
+func (ppm *PacmanPackageManager) UpgradeAll() ([]string, error) {
+	_, err := ppm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "pacman",
+		Sudo:    true,
+		Args:    []string{"-Syu", "--noconfirm"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ppm.CheckOSUpdates()
+}
+
+// EnsurePackagePresent can only install or upgrade to the version in the
+// sync databases, as Arch repositories carry no older versions, so an exact
+// constraint is met only when that is the version asked for.
+func (ppm *PacmanPackageManager) EnsurePackagePresent(pkg string, constraint string) error {
+	return ensurePresent(ppm, pkg, constraint, func(version string, installed *Package) error {
+		if installed == nil {
+			return ppm.AddPackage(pkg)
+		}
+		return ppm.UpgradePackage(pkg)
+	})
+}
+
+func (ppm *PacmanPackageManager) EnsurePackageAbsent(pkg string) error {
+	packages, err := ppm.ListPackages()
+	if err != nil {
+		return err
+	}
+
+	if isPresent(packages, pkg) {
+		// Package is installed; proceed with removal
+		return ppm.RemovePackage(pkg)
+	}
+
+	// Package is not installed; return without taking action
+	return nil
+}
@@ -0,0 +1,87 @@
+package packagemanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pacmanInfoFixture = `Name            : glibc
+Version         : 2.39+r52+gf8e4623421-1
+Description     : GNU C Library
+Architecture    : x86_64
+URL             : https://www.gnu.org/software/libc
+Licenses        : GPL-2.0-or-later  LGPL-2.1-or-later
+Depends On      : linux-api-headers>=4.10  tzdata  filesystem
+Optional Deps   : gd: for memusagestat
+                  perl: for mtrace [installed]
+Install Reason  : Installed as a dependency for another package
+
+Name            : yay
+Version         : 12.3.5-1
+Description     : Yet another yogurt. Pacman wrapper and AUR helper written in go.
+Architecture    : x86_64
+Install Reason  : Explicitly installed
+
+Name            : openssh
+Version         : 1:9.7p1-2
+Architecture    : x86_64
+
+`
+
+func TestPacmanListPackages(t *testing.T) {
+	mock := &MockCommandManager{Outputs: map[string]string{
+		"pacman -Qi": pacmanInfoFixture,
+		"pacman -Sl": "core glibc 2.39+r52+gf8e4623421-1 [installed]\n" +
+			"core openssh 1:9.7p1-2 [installed]\n" +
+			"extra nginx 1.26.0-1\n",
+	}}
+	ppm := &PacmanPackageManager{CommandManager: mock}
+
+	packages, err := ppm.ListPackages()
+	if err != nil {
+		t.Fatalf("Error listing packages: %v", err)
+	}
+
+	// yay comes from the AUR, so no repository provides it.
+	expected := []Package{
+		{Name: "glibc", Version: "2.39+r52+gf8e4623421", Release: "1", Arch: "x86_64", Repository: "core", State: Installed},
+		{Name: "yay", Version: "12.3.5", Release: "1", Arch: "x86_64", State: Installed},
+		{Name: "openssh", Version: "1:9.7p1", Release: "2", Arch: "x86_64", Repository: "core", State: Installed},
+	}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, packages)
+	}
+}
+
+func TestPacmanCheckOSUpdates(t *testing.T) {
+	updates := "glibc 2.39+r52+gf8e4623421-1 -> 2.40+r16+gaa533d58ff-2\nopenssh 1:9.7p1-2 -> 1:9.8p1-1\n"
+	tests := []struct {
+		name      string
+		outputs   map[string]string
+		exitCodes map[string]int
+		expected  []string
+	}{
+		{"updates", map[string]string{"checkupdates": updates}, nil, []string{"glibc", "openssh"}},
+		{"up to date", map[string]string{"checkupdates": ""}, map[string]int{"checkupdates": 2}, nil},
+		{"without checkupdates", map[string]string{"pacman -Qu": updates}, map[string]int{"checkupdates": 127}, []string{"glibc", "openssh"}},
+		{"up to date without checkupdates", map[string]string{"pacman -Qu": ""}, map[string]int{"checkupdates": 127, "pacman -Qu": 1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockCommandManager{Outputs: tt.outputs, ExitCodes: tt.exitCodes}
+			ppm := &PacmanPackageManager{CommandManager: mock}
+
+			updates, err := ppm.CheckOSUpdates()
+			if err != nil {
+				t.Fatalf("Error checking for updates: %v", err)
+			}
+			if !reflect.DeepEqual(updates, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, updates)
+			}
+			if contains(mock.Commands, "pacman -Sy") {
+				t.Errorf("Expected the sync databases to be left alone, got %q", mock.Commands)
+			}
+		})
+	}
+}
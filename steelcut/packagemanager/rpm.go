@@ -0,0 +1,44 @@
+package packagemanager
+
+import (
+	"context"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// rpmFormat prints the fields of each package for parseRPMQuery. rpm prints
+// "(none)" for a missing epoch and for the architecture of gpg-pubkey.
+const rpmFormat = "%{NAME}\t%{EPOCH}\t%{VERSION}\t%{RELEASE}\t%{ARCH}\n"
+
+// listRPMPackages lists the installed packages with rpm, which dnf and yum
+// share.
+func listRPMPackages(cmdManager cm.CommandManager) ([]Package, error) {
+	output, err := cmdManager.Run(context.TODO(), cm.CommandConfig{
+		Command: "rpm",
+		Args:    []string{"-qa", "--queryformat", rpmFormat},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseRPMQuery(output.STDOUT), nil
+}
+
+func parseRPMQuery(output string) []Package {
+	var packages []Package
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		p := Package{Name: fields[0], Version: fields[2], Release: fields[3], Arch: fields[4], State: Installed}
+		if fields[1] != "(none)" && fields[1] != "" {
+			p.Version = fields[1] + ":" + p.Version
+		}
+		if p.Arch == "(none)" {
+			p.Arch = ""
+		}
+		packages = append(packages, p)
+	}
+	return packages
+}
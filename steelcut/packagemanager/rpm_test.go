@@ -0,0 +1,23 @@
+package packagemanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+// rpmFixture is rpm -qa output in rpmFormat, shared by the dnf, yum and
+// zypper tests.
+const rpmFixture = "bash\t(none)\t5.1.8\t9.el9\tx86_64\n" +
+	"openssl\t1\t3.0.7\t27.el9\tx86_64\n" +
+	"gpg-pubkey\t(none)\tfd431d51\t4ae0493b\t(none)\n"
+
+func TestParseRPMQuery(t *testing.T) {
+	expected := []Package{
+		{Name: "bash", Version: "5.1.8", Release: "9.el9", Arch: "x86_64", State: Installed},
+		{Name: "openssl", Version: "1:3.0.7", Release: "27.el9", Arch: "x86_64", State: Installed},
+		{Name: "gpg-pubkey", Version: "fd431d51", Release: "4ae0493b", State: Installed},
+	}
+	if packages := parseRPMQuery(rpmFixture); !reflect.DeepEqual(packages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, packages)
+	}
+}
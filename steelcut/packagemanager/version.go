@@ -0,0 +1,184 @@
+package packagemanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VersionConstraint restricts the acceptable versions of a package.
+type VersionConstraint struct {
+	Op      string // =, >=, >, <= or <; empty accepts any version
+	Version string
+}
+
+// ParseVersionConstraint parses constraints such as ">= 1.2", "<2.0" or
+// "= 1.2.3-4". A bare version means "=" and an empty string accepts any
+// version.
+func ParseVersionConstraint(constraint string) (VersionConstraint, error) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" {
+		return VersionConstraint{}, nil
+	}
+
+	c := VersionConstraint{Op: "="}
+	for _, op := range []string{">=", "<=", "==", "=", ">", "<"} {
+		if rest, found := strings.CutPrefix(constraint, op); found {
+			c.Op, constraint = op, strings.TrimSpace(rest)
+			break
+		}
+	}
+	if c.Op == "==" {
+		c.Op = "="
+	}
+	if constraint == "" || strings.ContainsAny(constraint, " \t<>=") {
+		return VersionConstraint{}, fmt.Errorf("invalid version constraint: %q", constraint)
+	}
+	c.Version = constraint
+	return c, nil
+}
+
+func (c VersionConstraint) String() string {
+	return c.Op + " " + c.Version
+}
+
+// Allows reports whether the version of p meets the constraint. The release
+// is only compared when the constraint gives one, and the epoch only when
+// the constraint has one, so ">= 1.2" is met by 1:1.2.3-4.
+func (c VersionConstraint) Allows(p Package) bool {
+	if c.Op == "" {
+		return true
+	}
+
+	cmp := CompareVersions(comparableVersion(p, c.Version), c.Version)
+	switch c.Op {
+	case "=":
+		return cmp == 0
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	}
+	return false
+}
+
+// comparableVersion returns the version of p with only the parts that
+// requested gives: the release when it has one and the epoch when it has one.
+func comparableVersion(p Package, requested string) string {
+	version := p.Version
+	if strings.Contains(requested, "-") {
+		version = p.FullVersion()
+	}
+	if !strings.Contains(requested, ":") {
+		if _, upstream, found := strings.Cut(version, ":"); found {
+			version = upstream
+		}
+	}
+	return version
+}
+
+// CompareVersions compares two versions the way dpkg does, returning -1, 0
+// or 1. The epoch before a colon is compared first, defaulting to 0. The
+// rest is compared in alternating runs of non-digits, compared character by
+// character with letters before other characters and a tilde before
+// anything, even the end, and digits, compared numerically. This orders
+// rpm, apk and Homebrew versions the same way their own tools do in all but
+// unusual cases.
+func CompareVersions(a, b string) int {
+	epochA, restA := splitEpoch(a)
+	epochB, restB := splitEpoch(b)
+	if cmp := compareNumbers(epochA, epochB); cmp != 0 {
+		return cmp
+	}
+
+	for restA != "" || restB != "" {
+		var nonDigitA, nonDigitB string
+		nonDigitA, restA = splitRun(restA, false)
+		nonDigitB, restB = splitRun(restB, false)
+		if cmp := compareNonDigits(nonDigitA, nonDigitB); cmp != 0 {
+			return cmp
+		}
+
+		var digitsA, digitsB string
+		digitsA, restA = splitRun(restA, true)
+		digitsB, restB = splitRun(restB, true)
+		if cmp := compareNumbers(digitsA, digitsB); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func splitEpoch(version string) (string, string) {
+	if epoch, rest, found := strings.Cut(version, ":"); found && isDigits(epoch) {
+		return epoch, rest
+	}
+	return "0", version
+}
+
+// splitRun splits s after its leading run of digits or of non-digits.
+func splitRun(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func compareNonDigits(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ca, cb byte
+		if i < len(a) {
+			ca = a[i]
+		}
+		if i < len(b) {
+			cb = b[i]
+		}
+		if oa, ob := charOrder(ca), charOrder(cb); oa != ob {
+			if oa < ob {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// charOrder ranks a character of a non-digit run: a tilde first, then the
+// end of the run, letters, and everything else.
+func charOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c == 0:
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	}
+	return int(c) + 256
+}
+
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	switch {
+	case len(a) != len(b):
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isDigits(s string) bool {
+	_, rest := splitRun(s, true)
+	return s != "" && rest == ""
+}
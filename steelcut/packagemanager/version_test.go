@@ -0,0 +1,60 @@
+package packagemanager
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"1.2", "1.2.0", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0a", "1.0.1", -1},
+		{"1:0.9", "2.0", 1},
+		{"2.0-1ubuntu2", "2.0-1ubuntu10", -1},
+		{"5.14.0-362.el9", "5.14.0-70.el9", 1},
+		{"007", "7", 0},
+	}
+
+	for _, tt := range tests {
+		if cmp := CompareVersions(tt.a, tt.b); cmp != tt.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, cmp, tt.expected)
+		}
+	}
+}
+
+func TestVersionConstraint(t *testing.T) {
+	installed := Package{Name: "nginx", Version: "1:1.24.0", Release: "2ubuntu7", State: Installed}
+
+	tests := []struct {
+		constraint string
+		allowed    bool
+	}{
+		{"", true},
+		{">= 1.22", true},
+		{">1.24.0", false},
+		{"< 1.25", true},
+		{"1.24.0", true},
+		{"== 1.24.0-2ubuntu7", true},
+		{"= 1.24.0-2ubuntu8", false},
+		{"<= 0:2.0", false}, // the epoch counts once the constraint has one
+	}
+
+	for _, tt := range tests {
+		c, err := ParseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %v", tt.constraint, err)
+		}
+		if allowed := c.Allows(installed); allowed != tt.allowed {
+			t.Errorf("%q allows %s: expected %v, got %v", tt.constraint, installed.FullVersion(), tt.allowed, allowed)
+		}
+	}
+
+	for _, invalid := range []string{">=", "> = 1.0", "1.0 2.0"} {
+		if _, err := ParseVersionConstraint(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
@@ -11,24 +11,8 @@ type YumPackageManager struct {
 	CommandManager cm.CommandManager
 }
 
-func (ypm *YumPackageManager) ListPackages() ([]string, error) {
-	output, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "yum",
-		Args:    []string{"list", "installed"},
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	lines := strings.Split(output.STDOUT, "\n")
-	var packages []string
-	for _, line := range lines[1:] { // Skipping the header line
-		parts := strings.Fields(line)
-		if len(parts) > 0 {
-			packages = append(packages, parts[0])
-		}
-	}
-	return packages, nil
+func (ypm *YumPackageManager) ListPackages() ([]Package, error) {
+	return listRPMPackages(ypm.CommandManager)
 }
 
 func (ypm *YumPackageManager) AddPackage(pkg string) error {
@@ -59,10 +43,11 @@ func (ypm *YumPackageManager) UpgradePackage(pkg string) error {
 }
 
 func (ypm *YumPackageManager) CheckOSUpdates() ([]string, error) {
-	output, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	// yum list exits with 1 when there is nothing to update.
+	output, err := cm.RunChecked(context.TODO(), ypm.CommandManager, cm.CommandConfig{
 		Command: "yum",
 		Args:    []string{"list", "updates"},
-	})
+	}, func(status int) bool { return status == 1 })
 	if err != nil {
 		return nil, err
 	}
@@ -90,21 +75,21 @@ func (ypm *YumPackageManager) UpgradeAll() ([]string, error) {
 	return ypm.CheckOSUpdates()
 }
 
-func (ypm *YumPackageManager) EnsurePackagePresent(pkg string) error {
-	packages, err := ypm.ListPackages()
-	if err != nil {
-		return err
-	}
-
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is already installed; return without taking action
-			return nil
+func (ypm *YumPackageManager) EnsurePackagePresent(pkg string, constraint string) error {
+	return ensurePresent(ypm, pkg, constraint, func(version string, installed *Package) error {
+		// yum install leaves a newer installed version in place. The
+		// installed version carries its epoch, which version may not.
+		action := "install"
+		if installed != nil && CompareVersions(comparableVersion(*installed, version), version) > 0 {
+			action = "downgrade"
 		}
-	}
-
-	// Package is not installed; proceed with installation
-	return ypm.AddPackage(pkg)
+		_, err := ypm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+			Command: "yum",
+			Sudo:    true,
+			Args:    []string{action, "-y", pkg + "-" + version},
+		})
+		return err
+	})
 }
 
 func (ypm *YumPackageManager) EnsurePackageAbsent(pkg string) error {
@@ -113,11 +98,9 @@ func (ypm *YumPackageManager) EnsurePackageAbsent(pkg string) error {
 		return err
 	}
 
-	for _, installedPkg := range packages {
-		if installedPkg == pkg {
-			// Package is installed; proceed with removal
-			return ypm.RemovePackage(pkg)
-		}
+	if isPresent(packages, pkg) {
+		// Package is installed; proceed with removal
+		return ypm.RemovePackage(pkg)
 	}
 
 	// Package is not installed; return without taking action
@@ -0,0 +1,50 @@
+package packagemanager
+
+import (
+	"testing"
+)
+
+func TestYumEnsurePackagePresentDowngrades(t *testing.T) {
+	downgrade := "yum downgrade -y openssl-1:3.0.1"
+	mock := &MockCommandManager{Outputs: map[string]string{
+		"rpm -qa --queryformat " + rpmFormat: rpmFixture,
+		downgrade:                            "",
+	}}
+	ypm := &YumPackageManager{CommandManager: mock}
+
+	// The fixture does not change, so the downgrade is reported as not
+	// having taken effect.
+	if err := ypm.EnsurePackagePresent("openssl", "= 1:3.0.1"); err == nil {
+		t.Error("Expected an error for a version that was not installed")
+	}
+	if !contains(mock.Commands, downgrade) {
+		t.Errorf("Expected %q to be run, got %q", downgrade, mock.Commands)
+	}
+}
+
+func TestYumEnsurePackagePresentIgnoresInstalledEpoch(t *testing.T) {
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		// openssl is installed as 1:3.0.7; the epoch alone does not make
+		// it newer than a version given without one.
+		{"= 3.0.8", "yum install -y openssl-3.0.8"},
+		{"= 3.0.1", "yum downgrade -y openssl-3.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			mock := &MockCommandManager{Outputs: map[string]string{
+				"rpm -qa --queryformat " + rpmFormat: rpmFixture,
+				tt.expected:                          "",
+			}}
+			ypm := &YumPackageManager{CommandManager: mock}
+
+			ypm.EnsurePackagePresent("openssl", tt.constraint)
+			if !contains(mock.Commands, tt.expected) {
+				t.Errorf("Expected %q to be run, got %q", tt.expected, mock.Commands)
+			}
+		})
+	}
+}
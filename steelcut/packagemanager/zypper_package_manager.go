@@ -0,0 +1,151 @@
+package packagemanager
+
+import (
+	"context"
+	"strings"
+
+	cm "github.com/steelcutops/steelcut/steelcut/commandmanager"
+)
+
+// ZypperPackageManager manages packages on openSUSE and SLES.
+type ZypperPackageManager struct {
+	CommandManager cm.CommandManager
+}
+
+// zypperInformational reports the exit statuses 100 to 103, which zypper uses
+// for successful runs with something to point out, such as a reboot being
+// needed after a kernel update. The statuses above them are failures: 104
+// means a requested package was not found, 105 to 107 an interrupted run or
+// failed scripts.
+func zypperInformational(status int) bool {
+	return status >= 100 && status <= 103
+}
+
+// zypper runs zypper non-interactively as root.
+func (zpm *ZypperPackageManager) zypper(args ...string) (cm.CommandResult, error) {
+	return cm.RunChecked(context.TODO(), zpm.CommandManager, cm.CommandConfig{
+		Command: "zypper",
+		Sudo:    true,
+		Args:    append([]string{"--non-interactive"}, args...),
+	}, zypperInformational)
+}
+
+// ListPackages reads the package database with rpm and, where zypper can
+// tell, adds the repository each package was installed from.
+func (zpm *ZypperPackageManager) ListPackages() ([]Package, error) {
+	packages, err := listRPMPackages(zpm.CommandManager)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := cm.RunChecked(context.TODO(), zpm.CommandManager, cm.CommandConfig{
+		Command: "zypper",
+		Args:    []string{"--non-interactive", "--quiet", "search", "--installed-only", "--details", "--type", "package"},
+		Env:     []string{"LC_ALL=C"},
+	}, zypperInformational)
+	if err != nil {
+		// Without the repository metadata the packages are still good.
+		return packages, nil
+	}
+	repositories := parseZypperSearch(output.STDOUT)
+	for i, p := range packages {
+		packages[i].Repository = repositories[p.Name+"."+p.Arch]
+	}
+	return packages, nil
+}
+
+// parseZypperSearch maps name.arch to the repository from the table printed
+// by zypper search --details, whose rows look like
+// "i+ | bash | package | 5.2.15-2.1 | x86_64 | Main Repository (OSS)".
+// Packages no enabled repository provides are listed as (System Packages).
+func parseZypperSearch(output string) map[string]string {
+	repositories := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		columns := strings.Split(line, "|")
+		if len(columns) != 6 || !strings.HasPrefix(strings.TrimSpace(columns[0]), "i") {
+			continue
+		}
+		repository := strings.TrimSpace(columns[5])
+		if repository == "(System Packages)" {
+			continue
+		}
+		repositories[strings.TrimSpace(columns[1])+"."+strings.TrimSpace(columns[4])] = repository
+	}
+	return repositories
+}
+
+func (zpm *ZypperPackageManager) AddPackage(pkg string) error {
+	_, err := zpm.zypper("install", pkg)
+	return err
+}
+
+func (zpm *ZypperPackageManager) RemovePackage(pkg string) error {
+	_, err := zpm.zypper("remove", pkg)
+	return err
+}
+
+func (zpm *ZypperPackageManager) UpgradePackage(pkg string) error {
+	_, err := zpm.zypper("update", pkg)
+	return err
+}
+
+func (zpm *ZypperPackageManager) CheckOSUpdates() ([]string, error) {
+	if _, err := zpm.zypper("refresh"); err != nil {
+		return nil, err
+	}
+
+	output, err := cm.RunChecked(context.TODO(), zpm.CommandManager, cm.CommandConfig{
+		Command: "zypper",
+		Args:    []string{"--non-interactive", "--quiet", "list-updates"},
+		Env:     []string{"LC_ALL=C"},
+	}, zypperInformational)
+	if err != nil {
+		return nil, err
+	}
+	return parseZypperUpdates(output.STDOUT), nil
+}
+
+// parseZypperUpdates reads the package names from zypper list-updates, whose
+// rows look like "v | Main Update Repository | bash | 5.2.15-2.1 | 5.2.15-3.1 | x86_64".
+func parseZypperUpdates(output string) []string {
+	var updates []string
+	for _, line := range strings.Split(output, "\n") {
+		columns := strings.Split(line, "|")
+		if len(columns) < 6 || strings.TrimSpace(columns[0]) != "v" {
+			continue
+		}
+		updates = append(updates, strings.TrimSpace(columns[2]))
+	}
+	return updates
+}
+
+func (zpm *ZypperPackageManager) UpgradeAll() ([]string, error) {
+	if _, err := zpm.zypper("update"); err != nil {
+		return nil, err
+	}
+	return zpm.CheckOSUpdates()
+}
+
+func (zpm *ZypperPackageManager) EnsurePackagePresent(pkg string, constraint string) error {
+	return ensurePresent(zpm, pkg, constraint, func(version string, installed *Package) error {
+		// --oldpackage lets zypper install a version older than the one
+		// installed.
+		_, err := zpm.zypper("install", "--oldpackage", pkg+"="+version)
+		return err
+	})
+}
+
+func (zpm *ZypperPackageManager) EnsurePackageAbsent(pkg string) error {
+	packages, err := zpm.ListPackages()
+	if err != nil {
+		return err
+	}
+
+	if isPresent(packages, pkg) {
+		// Package is installed; proceed with removal
+		return zpm.RemovePackage(pkg)
+	}
+
+	// Package is not installed; return without taking action
+	return nil
+}
@@ -0,0 +1,89 @@
+package packagemanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+const zypperSearchFixture = `S  | Name        | Type    | Version    | Arch   | Repository
+---+-------------+---------+------------+--------+----------------------
+i+ | bash        | package | 5.1.8-9    | x86_64 | Main Repository (OSS)
+i  | openssl     | package | 3.0.7-27   | x86_64 | (System Packages)
+`
+
+const zypperUpdatesFixture = `S | Repository             | Name | Current Version | Available Version | Arch
+--+------------------------+------+-----------------+-------------------+-------
+v | Main Update Repository | bash | 5.1.8-9.el9     | 5.1.8-10          | x86_64
+`
+
+func TestZypperListPackages(t *testing.T) {
+	mock := &MockCommandManager{Outputs: map[string]string{
+		"rpm -qa --queryformat " + rpmFormat:                                                rpmFixture,
+		"zypper --non-interactive --quiet search --installed-only --details --type package": zypperSearchFixture,
+	}}
+	zpm := &ZypperPackageManager{CommandManager: mock}
+
+	packages, err := zpm.ListPackages()
+	if err != nil {
+		t.Fatalf("Error listing packages: %v", err)
+	}
+
+	repositories := map[string]string{}
+	for _, p := range packages {
+		repositories[p.Name] = p.Repository
+	}
+	expected := map[string]string{"bash": "Main Repository (OSS)", "openssl": "", "gpg-pubkey": ""}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("Expected %v, got %v", expected, repositories)
+	}
+}
+
+func TestZypperUpgradeAll(t *testing.T) {
+	// 103 asks for zypper itself to be restarted, which is not a failure.
+	mock := &MockCommandManager{
+		Outputs: map[string]string{
+			"zypper --non-interactive update":               "",
+			"zypper --non-interactive refresh":              "",
+			"zypper --non-interactive --quiet list-updates": zypperUpdatesFixture,
+		},
+		ExitCodes: map[string]int{"zypper --non-interactive update": 103},
+	}
+	zpm := &ZypperPackageManager{CommandManager: mock}
+
+	updates, err := zpm.UpgradeAll()
+	if err != nil {
+		t.Fatalf("Error upgrading: %v", err)
+	}
+	if !reflect.DeepEqual(updates, []string{"bash"}) {
+		t.Errorf("Expected bash to still be upgradable, got %v", updates)
+	}
+}
+
+func TestZypperEnsurePackagePresentExactVersion(t *testing.T) {
+	install := "zypper --non-interactive install --oldpackage bash=5.1.0"
+	mock := &MockCommandManager{Outputs: map[string]string{
+		"rpm -qa --queryformat " + rpmFormat: rpmFixture,
+		install:                              "",
+	}}
+	zpm := &ZypperPackageManager{CommandManager: mock}
+
+	if err := zpm.EnsurePackagePresent("bash", "5.1.0"); err == nil {
+		t.Error("Expected an error for a version that was not installed")
+	}
+	if !contains(mock.Commands, install) {
+		t.Errorf("Expected %q to be run, got %q", install, mock.Commands)
+	}
+}
+
+func TestZypperAddPackageNotFound(t *testing.T) {
+	install := "zypper --non-interactive install nosuchpackage"
+	mock := &MockCommandManager{
+		Outputs:   map[string]string{install: "No provider of 'nosuchpackage' found.\n"},
+		ExitCodes: map[string]int{install: 104},
+	}
+	zpm := &ZypperPackageManager{CommandManager: mock}
+
+	if err := zpm.AddPackage("nosuchpackage"); err == nil {
+		t.Error("Expected an install exiting with 104 to fail")
+	}
+}
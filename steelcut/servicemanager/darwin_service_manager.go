@@ -55,10 +55,7 @@ func (dsm *DarwinServiceManager) ReloadService(serviceName string) error {
 }
 
 func (dsm *DarwinServiceManager) CheckServiceStatus(serviceName string) (ServiceStatus, error) {
-	output, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "launchctl",
-		Args:    []string{"print", fmt.Sprintf("system/%s", serviceName)},
-	})
+	output, err := dsm.printService(serviceName)
 	if err != nil {
 		return "", err
 	}
@@ -72,12 +69,18 @@ func (dsm *DarwinServiceManager) CheckServiceStatus(serviceName string) (Service
 func (dsm *DarwinServiceManager) IsServiceEnabled(serviceName string) (bool, error) {
 	// On Darwin, determining if a service is enabled is tricky. The service's plist presence in /Library/LaunchDaemons
 	// doesn't guarantee it's enabled. This is a basic check and might not be 100% accurate.
-	output, err := dsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
-		Command: "launchctl",
-		Args:    []string{"print", fmt.Sprintf("system/%s", serviceName)},
-	})
+	output, err := dsm.printService(serviceName)
 	if err != nil {
 		return false, err
 	}
 	return strings.Contains(output.STDOUT, serviceName), nil
 }
+
+// printService runs launchctl print for the service. launchctl exits with 113
+// for a service that is not loaded, which leaves the output empty.
+func (dsm *DarwinServiceManager) printService(serviceName string) (cm.CommandResult, error) {
+	return cm.RunChecked(context.TODO(), dsm.CommandManager, cm.CommandConfig{
+		Command: "launchctl",
+		Args:    []string{"print", fmt.Sprintf("system/%s", serviceName)},
+	}, func(status int) bool { return status == 113 })
+}
@@ -60,10 +60,11 @@ func (lsm *LinuxServiceManager) ReloadService(serviceName string) error {
 }
 
 func (lsm *LinuxServiceManager) CheckServiceStatus(serviceName string) (ServiceStatus, error) {
-	output, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	// systemctl is-active exits with 3 for a unit that is not active.
+	output, err := cm.RunChecked(context.TODO(), lsm.CommandManager, cm.CommandConfig{
 		Command: "systemctl",
 		Args:    []string{"is-active", serviceName},
-	})
+	}, func(status int) bool { return status == 3 })
 	if err != nil {
 		return "", err
 	}
@@ -80,10 +81,11 @@ func (lsm *LinuxServiceManager) CheckServiceStatus(serviceName string) (ServiceS
 }
 
 func (lsm *LinuxServiceManager) IsServiceEnabled(serviceName string) (bool, error) {
-	output, err := lsm.CommandManager.Run(context.TODO(), cm.CommandConfig{
+	// systemctl is-enabled exits with 1 for a unit that is not enabled.
+	output, err := cm.RunChecked(context.TODO(), lsm.CommandManager, cm.CommandConfig{
 		Command: "systemctl",
 		Args:    []string{"is-enabled", serviceName},
-	})
+	}, func(status int) bool { return status == 1 })
 	if err != nil {
 		return false, err
 	}